@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+)
+
+// Sample is a post along with its engagement at the time it was retrieved.
+// Replies and Impressions are -1 when the platform does not provide them.
+type Sample struct {
+	RemoteID    string
+	Text        string
+	URL         string
+	Published   time.Time
+	Likes       int
+	Reposts     int
+	Replies     int
+	Impressions int
+}
+
+// Source retrieves posts and their engagement for an account on a single
+// platform.
+type Source interface {
+
+	// Recent returns the most recent posts published by the account.
+	Recent() ([]*Sample, error)
+
+	// Lookup returns the current engagement with the specified posts. Posts
+	// that no longer exist are omitted.
+	Lookup(posts []*db.Post) ([]*Sample, error)
+}
+
+// New creates a source for the specified account. The publisher options
+// provide the Twitter application credentials.
+func New(a *db.Account, o *publisher.Options) (Source, error) {
+	switch a.Platform {
+	case db.PlatformTwitter:
+		return newTwitterSource(a, o), nil
+	case db.PlatformMastodon:
+		return newMastodonSource(a), nil
+	case db.PlatformBluesky:
+		return newBlueskySource(a), nil
+	}
+	return nil, errors.New("unsupported platform")
+}
+
+// remoteIDs returns the remote IDs of the posts.
+func remoteIDs(posts []*db.Post) []string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = p.RemoteID
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/bluesky"
+	"github.com/nathan-osman/informas/db"
+)
+
+// blueskyBatchSize is the number of posts that can be retrieved at once.
+const blueskyBatchSize = 25
+
+// blueskySource reads posts and their counts. Bluesky does not count
+// impressions.
+type blueskySource struct {
+	account *db.Account
+}
+
+func newBlueskySource(a *db.Account) *blueskySource {
+	return &blueskySource{
+		account: a,
+	}
+}
+
+// client returns a client using the cached session for the account.
+func (b *blueskySource) client() (*bluesky.Client, error) {
+	return bluesky.Login(b.account.Instance, b.account.RemoteID, b.account.AccessToken)
+}
+
+// sample converts a post to a sample.
+func (b *blueskySource) sample(p *bluesky.PostView) *Sample {
+	s := &Sample{
+		RemoteID:    p.URI,
+		URL:         p.WebURL(),
+		Published:   p.IndexedAt.UTC(),
+		Likes:       p.LikeCount,
+		Reposts:     p.RepostCount,
+		Replies:     p.ReplyCount,
+		Impressions: -1,
+	}
+	if p.Record != nil {
+		s.Text = p.Record.Text
+		if t, err := time.Parse(time.RFC3339Nano, p.Record.CreatedAt); err == nil {
+			s.Published = t.UTC()
+		}
+	}
+	return s
+}
+
+// Recent implements Source.
+func (b *blueskySource) Recent() ([]*Sample, error) {
+	c, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	posts, err := c.GetAuthorFeed(c.DID(), 50)
+	if err != nil {
+		return nil, err
+	}
+	samples := []*Sample{}
+	for _, p := range posts {
+		samples = append(samples, b.sample(p))
+	}
+	return samples, nil
+}
+
+// Lookup implements Source.
+func (b *blueskySource) Lookup(posts []*db.Post) ([]*Sample, error) {
+	c, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	samples := []*Sample{}
+	uris := remoteIDs(posts)
+	for len(uris) != 0 {
+		n := len(uris)
+		if n > blueskyBatchSize {
+			n = blueskyBatchSize
+		}
+		views, err := c.GetPosts(uris[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range views {
+			samples = append(samples, b.sample(p))
+		}
+		uris = uris[n:]
+	}
+	return samples, nil
+}
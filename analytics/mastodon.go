@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"net/http"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/mastodon"
+)
+
+// mastodonSource reads statuses and their counts. Mastodon does not count
+// impressions.
+type mastodonSource struct {
+	account *db.Account
+	client  *mastodon.Client
+}
+
+func newMastodonSource(a *db.Account) *mastodonSource {
+	return &mastodonSource{
+		account: a,
+		client:  mastodon.NewClient(a.Instance, a.AccessToken),
+	}
+}
+
+// sample converts a status to a sample.
+func (m *mastodonSource) sample(s *mastodon.Status) *Sample {
+	return &Sample{
+		RemoteID:    s.ID,
+		Text:        s.Text(),
+		URL:         s.WebURL(),
+		Published:   s.CreatedAt.UTC(),
+		Likes:       s.FavouritesCount,
+		Reposts:     s.ReblogsCount,
+		Replies:     s.RepliesCount,
+		Impressions: -1,
+	}
+}
+
+// Recent implements Source.
+func (m *mastodonSource) Recent() ([]*Sample, error) {
+	statuses, err := m.client.AccountStatuses(m.account.RemoteID)
+	if err != nil {
+		return nil, err
+	}
+	samples := []*Sample{}
+	for _, s := range statuses {
+		samples = append(samples, m.sample(s))
+	}
+	return samples, nil
+}
+
+// Lookup implements Source. Statuses are retrieved one at a time since older
+// servers cannot retrieve several at once.
+func (m *mastodonSource) Lookup(posts []*db.Post) ([]*Sample, error) {
+	samples := []*Sample{}
+	for _, p := range posts {
+		s, err := m.client.GetStatus(p.RemoteID)
+		if err != nil {
+			if e, ok := err.(*mastodon.Error); ok && e.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+		samples = append(samples, m.sample(s))
+	}
+	return samples, nil
+}
@@ -0,0 +1,191 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/sirupsen/logrus"
+)
+
+// TrackingPeriod is how long after publication the engagement with a post is
+// measured.
+const TrackingPeriod = 30 * 24 * time.Hour
+
+const (
+	// refreshInterval determines how often accounts are checked for new
+	// posts and posts that are due to be measured
+	refreshInterval = 5 * time.Minute
+
+	// minDelay and maxDelay limit the time between measurements of a post
+	minDelay = 15 * time.Minute
+	maxDelay = 24 * time.Hour
+
+	// batchSize limits the number of posts measured per account at once
+	batchSize = 100
+)
+
+// nextRefresh determines when a post should next be measured. Engagement
+// changes quickly after a post is published and slowly afterwards, so the
+// delay grows with the age of the post.
+func nextRefresh(published, now time.Time) time.Time {
+	d := now.Sub(published) / 8
+	if d < minDelay {
+		d = minDelay
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return now.Add(d)
+}
+
+// Refresher periodically discovers posts published by every account and
+// records their engagement.
+type Refresher struct {
+	options func() (*publisher.Options, error)
+	log     *logrus.Entry
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewRefresher creates a new refresher and begins recording engagement. The
+// options are retrieved before each check so that changes take effect
+// without a restart.
+func NewRefresher(options func() (*publisher.Options, error)) *Refresher {
+	r := &Refresher{
+		options: options,
+		log:     logrus.WithField("context", "analytics"),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go r.run()
+	return r
+}
+
+// discover stores posts that are not yet tracked. They are measured straight
+// away.
+func (r *Refresher) discover(s Source, a *db.Account) error {
+	samples, err := s.Recent()
+	if err != nil {
+		return err
+	}
+	var (
+		now   = time.Now().UTC()
+		since = now.Add(-TrackingPeriod)
+	)
+	return db.Transaction(func(t *db.Token) error {
+		for _, sample := range samples {
+			if sample.Published.Before(since) {
+				continue
+			}
+			p := &db.Post{
+				AccountID:   a.ID,
+				RemoteID:    sample.RemoteID,
+				Text:        sample.Text,
+				URL:         sample.URL,
+				Published:   sample.Published,
+				NextRefresh: now,
+			}
+			if err := p.Save(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// measure records the engagement with posts that are due. Posts that no
+// longer exist are not measured again.
+func (r *Refresher) measure(s Source, a *db.Account) error {
+	now := time.Now().UTC()
+	posts, err := db.DuePosts(&db.Token{}, a.ID, now.Add(-TrackingPeriod), batchSize)
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+	samples, err := s.Lookup(posts)
+	if err != nil {
+		return err
+	}
+	found := map[string]*Sample{}
+	for _, sample := range samples {
+		found[sample.RemoteID] = sample
+	}
+	return db.Transaction(func(t *db.Token) error {
+		for _, p := range posts {
+			sample, ok := found[p.RemoteID]
+			if !ok {
+				p.NextRefresh = p.Published.Add(TrackingPeriod)
+				if err := p.Save(t); err != nil {
+					return err
+				}
+				continue
+			}
+			m := &db.Metric{
+				PostID:      p.ID,
+				Recorded:    now,
+				Likes:       sample.Likes,
+				Reposts:     sample.Reposts,
+				Replies:     sample.Replies,
+				Impressions: sample.Impressions,
+			}
+			if err := m.Save(t); err != nil {
+				return err
+			}
+			p.Text = sample.Text
+			p.NextRefresh = nextRefresh(p.Published, now)
+			if err := p.Save(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// refresh discovers and measures posts for all accounts.
+func (r *Refresher) refresh() {
+	accounts, err := db.AllAccounts(&db.Token{})
+	if err != nil {
+		r.log.WithError(err).Error("unable to retrieve accounts")
+		return
+	}
+	o, err := r.options()
+	if err != nil {
+		r.log.WithError(err).Error("unable to retrieve credentials")
+		return
+	}
+	for _, a := range accounts {
+		s, err := New(a, o)
+		if err != nil {
+			r.log.WithError(err).WithField("account", a.ID).Warning("unable to create source")
+			continue
+		}
+		if err := r.discover(s, a); err != nil {
+			r.log.WithError(err).WithField("account", a.ID).Warning("unable to retrieve posts")
+		}
+		if err := r.measure(s, a); err != nil {
+			r.log.WithError(err).WithField("account", a.ID).Warning("unable to measure posts")
+		}
+	}
+}
+
+// run records engagement until stopped.
+func (r *Refresher) run() {
+	defer close(r.stopped)
+	for {
+		r.refresh()
+		select {
+		case <-time.After(refreshInterval):
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops recording engagement.
+func (r *Refresher) Close() {
+	close(r.stop)
+	<-r.stopped
+}
@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/twitter"
+)
+
+// twitterSource reads tweets and their public metrics from the v2 API, which
+// is the only version that provides reply and impression counts.
+type twitterSource struct {
+	account *db.Account
+	client  *twitter.Client
+}
+
+func newTwitterSource(a *db.Account, o *publisher.Options) *twitterSource {
+	return &twitterSource{
+		account: a,
+		client: twitter.NewClient(
+			o.TwitterConsumerKey,
+			o.TwitterConsumerSecret,
+			a.AccessToken,
+			a.AccessSecret,
+		),
+	}
+}
+
+// sample converts a tweet to a sample.
+func (t *twitterSource) sample(tweet *twitter.TweetMetrics) *Sample {
+	s := &Sample{
+		RemoteID:    tweet.ID,
+		Text:        tweet.Text,
+		URL:         "https://twitter.com/" + t.account.Username + "/status/" + tweet.ID,
+		Published:   tweet.CreatedAt.UTC(),
+		Replies:     -1,
+		Impressions: -1,
+	}
+	if m := tweet.PublicMetrics; m != nil {
+		s.Likes = m.LikeCount
+		s.Reposts = m.RetweetCount + m.QuoteCount
+		s.Replies = m.ReplyCount
+		s.Impressions = m.ImpressionCount
+	}
+	return s
+}
+
+// Recent implements Source.
+func (t *twitterSource) Recent() ([]*Sample, error) {
+	tweets, err := t.client.UserTweets(t.account.RemoteID)
+	if err != nil {
+		return nil, err
+	}
+	samples := []*Sample{}
+	for _, tweet := range tweets {
+		samples = append(samples, t.sample(tweet))
+	}
+	return samples, nil
+}
+
+// Lookup implements Source.
+func (t *twitterSource) Lookup(posts []*db.Post) ([]*Sample, error) {
+	samples := []*Sample{}
+	ids := remoteIDs(posts)
+	for len(ids) != 0 {
+		n := len(ids)
+		if n > 100 {
+			n = 100
+		}
+		tweets, err := t.client.LookupTweets(ids[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, tweet := range tweets {
+			samples = append(samples, t.sample(tweet))
+		}
+		ids = ids[n:]
+	}
+	return samples, nil
+}
@@ -0,0 +1,264 @@
+package approval
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// Status is the outcome of an approval.
+type Status string
+
+// Statuses of an approval.
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+// Errors returned when a user may not act on an approval.
+var (
+	ErrNotPending      = errors.New("approval is no longer pending")
+	ErrNotApprover     = errors.New("user is not an approver for this stage")
+	ErrAlreadyApproved = errors.New("user has already approved this stage")
+	ErrAuthor          = errors.New("authors cannot approve their own tweets")
+)
+
+// ErrUnreachable is returned by Start when a stage needs more approvals than
+// there are users other than the author who may give them.
+var ErrUnreachable = errors.New("a stage cannot be approved without the author")
+
+// Stage must be approved by Required users from Approvers before the next
+// stage begins. If EscalateAfter is not zero and the stage is still waiting
+// after that time, the users in EscalateTo may also approve it.
+type Stage struct {
+	Name          string
+	Required      int
+	Approvers     []int
+	EscalateAfter time.Duration
+	EscalateTo    []int
+}
+
+// Policy contains the stages that a tweet passes through in order. Tweets
+// written by trusted users and tweets for accounts without any stages are
+// approved immediately.
+type Policy struct {
+	Stages  []*Stage
+	Trusted []int
+}
+
+// State is the progress of a single tweet through a policy. States are never
+// modified; each transition returns a new one.
+type State struct {
+	Status    Status
+	AuthorID  int
+	Stage     int
+	Approvals []int
+	Started   time.Time
+	Escalated bool
+}
+
+// contains determines whether the ID is in the list.
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every stage can be approved.
+func (p *Policy) Validate() error {
+	for _, s := range p.Stages {
+		if s.Required < 1 {
+			return errors.New("each stage requires at least one approval")
+		}
+		if s.Required > len(s.Approvers) {
+			return errors.New("a stage cannot require more approvals than it has approvers")
+		}
+		if s.EscalateAfter < 0 {
+			return errors.New("escalation time cannot be negative")
+		}
+	}
+	return nil
+}
+
+// Combine creates a single policy for a tweet written by the author for
+// several accounts. It contains the stages of each policy in order, except
+// for policies under which the author's tweets are approved immediately, so
+// the tweet is approved once it would have been approved for every account.
+// A stage identical to one already added, such as the default stage shared
+// by accounts without a policy, is only included once.
+func Combine(authorID int, policies ...*Policy) *Policy {
+	c := &Policy{
+		Stages: []*Stage{},
+	}
+	for _, p := range policies {
+		if contains(p.Trusted, authorID) {
+			continue
+		}
+		for _, st := range p.Stages {
+			duplicate := false
+			for _, v := range c.Stages {
+				if reflect.DeepEqual(v, st) {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				c.Stages = append(c.Stages, st)
+			}
+		}
+	}
+	return c
+}
+
+// reachable determines whether enough users other than the author may
+// approve the stage. Escalation users are counted since the stage is
+// escalated if it waits for too long.
+func (st *Stage) reachable(authorID int) bool {
+	candidates := st.Approvers
+	if st.EscalateAfter != 0 {
+		candidates = append(append([]int{}, candidates...), st.EscalateTo...)
+	}
+	ids := []int{}
+	for _, id := range candidates {
+		if id != authorID && !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return len(ids) >= st.Required
+}
+
+// Start returns the initial state for a tweet written by the author.
+// ErrUnreachable is returned if the author is one of the approvers of a
+// stage that could then never be approved.
+func (p *Policy) Start(authorID int, now time.Time) (*State, error) {
+	s := &State{
+		Status:   StatusPending,
+		AuthorID: authorID,
+		Started:  now,
+	}
+	if len(p.Stages) == 0 || contains(p.Trusted, authorID) {
+		s.Status = StatusApproved
+		return s, nil
+	}
+	for _, st := range p.Stages {
+		if !st.reachable(authorID) {
+			return nil, ErrUnreachable
+		}
+	}
+	return s, nil
+}
+
+// Approvers returns the users who may act on the current stage and have not
+// yet approved it. Nil is returned once the approval is no longer pending or
+// if the stage was removed from the policy.
+func (p *Policy) Approvers(s *State) []int {
+	if s.Status != StatusPending || s.Stage >= len(p.Stages) {
+		return nil
+	}
+	stage := p.Stages[s.Stage]
+	candidates := stage.Approvers
+	if s.Escalated {
+		candidates = append(append([]int{}, candidates...), stage.EscalateTo...)
+	}
+	ids := []int{}
+	for _, id := range candidates {
+		if id != s.AuthorID && !contains(s.Approvals, id) && !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Reviewers returns every user who may act on any stage of the policy,
+// including those who may only do so once a stage is escalated.
+func (p *Policy) Reviewers() []int {
+	ids := []int{}
+	for _, stage := range p.Stages {
+		for _, id := range append(append([]int{}, stage.Approvers...), stage.EscalateTo...) {
+			if !contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// check ensures that the user may act on the current stage.
+func (p *Policy) check(s *State, userID int) error {
+	if s.Status != StatusPending {
+		return ErrNotPending
+	}
+	if userID == s.AuthorID {
+		return ErrAuthor
+	}
+	if contains(s.Approvals, userID) {
+		return ErrAlreadyApproved
+	}
+	if !contains(p.Approvers(s), userID) {
+		return ErrNotApprover
+	}
+	return nil
+}
+
+// Approve records an approval for the current stage. Once the stage has
+// enough approvals, the next stage begins or, after the last stage, the
+// tweet is approved.
+func (p *Policy) Approve(s *State, userID int, now time.Time) (*State, error) {
+	if err := p.check(s, userID); err != nil {
+		return nil, err
+	}
+	n := *s
+	n.Approvals = append(append([]int{}, s.Approvals...), userID)
+	if len(n.Approvals) < p.Stages[s.Stage].Required {
+		return &n, nil
+	}
+	n.Approvals = nil
+	n.Started = now
+	n.Escalated = false
+	if s.Stage+1 < len(p.Stages) {
+		n.Stage = s.Stage + 1
+	} else {
+		n.Status = StatusApproved
+	}
+	return &n, nil
+}
+
+// Reject ends the approval. Any approver of the current stage may reject it.
+func (p *Policy) Reject(s *State, userID int, now time.Time) (*State, error) {
+	if err := p.check(s, userID); err != nil && err != ErrAlreadyApproved {
+		return nil, err
+	}
+	n := *s
+	n.Status = StatusRejected
+	n.Started = now
+	return &n, nil
+}
+
+// EscalationTime returns the time at which the current stage is escalated.
+// The second return value is false if it will never be escalated.
+func (p *Policy) EscalationTime(s *State) (time.Time, bool) {
+	if s.Status != StatusPending || s.Escalated || s.Stage >= len(p.Stages) {
+		return time.Time{}, false
+	}
+	stage := p.Stages[s.Stage]
+	if stage.EscalateAfter == 0 || len(stage.EscalateTo) == 0 {
+		return time.Time{}, false
+	}
+	return s.Started.Add(stage.EscalateAfter), true
+}
+
+// Escalate allows the escalation users to act on the current stage if it has
+// been waiting for too long. The second return value indicates whether the
+// state changed, in which case the new approvers should be notified.
+func (p *Policy) Escalate(s *State, now time.Time) (*State, bool) {
+	t, ok := p.EscalationTime(s)
+	if !ok || now.Before(t) {
+		return s, false
+	}
+	n := *s
+	n.Escalated = true
+	return &n, true
+}
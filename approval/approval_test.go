@@ -0,0 +1,220 @@
+package approval
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCombine(t *testing.T) {
+	var (
+		legal = &Stage{Name: "Legal", Required: 1, Approvers: []int{2}}
+		comms = &Stage{Name: "Comms", Required: 1, Approvers: []int{3}}
+		open  = &Policy{}
+		p1    = &Policy{Stages: []*Stage{legal}}
+		p2    = &Policy{Stages: []*Stage{comms}, Trusted: []int{1}}
+		p3    = &Policy{Stages: []*Stage{{Name: "Legal", Required: 1, Approvers: []int{2}}}}
+	)
+	for _, tc := range []struct {
+		description string
+		authorID    int
+		policies    []*Policy
+		expected    []*Stage
+	}{
+		{"stages are kept in order", 4, []*Policy{p1, p2}, []*Stage{legal, comms}},
+		{"trusted author skips a policy", 1, []*Policy{p1, p2}, []*Stage{legal}},
+		{"policies without stages", 4, []*Policy{open, open}, []*Stage{}},
+		{"identical stages are included once", 4, []*Policy{p1, p3, p2}, []*Stage{legal, comms}},
+	} {
+		c := Combine(tc.authorID, tc.policies...)
+		if !reflect.DeepEqual(c.Stages, tc.expected) {
+			t.Errorf("%s: got %d stages", tc.description, len(c.Stages))
+		}
+	}
+	if s, err := Combine(1, p2, open).Start(1, time.Now()); err != nil || s.Status != StatusApproved {
+		t.Fatalf("trusted author got %v, %v", s, err)
+	}
+}
+
+// newTestPolicy creates a policy with a legal stage requiring two of three
+// approvals followed by a comms stage that is escalated after an hour.
+func newTestPolicy() *Policy {
+	return &Policy{
+		Stages: []*Stage{
+			{Name: "Legal", Required: 2, Approvers: []int{2, 3, 4}},
+			{Name: "Comms", Required: 1, Approvers: []int{5}, EscalateAfter: time.Hour, EscalateTo: []int{6}},
+		},
+		Trusted: []int{9},
+	}
+}
+
+func TestStart(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		description string
+		policy      *Policy
+		authorID    int
+		expected    Status
+	}{
+		{"trusted author", newTestPolicy(), 9, StatusApproved},
+		{"no stages", &Policy{}, 1, StatusApproved},
+		{"untrusted author", newTestPolicy(), 1, StatusPending},
+	} {
+		s, err := tc.policy.Start(tc.authorID, now)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.description, err)
+		}
+		if s.Status != tc.expected {
+			t.Errorf("%s: got %s, expected %s", tc.description, s.Status, tc.expected)
+		}
+	}
+}
+
+func TestStartUnreachable(t *testing.T) {
+	var (
+		now     = time.Now()
+		blocked = &Policy{Stages: []*Stage{{Name: "Legal", Required: 2, Approvers: []int{1, 2}}}}
+		escaped = &Policy{Stages: []*Stage{{Name: "Legal", Required: 2, Approvers: []int{1, 2}, EscalateAfter: time.Hour, EscalateTo: []int{3}}}}
+	)
+	if _, err := blocked.Start(1, now); err != ErrUnreachable {
+		t.Fatalf("got %v, expected ErrUnreachable", err)
+	}
+	if _, err := blocked.Start(3, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := escaped.Start(1, now); err != nil {
+		t.Fatalf("escalation users were not counted: %s", err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	var (
+		p      = newTestPolicy()
+		now    = time.Now()
+		s, _   = p.Start(1, now)
+		later  = now.Add(time.Minute)
+		s1, e1 = p.Approve(s, 2, later)
+	)
+	if e1 != nil {
+		t.Fatal(e1)
+	}
+	if s1.Status != StatusPending || s1.Stage != 0 || !reflect.DeepEqual(s1.Approvals, []int{2}) {
+		t.Fatalf("below the count: got %+v", s1)
+	}
+	if len(s.Approvals) != 0 {
+		t.Fatal("previous state was modified")
+	}
+	s2, err := p.Approve(s1, 3, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.Status != StatusPending || s2.Stage != 1 || len(s2.Approvals) != 0 || !s2.Started.Equal(later) {
+		t.Fatalf("next stage: got %+v", s2)
+	}
+	s3, err := p.Approve(s2, 5, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3.Status != StatusApproved {
+		t.Fatalf("last stage: got %s", s3.Status)
+	}
+}
+
+func TestReject(t *testing.T) {
+	var (
+		p    = newTestPolicy()
+		s, _ = p.Start(1, time.Now())
+	)
+	s1, err := p.Approve(s, 2, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := p.Reject(s1, 2, time.Now())
+	if err != nil {
+		t.Fatalf("approver could not reject after approving: %s", err)
+	}
+	if s2.Status != StatusRejected {
+		t.Fatalf("got %s", s2.Status)
+	}
+	if _, err := p.Reject(s, 5, time.Now()); err != ErrNotApprover {
+		t.Fatalf("approver of a later stage got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var (
+		p       = newTestPolicy()
+		now     = time.Now()
+		s, _    = p.Start(1, now)
+		s1, _   = p.Approve(s, 2, now)
+		done, _ = (&Policy{}).Start(1, now)
+	)
+	for _, tc := range []struct {
+		description string
+		state       *State
+		userID      int
+		expected    error
+	}{
+		{"author", s, 1, ErrAuthor},
+		{"already approved", s1, 2, ErrAlreadyApproved},
+		{"not an approver", s, 5, ErrNotApprover},
+		{"escalation user before escalation", newTestState(p, now), 6, ErrNotApprover},
+		{"not pending", done, 2, ErrNotPending},
+	} {
+		if _, err := p.Approve(tc.state, tc.userID, now); err != tc.expected {
+			t.Errorf("%s: got %v, expected %v", tc.description, err, tc.expected)
+		}
+	}
+}
+
+// newTestState returns a state waiting at the comms stage of the test policy.
+func newTestState(p *Policy, now time.Time) *State {
+	s, _ := p.Start(1, now)
+	s, _ = p.Approve(s, 2, now)
+	s, _ = p.Approve(s, 3, now)
+	return s
+}
+
+func TestEscalate(t *testing.T) {
+	var (
+		p   = newTestPolicy()
+		now = time.Now()
+		s   = newTestState(p, now)
+	)
+	if at, ok := p.EscalationTime(s); !ok || !at.Equal(now.Add(time.Hour)) {
+		t.Fatalf("escalation time: got %s, %v", at, ok)
+	}
+	if n, changed := p.Escalate(s, now.Add(59*time.Minute)); changed || n.Escalated {
+		t.Fatal("escalated before EscalateAfter")
+	}
+	n, changed := p.Escalate(s, now.Add(time.Hour))
+	if !changed || !n.Escalated {
+		t.Fatal("not escalated after EscalateAfter")
+	}
+	if !reflect.DeepEqual(p.Approvers(n), []int{5, 6}) {
+		t.Fatalf("approvers after escalation: %v", p.Approvers(n))
+	}
+	if _, changed := p.Escalate(n, now.Add(2*time.Hour)); changed {
+		t.Fatal("escalated twice")
+	}
+	a, err := p.Approve(n, 6, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Status != StatusApproved {
+		t.Fatalf("escalation user approval: got %s", a.Status)
+	}
+	first, _ := p.Start(1, now)
+	if _, ok := p.EscalationTime(first); ok {
+		t.Fatal("stage without escalation has an escalation time")
+	}
+}
+
+func TestReviewers(t *testing.T) {
+	if v := newTestPolicy().Reviewers(); !reflect.DeepEqual(v, []int{2, 3, 4, 5, 6}) {
+		t.Fatalf("got %v", v)
+	}
+	if v := (&Policy{}).Reviewers(); len(v) != 0 {
+		t.Fatalf("policy without stages: got %v", v)
+	}
+}
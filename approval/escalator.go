@@ -0,0 +1,113 @@
+package approval
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// escalateInterval determines how often pending tweets are checked for
+// stages that have been waiting for too long.
+const escalateInterval = 5 * time.Minute
+
+// Escalator periodically escalates approval stages that have sat unanswered
+// for longer than their policy allows and notifies the escalation users.
+type Escalator struct {
+	log     *logrus.Entry
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewEscalator creates a new escalator and begins checking pending tweets.
+func NewEscalator() *Escalator {
+	e := &Escalator{
+		log:     logrus.WithField("context", "approval"),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go e.run()
+	return e
+}
+
+// escalate escalates the group if its current stage is due and notifies the
+// users who may now approve it.
+func (e *Escalator) escalate(t *db.Token, g *db.TweetGroup, now time.Time) error {
+	tweets, err := db.GroupTweets(t, g.ID)
+	if err != nil {
+		return err
+	}
+	if len(tweets) == 0 {
+		return nil
+	}
+	p, err := LoadGroup(t, g, tweets)
+	if err != nil {
+		return err
+	}
+	s := GroupState(g)
+	n, changed := p.Escalate(s, now)
+	if !changed {
+		return nil
+	}
+	SetGroupState(g, n)
+	if err := g.Save(t); err != nil {
+		return err
+	}
+	waiting := []int{}
+	for _, id := range p.Approvers(n) {
+		if !contains(p.Approvers(s), id) {
+			waiting = append(waiting, id)
+		}
+	}
+	return notify.Notify(
+		t,
+		waiting,
+		notify.KindApprovalRequired,
+		fmt.Sprintf(
+			"The %s stage of tweet #%d has been escalated and needs your approval",
+			p.Stages[n.Stage].Name,
+			tweets[0].ID,
+		),
+		fmt.Sprintf("/tweets/%d", tweets[0].ID),
+	)
+}
+
+// process escalates every pending group that is due.
+func (e *Escalator) process() error {
+	now := time.Now().UTC()
+	return db.Transaction(func(t *db.Token) error {
+		groups, err := db.ClaimPendingTweetGroups(t, string(StatusPending))
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if err := e.escalate(t, g, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// run checks pending tweets until stopped.
+func (e *Escalator) run() {
+	defer close(e.stopped)
+	for {
+		if err := e.process(); err != nil {
+			e.log.WithError(err).Error("unable to escalate approvals")
+		}
+		select {
+		case <-time.After(escalateInterval):
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// Close stops checking pending tweets.
+func (e *Escalator) Close() {
+	close(e.stop)
+	<-e.stopped
+}
@@ -0,0 +1,104 @@
+package approval
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+// DefaultStage is the name of the stage used for accounts without a policy.
+const DefaultStage = "Administrators"
+
+// administrators returns the IDs of the users who may approve tweets for
+// accounts without a policy.
+func administrators(t *db.Token) ([]int, error) {
+	users, err := db.AllUsers(t, "Username")
+	if err != nil {
+		return nil, err
+	}
+	ids := []int{}
+	for _, u := range users {
+		if u.IsAdmin && !u.IsDisabled {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Load reads the approval policy for an account from the database. Accounts
+// without any stages use the default policy, under which tweets must be
+// approved by an administrator and the tweets of administrators are approved
+// immediately.
+func Load(t *db.Token, accountID int) (*Policy, error) {
+	stages, err := db.AccountStages(t, accountID)
+	if err != nil {
+		return nil, err
+	}
+	trusted, err := db.TrustedUsers(t, accountID)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		Stages:  []*Stage{},
+		Trusted: trusted,
+	}
+	if len(stages) == 0 {
+		admins, err := administrators(t)
+		if err != nil {
+			return nil, err
+		}
+		p.Stages = append(p.Stages, &Stage{
+			Name:      DefaultStage,
+			Required:  1,
+			Approvers: admins,
+		})
+		p.Trusted = append(p.Trusted, admins...)
+		return p, nil
+	}
+	for _, s := range stages {
+		p.Stages = append(p.Stages, &Stage{
+			Name:          s.Name,
+			Required:      s.Required,
+			Approvers:     s.Approvers,
+			EscalateAfter: time.Duration(s.EscalateHours) * time.Hour,
+			EscalateTo:    s.EscalateTo,
+		})
+	}
+	return p, nil
+}
+
+// LoadGroup reads the combined approval policy for a group from the policies
+// of the accounts its tweets are posted to.
+func LoadGroup(t *db.Token, g *db.TweetGroup, tweets []*db.Tweet) (*Policy, error) {
+	policies := []*Policy{}
+	for _, tw := range tweets {
+		p, err := Load(t, tw.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return Combine(g.UserID, policies...), nil
+}
+
+// GroupState returns the approval state stored in a group.
+func GroupState(g *db.TweetGroup) *State {
+	return &State{
+		Status:    Status(g.Status),
+		AuthorID:  g.UserID,
+		Stage:     g.Stage,
+		Approvals: g.Approvals,
+		Started:   g.Started,
+		Escalated: g.Escalated,
+	}
+}
+
+// SetGroupState stores the approval state in a group. The group must be saved
+// afterwards.
+func SetGroupState(g *db.TweetGroup, s *State) {
+	g.Status = string(s.Status)
+	g.Stage = s.Stage
+	g.Approvals = s.Approvals
+	g.Started = s.Started
+	g.Escalated = s.Escalated
+}
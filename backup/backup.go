@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/media"
+)
+
+const (
+	// format identifies archives created by this package
+	format = "informas-backup"
+
+	// manifestName is the name of the first file in the archive
+	manifestName = "manifest.json"
+
+	// tablePrefix and mediaPrefix are the directories in the archive that
+	// contain the rows of each table and the contents of uploaded files
+	tablePrefix = "tables/"
+	mediaPrefix = "media/"
+)
+
+// Manifest describes the contents of an archive. If Salt is set, credentials
+// in the archive are encrypted with a passphrase and Check contains a known
+// value encrypted with it.
+type Manifest struct {
+	Format        string    `json:"format"`
+	SchemaVersion int       `json:"schema_version"`
+	Created       time.Time `json:"created"`
+	Salt          []byte    `json:"salt,omitempty"`
+	Check         string    `json:"check,omitempty"`
+}
+
+// secretContext returns the context used to encrypt a column with the
+// passphrase.
+func secretContext(table, column string) string {
+	return table + "." + column
+}
+
+// writeFile adds a file of the specified size to the archive, copying its
+// contents from the reader.
+func writeFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+// writeTable exports the rows of a table to a temporary file, since the size
+// of each file must be known before it is added to the archive. Credentials
+// are encrypted with the passphrase if one was provided.
+func writeTable(t *db.Token, tw *tar.Writer, name string, p *passphrase, f func(db.Row)) error {
+	tmp, err := ioutil.TempFile("", "informas-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	e := json.NewEncoder(tmp)
+	if err := db.ExportTable(t, name, func(row db.Row) error {
+		f(row)
+		if p != nil {
+			for _, c := range db.SecretColumns(name) {
+				value, ok := row[c].(string)
+				if !ok || len(value) == 0 {
+					continue
+				}
+				v, err := p.encrypt(value, secretContext(name, c))
+				if err != nil {
+					return err
+				}
+				row[c] = v
+			}
+		}
+		return e.Encode(row)
+	}); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return writeFile(tw, tablePrefix+name+".jsonl", size, tmp)
+}
+
+// writeMedia copies an uploaded file from the store into the archive.
+func writeMedia(tw *tar.Writer, store media.Store, key string) error {
+	size, err := store.Stat(key)
+	if err != nil {
+		return err
+	}
+	r, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return writeFile(tw, mediaPrefix+key, size, r)
+}
+
+// Write creates a compressed archive containing every table and all uploaded
+// files. Each table is stored as one JSON object per row, so the archive does
+// not depend on the database it was created from. Credentials are decrypted
+// with the master key and, if a passphrase is provided, encrypted with it
+// instead. Without a passphrase, anyone who can read the archive can read
+// the credentials. Tables and files are streamed into the archive so that
+// they are never held in memory.
+func Write(w io.Writer, store media.Store, passphraseValue string) error {
+	m := &Manifest{
+		Format:        format,
+		SchemaVersion: db.SchemaVersion,
+		Created:       time.Now(),
+	}
+	var p *passphrase
+	if len(passphraseValue) != 0 {
+		salt, err := newSalt()
+		if err != nil {
+			return err
+		}
+		p, err = newPassphrase(passphraseValue, salt)
+		if err != nil {
+			return err
+		}
+		check, err := p.encrypt(checkValue, manifestName)
+		if err != nil {
+			return err
+		}
+		m.Salt = salt
+		m.Check = check
+	}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tw, manifestName, int64(len(b)), bytes.NewReader(b)); err != nil {
+		return err
+	}
+	keys := []string{}
+	if err := db.Transaction(func(t *db.Token) error {
+		if err := db.BeginSnapshot(t); err != nil {
+			return err
+		}
+		for _, name := range db.Tables() {
+			if err := writeTable(t, tw, name, p, func(row db.Row) {
+				if name == "Media" {
+					if key, ok := row["key"].(string); ok {
+						keys = append(keys, key)
+					}
+				}
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := writeMedia(tw, store, key); err != nil {
+			return fmt.Errorf("unable to read media %s: %s", key, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,301 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/keyring"
+	"github.com/nathan-osman/informas/media"
+)
+
+// newTestArchive creates an archive containing only the manifest.
+func newTestArchive(t *testing.T, m *Manifest) *tar.Reader {
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		buf = &bytes.Buffer{}
+		tw  = tar.NewWriter(buf)
+	)
+	if err := writeFile(tw, manifestName, int64(len(b)), bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tar.NewReader(buf)
+}
+
+func TestReadManifest(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		manifest    *Manifest
+		valid       bool
+	}{
+		{"current schema", &Manifest{Format: format, SchemaVersion: db.SchemaVersion}, true},
+		{"older schema", &Manifest{Format: format, SchemaVersion: 0}, true},
+		{"newer schema", &Manifest{Format: format, SchemaVersion: db.SchemaVersion + 1}, false},
+		{"wrong format", &Manifest{Format: "other", SchemaVersion: db.SchemaVersion}, false},
+	} {
+		_, err := readManifest(newTestArchive(t, tc.manifest))
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: got %v", tc.description, err)
+		}
+	}
+}
+
+func TestRowReader(t *testing.T) {
+	p, err := newPassphrase("test", []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		row  string
+	}{
+		{"Stages", `{"id":1,"accountid":2,"position":1,"name":"Legal","required":2,"escalatehours":24}`},
+		{"StageUsers", `{"stageid":1,"userid":3,"isescalation":false}`},
+		{"TrustedUsers", `{"accountid":2,"userid":4}`},
+		{"TweetGroups", `{"id":5,"userid":4,"status":"pending","stage":1,"approvals":[3,6],"escalated":true}`},
+	} {
+		expected := db.Row{}
+		d := json.NewDecoder(strings.NewReader(tc.row))
+		d.UseNumber()
+		if err := d.Decode(&expected); err != nil {
+			t.Fatal(err)
+		}
+		next := rowReader(strings.NewReader(tc.row+"\n"), tc.name, p)
+		row, err := next()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if !reflect.DeepEqual(row, expected) {
+			t.Errorf("%s: got %v", tc.name, row)
+		}
+		if _, err := next(); err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", tc.name, err)
+		}
+	}
+}
+
+// testDatabase connects to the PostgreSQL database named by
+// INFORMAS_TEST_DB_NAME, skipping the test if it is not set. The database must
+// be empty since every table is truncated when the test finishes. The
+// connection returned is used to inspect the stored values directly.
+func testDatabase(t *testing.T) *sql.DB {
+	name := os.Getenv("INFORMAS_TEST_DB_NAME")
+	if len(name) == 0 {
+		t.Skip("INFORMAS_TEST_DB_NAME is not set")
+	}
+	var (
+		user     = os.Getenv("INFORMAS_TEST_DB_USER")
+		password = os.Getenv("INFORMAS_TEST_DB_PASSWORD")
+		host     = os.Getenv("INFORMAS_TEST_DB_HOST")
+	)
+	if len(host) == 0 {
+		host = "localhost"
+	}
+	if err := db.Connect(name, user, password, host, 5432); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := db.IsEmpty(&db.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty {
+		t.Fatalf("%s is not empty", name)
+	}
+	conn, err := sql.Open(
+		"postgres",
+		fmt.Sprintf("dbname=%s user=%s password=%s host=%s", name, user, password, host),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		truncate(t, conn)
+		conn.Close()
+	})
+	return conn
+}
+
+// truncate removes every row from the database.
+func truncate(t *testing.T, conn *sql.DB) {
+	if _, err := conn.Exec(
+		fmt.Sprintf(
+			"TRUNCATE %s RESTART IDENTITY CASCADE",
+			strings.Join(db.Tables(), ", "),
+		),
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKeyring creates a keyring containing a single random key with the
+// specified version.
+func testKeyring(t *testing.T, version int) *keyring.Keyring {
+	key, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := keyring.Parse(fmt.Sprintf("%d:%s", version, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// testStore creates a media store in a temporary directory.
+func testStore(t *testing.T) media.Store {
+	dir, err := ioutil.TempDir("", "informas-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	s, err := media.NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// storedToken reads the access token of the account as it is stored.
+func storedToken(t *testing.T, conn *sql.DB, id int) string {
+	var v string
+	if err := conn.QueryRow(
+		"SELECT AccessToken FROM Accounts WHERE ID = $1",
+		id,
+	).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRoundTrip(t *testing.T) {
+	var (
+		conn       = testDatabase(t)
+		source     = testStore(t)
+		target     = testStore(t)
+		passphrase = "correct horse battery staple"
+		contents   = "not really a PNG"
+	)
+	defer db.SetKeyring(nil)
+	db.SetKeyring(testKeyring(t, 1))
+
+	// Populate the database with a user, an account with encrypted
+	// credentials, a secret setting and an uploaded file
+	u := &db.User{Username: "alice", Email: "alice@example.com", IsAdmin: true}
+	if err := u.Save(&db.Token{}); err != nil {
+		t.Fatal(err)
+	}
+	a := &db.Account{
+		Platform:     db.PlatformTwitter,
+		RemoteID:     "1",
+		Username:     "example",
+		AccessToken:  "access-token",
+		AccessSecret: "access-secret",
+	}
+	if err := a.Save(&db.Token{}); err != nil {
+		t.Fatal(err)
+	}
+	if v := storedToken(t, conn, a.ID); keyring.KeyVersion(v) != 1 {
+		t.Fatalf("token stored as %q", v)
+	}
+	c, err := db.NewConfig(&db.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetSecret(&db.Token{}, "smtp_password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Put("example.png", strings.NewReader(contents)); err != nil {
+		t.Fatal(err)
+	}
+	m := &db.Media{
+		UserID:      u.ID,
+		Key:         "example.png",
+		ContentType: "image/png",
+		Size:        int64(len(contents)),
+		Created:     time.Now().UTC(),
+	}
+	if err := m.Save(&db.Token{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Back up the database and restore it into an empty one that uses a
+	// different master key
+	b := &bytes.Buffer{}
+	if err := Write(b, source, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	archive := b.Bytes()
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ioutil.ReadAll(gr); err != nil || bytes.Contains(v, []byte("access-token")) {
+		t.Errorf("credentials are not encrypted in the archive: %v", err)
+	}
+	truncate(t, conn)
+	db.SetKeyring(testKeyring(t, 2))
+	if _, err := Restore(bytes.NewReader(archive), target, "wrong"); err != errWrongPassphrase {
+		t.Fatalf("wrong passphrase: got %v", err)
+	}
+	if _, err := Restore(bytes.NewReader(archive), target, passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	// The credentials must be readable with the new key and only with it
+	restored, err := db.FindAccount(&db.Token{}, "ID", a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.AccessToken != "access-token" || restored.AccessSecret != "access-secret" {
+		t.Errorf("got credentials %q and %q", restored.AccessToken, restored.AccessSecret)
+	}
+	if v := storedToken(t, conn, a.ID); keyring.KeyVersion(v) != 2 {
+		t.Errorf("token restored as %q", v)
+	}
+	c, err = db.NewConfig(&db.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ProtectSecret(&db.Token{}, "smtp_password"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.GetSecret("smtp_password"); err != nil || v != "hunter2" {
+		t.Errorf("got setting %q, %v", v, err)
+	}
+	if _, err := db.FindUser(&db.Token{}, "Username", "alice"); err != nil {
+		t.Error(err)
+	}
+	r, err := target.Get("example.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if v, err := ioutil.ReadAll(r); err != nil || string(v) != contents {
+		t.Errorf("got media %q, %v", v, err)
+	}
+
+	// Restoring again must fail now that the database is not empty
+	if _, err := Restore(bytes.NewReader(archive), target, passphrase); err != errNotEmpty {
+		t.Errorf("second restore: got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// saltSize is the length of the random salt used to derive the key
+	saltSize = 16
+
+	// checkValue is encrypted into the manifest so that an incorrect
+	// passphrase is detected before anything is restored
+	checkValue = "informas"
+)
+
+var (
+	errPassphraseRequired = errors.New("the archive is encrypted and requires a passphrase")
+	errWrongPassphrase    = errors.New("the passphrase is incorrect")
+	errInvalidSecret      = errors.New("encrypted value is malformed")
+)
+
+// passphrase encrypts credentials with a key derived from a passphrase.
+type passphrase struct {
+	gcm cipher.AEAD
+}
+
+// newPassphrase derives a key from the passphrase and salt using scrypt.
+func newPassphrase(value string, salt []byte) (*passphrase, error) {
+	key, err := scrypt.Key([]byte(value), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, err
+	}
+	return &passphrase{gcm: gcm}, nil
+}
+
+// newSalt generates a random salt for a new archive.
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// encrypt encrypts the value with AES-GCM. The context is authenticated so
+// that values cannot be moved between columns.
+func (p *passphrase) encrypt(value, context string) (string, error) {
+	nonce := make([]byte, p.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	b := p.gcm.Seal(nonce, nonce, []byte(value), []byte(context))
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// decrypt decrypts a value created by encrypt.
+func (p *passphrase) decrypt(value, context string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(b) < p.gcm.NonceSize() {
+		return "", errInvalidSecret
+	}
+	n := p.gcm.NonceSize()
+	plaintext, err := p.gcm.Open(nil, b[:n], b[n:], []byte(context))
+	if err != nil {
+		return "", errInvalidSecret
+	}
+	return string(plaintext), nil
+}
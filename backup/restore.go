@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/media"
+)
+
+var (
+	errInvalidArchive = errors.New("the file is not an informas backup")
+	errNotEmpty       = errors.New("backups can only be restored into an empty database")
+)
+
+// readManifest reads the manifest from the start of the archive and ensures
+// that its tables can be restored into the current schema.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	h, err := tr.Next()
+	if err != nil || h.Name != manifestName {
+		return nil, errInvalidArchive
+	}
+	m := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(m); err != nil || m.Format != format {
+		return nil, errInvalidArchive
+	}
+	if m.SchemaVersion > db.SchemaVersion {
+		return nil, fmt.Errorf(
+			"the archive uses schema version %d but only versions up to %d are supported",
+			m.SchemaVersion,
+			db.SchemaVersion,
+		)
+	}
+	return m, nil
+}
+
+// rowReader returns a function that reads rows of a table from the archive,
+// decrypting the credentials if the archive is encrypted.
+func rowReader(r io.Reader, name string, p *passphrase) func() (db.Row, error) {
+	d := json.NewDecoder(bufio.NewReader(r))
+	d.UseNumber()
+	return func() (db.Row, error) {
+		row := db.Row{}
+		if err := d.Decode(&row); err != nil {
+			return nil, err
+		}
+		if p != nil {
+			for _, c := range db.SecretColumns(name) {
+				value, ok := row[c].(string)
+				if !ok || len(value) == 0 {
+					continue
+				}
+				v, err := p.decrypt(value, secretContext(name, c))
+				if err != nil {
+					return nil, err
+				}
+				row[c] = v
+			}
+		}
+		return row, nil
+	}
+}
+
+// Restore loads an archive created by Write into an empty database, which
+// must already be migrated. Credentials are encrypted with the master key as
+// they are inserted. Nothing is written to the database unless the whole
+// archive is restored, though uploaded files may remain in the store if an
+// error occurs.
+func Restore(r io.Reader, store media.Store, passphraseValue string) (*Manifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errInvalidArchive
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	m, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	var p *passphrase
+	if len(m.Salt) != 0 {
+		if len(passphraseValue) == 0 {
+			return nil, errPassphraseRequired
+		}
+		p, err = newPassphrase(passphraseValue, m.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if v, err := p.decrypt(m.Check, manifestName); err != nil || v != checkValue {
+			return nil, errWrongPassphrase
+		}
+	}
+	if err := db.Transaction(func(t *db.Token) error {
+		empty, err := db.IsEmpty(t)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return errNotEmpty
+		}
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case strings.HasPrefix(h.Name, tablePrefix):
+				name := strings.TrimSuffix(strings.TrimPrefix(h.Name, tablePrefix), ".jsonl")
+				if err := db.ImportTable(t, name, rowReader(tr, name, p)); err != nil {
+					return fmt.Errorf("unable to restore %s: %s", name, err)
+				}
+			case strings.HasPrefix(h.Name, mediaPrefix):
+				key := strings.TrimPrefix(h.Name, mediaPrefix)
+				if err := store.Put(key, tr); err != nil {
+					return fmt.Errorf("unable to restore media %s: %s", key, err)
+				}
+			default:
+				return errInvalidArchive
+			}
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,120 @@
+package bluesky
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// chatService is the service that the PDS forwards chat requests to; the
+	// app password must be allowed to access direct messages
+	chatService = "did:web:api.bsky.chat#bsky_chat"
+
+	// messageViewType identifies messages that have not been deleted
+	messageViewType = "chat.bsky.convo.defs#messageView"
+)
+
+// Member describes a participant in a conversation.
+type Member struct {
+	DID    string `json:"did"`
+	Handle string `json:"handle"`
+}
+
+// Message is a direct message in a conversation.
+type Message struct {
+	Type   string `json:"$type"`
+	ID     string `json:"id"`
+	Rev    string `json:"rev"`
+	Text   string `json:"text"`
+	Sender struct {
+		DID string `json:"did"`
+	} `json:"sender"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// Convo is a conversation between the account and one or more members. The
+// revision increases whenever the conversation changes and can be compared
+// lexically.
+type Convo struct {
+	ID      string    `json:"id"`
+	Rev     string    `json:"rev"`
+	Members []*Member `json:"members"`
+}
+
+// chat sends the request to the chat service.
+func (c *Client) chat(req *http.Request, v interface{}) error {
+	req.Header.Set("Atproto-Proxy", chatService)
+	return c.do(req, v)
+}
+
+// ListConvos returns a page of conversations, most recently updated first,
+// along with the cursor for the next page, which is empty on the last page.
+func (c *Client) ListConvos(cursor string, limit int) ([]*Convo, string, error) {
+	var resp struct {
+		Cursor string   `json:"cursor"`
+		Convos []*Convo `json:"convos"`
+	}
+	params := url.Values{"limit": {strconv.Itoa(limit)}}
+	if len(cursor) != 0 {
+		params.Set("cursor", cursor)
+	}
+	req, err := c.newQuery("chat.bsky.convo.listConvos", params)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := c.chat(req, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Convos, resp.Cursor, nil
+}
+
+// GetMessages returns the most recent messages in a conversation, newest
+// first. Deleted messages are omitted.
+func (c *Client) GetMessages(convoID string, limit int) ([]*Message, error) {
+	var resp struct {
+		Messages []*Message `json:"messages"`
+	}
+	req, err := c.newQuery(
+		"chat.bsky.convo.getMessages",
+		url.Values{
+			"convoId": {convoID},
+			"limit":   {strconv.Itoa(limit)},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.chat(req, &resp); err != nil {
+		return nil, err
+	}
+	messages := []*Message{}
+	for _, m := range resp.Messages {
+		if m.Type == messageViewType {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// SendMessage sends a direct message to a conversation.
+func (c *Client) SendMessage(convoID, text string) (*Message, error) {
+	m := &Message{}
+	req, err := c.newProcedure(
+		"chat.bsky.convo.sendMessage",
+		map[string]interface{}{
+			"convoId": convoID,
+			"message": map[string]string{
+				"text": text,
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.chat(req, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,202 @@
+package bluesky
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPDS is the personal data server used when none is specified.
+	DefaultPDS = "https://bsky.social"
+
+	// timeout limits the time taken by each request, including the upload of
+	// a blob.
+	timeout = time.Minute
+)
+
+// Client makes XRPC requests to a personal data server (PDS). Most requests
+// require a session to be created first.
+type Client struct {
+	client     *http.Client
+	pdsURL     string
+	access     string
+	refresh    string
+	did        string
+	handle     string
+	invalidate func()
+}
+
+// NewClient creates a client for the PDS at the specified URL.
+func NewClient(pdsURL string) *Client {
+	if len(pdsURL) == 0 {
+		pdsURL = DefaultPDS
+	}
+	return &Client{
+		client: &http.Client{Timeout: timeout},
+		pdsURL: strings.TrimRight(pdsURL, "/"),
+	}
+}
+
+// Error is returned when the server responds with an error status.
+type Error struct {
+	StatusCode int
+	Name       string `json:"error"`
+	Message    string `json:"message"`
+}
+
+// Error returns a description of the error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("bluesky: %d %s: %s", e.StatusCode, e.Name, e.Message)
+}
+
+// isAuthError determines whether the error indicates that the session is no
+// longer valid.
+func (e *Error) isAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized ||
+		e.Name == "ExpiredToken" ||
+		e.Name == "InvalidToken"
+}
+
+// do sends the request and decodes the JSON response into v.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	if len(c.access) != 0 {
+		req.Header.Set("Authorization", "Bearer "+c.access)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &Error{StatusCode: resp.StatusCode}
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(b, e) != nil || len(e.Name) == 0 {
+			e.Name = http.StatusText(resp.StatusCode)
+		}
+		if c.invalidate != nil && e.isAuthError() {
+			c.invalidate()
+		}
+		return e
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// newQuery creates a request for an XRPC query method with the specified
+// parameters.
+func (c *Client) newQuery(method string, params url.Values) (*http.Request, error) {
+	return http.NewRequest(
+		http.MethodGet,
+		c.pdsURL+"/xrpc/"+method+"?"+params.Encode(),
+		nil,
+	)
+}
+
+// newProcedure creates a request for an XRPC procedure with a JSON body.
+func (c *Client) newProcedure(method string, body interface{}) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.pdsURL+"/xrpc/"+method,
+		bytes.NewReader(b),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// query invokes an XRPC query method with the specified parameters.
+func (c *Client) query(method string, params url.Values, v interface{}) error {
+	req, err := c.newQuery(method, params)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+// procedure invokes an XRPC procedure with a JSON body.
+func (c *Client) procedure(method string, body, v interface{}) error {
+	req, err := c.newProcedure(method, body)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+// CreateSession authenticates using an app password. The identifier may be
+// the account's handle, DID or email address.
+func (c *Client) CreateSession(identifier, appPassword string) error {
+	resp := &sessionResponse{}
+	if err := c.procedure(
+		"com.atproto.server.createSession",
+		map[string]string{
+			"identifier": identifier,
+			"password":   appPassword,
+		},
+		resp,
+	); err != nil {
+		return err
+	}
+	c.setSession(resp)
+	return nil
+}
+
+// RefreshSession obtains new tokens using the refresh token of the current
+// session.
+func (c *Client) RefreshSession() error {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.pdsURL+"/xrpc/com.atproto.server.refreshSession",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	c.access = c.refresh
+	resp := &sessionResponse{}
+	if err := c.do(req, resp); err != nil {
+		c.access = ""
+		return err
+	}
+	c.setSession(resp)
+	return nil
+}
+
+// DID returns the identifier of the account the session belongs to.
+func (c *Client) DID() string {
+	return c.did
+}
+
+// Handle returns the handle of the account the session belongs to.
+func (c *Client) Handle() string {
+	return c.handle
+}
+
+// ResolveHandle looks up the DID for a handle.
+func (c *Client) ResolveHandle(handle string) (string, error) {
+	var resp struct {
+		DID string `json:"did"`
+	}
+	if err := c.query(
+		"com.atproto.identity.resolveHandle",
+		url.Values{"handle": {handle}},
+		&resp,
+	); err != nil {
+		return "", err
+	}
+	return resp.DID, nil
+}
@@ -0,0 +1,69 @@
+package bluesky
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PostView describes a post along with its engagement counts.
+type PostView struct {
+	URI         string    `json:"uri"`
+	CID         string    `json:"cid"`
+	Author      *Author   `json:"author"`
+	Record      *Post     `json:"record"`
+	LikeCount   int       `json:"likeCount"`
+	RepostCount int       `json:"repostCount"`
+	ReplyCount  int       `json:"replyCount"`
+	IndexedAt   time.Time `json:"indexedAt"`
+}
+
+// WebURL returns the address of the post in the Bluesky web app.
+func (p *PostView) WebURL() string {
+	return webURL(p.URI, p.Author)
+}
+
+// GetAuthorFeed returns the most recent posts by the actor, newest first.
+// Reposts and replies are excluded.
+func (c *Client) GetAuthorFeed(actor string, limit int) ([]*PostView, error) {
+	var resp struct {
+		Feed []struct {
+			Post   *PostView   `json:"post"`
+			Reason interface{} `json:"reason"`
+		} `json:"feed"`
+	}
+	if err := c.query(
+		"app.bsky.feed.getAuthorFeed",
+		url.Values{
+			"actor":  {actor},
+			"limit":  {strconv.Itoa(limit)},
+			"filter": {"posts_no_replies"},
+		},
+		&resp,
+	); err != nil {
+		return nil, err
+	}
+	posts := []*PostView{}
+	for _, f := range resp.Feed {
+		if f.Post != nil && f.Reason == nil {
+			posts = append(posts, f.Post)
+		}
+	}
+	return posts, nil
+}
+
+// GetPosts returns the posts with the specified URIs. Up to 25 URIs may be
+// provided and posts that no longer exist are omitted.
+func (c *Client) GetPosts(uris []string) ([]*PostView, error) {
+	var resp struct {
+		Posts []*PostView `json:"posts"`
+	}
+	if err := c.query(
+		"app.bsky.feed.getPosts",
+		url.Values{"uris": uris},
+		&resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Posts, nil
+}
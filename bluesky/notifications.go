@@ -0,0 +1,67 @@
+package bluesky
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification reasons for posts directed at the account.
+const (
+	ReasonMention = "mention"
+	ReasonReply   = "reply"
+)
+
+// Author describes the account that caused a notification.
+type Author struct {
+	DID    string `json:"did"`
+	Handle string `json:"handle"`
+}
+
+// Notification describes an event concerning the account. Record contains
+// the post for mentions and replies.
+type Notification struct {
+	URI       string    `json:"uri"`
+	CID       string    `json:"cid"`
+	Author    *Author   `json:"author"`
+	Reason    string    `json:"reason"`
+	Record    *Post     `json:"record"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+// webURL returns the address of the post with the specified URI in the
+// Bluesky web app.
+func webURL(uri string, author *Author) string {
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if len(parts) != 3 || author == nil {
+		return ""
+	}
+	return "https://bsky.app/profile/" + author.Handle + "/post/" + parts[2]
+}
+
+// WebURL returns the address of the post in the Bluesky web app.
+func (n *Notification) WebURL() string {
+	return webURL(n.URI, n.Author)
+}
+
+// ListNotifications returns a page of notifications, newest first, along with
+// the cursor for the next page, which is empty on the last page.
+func (c *Client) ListNotifications(cursor string, limit int) ([]*Notification, string, error) {
+	var resp struct {
+		Cursor        string          `json:"cursor"`
+		Notifications []*Notification `json:"notifications"`
+	}
+	params := url.Values{"limit": {strconv.Itoa(limit)}}
+	if len(cursor) != 0 {
+		params.Set("cursor", cursor)
+	}
+	if err := c.query(
+		"app.bsky.notification.listNotifications",
+		params,
+		&resp,
+	); err != nil {
+		return nil, "", err
+	}
+	return resp.Notifications, resp.Cursor, nil
+}
@@ -0,0 +1,170 @@
+package bluesky
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// postCollection is the collection that posts are stored in.
+const postCollection = "app.bsky.feed.post"
+
+// StrongRef identifies a specific version of a record.
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// ReplyRef identifies the post being replied to and the start of the thread.
+type ReplyRef struct {
+	Root   StrongRef `json:"root"`
+	Parent StrongRef `json:"parent"`
+}
+
+// ByteSlice indicates the location of a facet in UTF-8 encoded text.
+type ByteSlice struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+// Feature describes a link or mention. Only one of the fields is set.
+type Feature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri,omitempty"`
+	DID  string `json:"did,omitempty"`
+}
+
+// Facet annotates a range of text with a link or mention.
+type Facet struct {
+	Index    ByteSlice `json:"index"`
+	Features []Feature `json:"features"`
+}
+
+// Image is an image embedded in a post.
+type Image struct {
+	Image json.RawMessage `json:"image"`
+	Alt   string          `json:"alt"`
+}
+
+// ImagesEmbed attaches up to four images to a post.
+type ImagesEmbed struct {
+	Type   string   `json:"$type"`
+	Images []*Image `json:"images"`
+}
+
+// Post is an app.bsky.feed.post record.
+type Post struct {
+	Type      string       `json:"$type"`
+	Text      string       `json:"text"`
+	CreatedAt string       `json:"createdAt"`
+	Facets    []*Facet     `json:"facets,omitempty"`
+	Reply     *ReplyRef    `json:"reply,omitempty"`
+	Embed     *ImagesEmbed `json:"embed,omitempty"`
+}
+
+// NewImagesEmbed creates an embed for the specified images.
+func NewImagesEmbed(images []*Image) *ImagesEmbed {
+	return &ImagesEmbed{
+		Type:   "app.bsky.embed.images",
+		Images: images,
+	}
+}
+
+// LinkFacet creates a facet linking the range of text to the URI.
+func LinkFacet(start, end int, uri string) *Facet {
+	return &Facet{
+		Index: ByteSlice{ByteStart: start, ByteEnd: end},
+		Features: []Feature{
+			{Type: "app.bsky.richtext.facet#link", URI: uri},
+		},
+	}
+}
+
+// MentionFacet creates a facet mentioning the account with the DID.
+func MentionFacet(start, end int, did string) *Facet {
+	return &Facet{
+		Index: ByteSlice{ByteStart: start, ByteEnd: end},
+		Features: []Feature{
+			{Type: "app.bsky.richtext.facet#mention", DID: did},
+		},
+	}
+}
+
+// UploadBlob uploads a file and returns the blob reference for embedding it.
+func (c *Client) UploadBlob(r io.Reader, contentType string) (json.RawMessage, error) {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.pdsURL+"/xrpc/com.atproto.repo.uploadBlob",
+		r,
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	var resp struct {
+		Blob json.RawMessage `json:"blob"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Blob, nil
+}
+
+// CreatePost publishes a post to the repository of the current session.
+func (c *Client) CreatePost(p *Post) (*StrongRef, error) {
+	p.Type = postCollection
+	if len(p.CreatedAt) == 0 {
+		p.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	ref := &StrongRef{}
+	if err := c.procedure(
+		"com.atproto.repo.createRecord",
+		map[string]interface{}{
+			"repo":       c.did,
+			"collection": postCollection,
+			"record":     p,
+		},
+		ref,
+	); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// ReplyTo builds the reference needed to reply to the post with the specified
+// AT URI. The root of the thread is taken from the parent if it is itself a
+// reply.
+func (c *Client) ReplyTo(uri string) (*ReplyRef, error) {
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if len(parts) != 3 {
+		return nil, errors.New("bluesky: invalid post URI")
+	}
+	var resp struct {
+		URI   string `json:"uri"`
+		CID   string `json:"cid"`
+		Value struct {
+			Reply *ReplyRef `json:"reply"`
+		} `json:"value"`
+	}
+	if err := c.query(
+		"com.atproto.repo.getRecord",
+		url.Values{
+			"repo":       {parts[0]},
+			"collection": {parts[1]},
+			"rkey":       {parts[2]},
+		},
+		&resp,
+	); err != nil {
+		return nil, err
+	}
+	parent := StrongRef{URI: resp.URI, CID: resp.CID}
+	r := &ReplyRef{Root: parent, Parent: parent}
+	if resp.Value.Reply != nil {
+		r.Root = resp.Value.Reply.Root
+	}
+	return r, nil
+}
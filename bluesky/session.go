@@ -0,0 +1,150 @@
+package bluesky
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before it expires that an access token is
+// refreshed, so that it does not expire during a request.
+const refreshMargin = time.Minute
+
+// sessionResponse is returned when a session is created or refreshed.
+type sessionResponse struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
+}
+
+// setSession uses the tokens from the response for later requests.
+func (c *Client) setSession(resp *sessionResponse) {
+	c.access = resp.AccessJwt
+	c.refresh = resp.RefreshJwt
+	c.did = resp.DID
+	c.handle = resp.Handle
+}
+
+// tokenExpiry reads the expiry time from a JWT without verifying it. The
+// second return value is false if the token has no expiry time.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// session is a cached session for a single account. The app password is
+// stored as a hash so that changes to it can be detected. The mutex is held
+// while the session is created or refreshed so that requests for the same
+// account wait for it instead of creating sessions of their own.
+type session struct {
+	mutex sync.Mutex
+	sessionResponse
+	password string
+}
+
+// expired determines whether the access token of the session should no
+// longer be used.
+func (s *session) expired(now time.Time) bool {
+	t, ok := tokenExpiry(s.AccessJwt)
+	return ok && now.Add(refreshMargin).After(t)
+}
+
+// Sessions caches a session for each account so that app passwords are only
+// used when there is no session or it can no longer be refreshed. The PDS
+// limits how often sessions may be created.
+type Sessions struct {
+	mutex    sync.Mutex
+	sessions map[string]*session
+	now      func() time.Time
+}
+
+// NewSessions creates an empty cache.
+func NewSessions() *Sessions {
+	return &Sessions{
+		sessions: make(map[string]*session),
+		now:      time.Now,
+	}
+}
+
+// defaultSessions is used by Login.
+var defaultSessions = NewSessions()
+
+// Login returns a client for the account using the shared session cache.
+func Login(pdsURL, identifier, appPassword string) (*Client, error) {
+	return defaultSessions.Client(pdsURL, identifier, appPassword)
+}
+
+// get returns the session for the key, adding an empty one if there is none.
+// The cache is only locked while the map is used so that a slow PDS does not
+// hold up other accounts.
+func (s *Sessions) get(key string) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	v, ok := s.sessions[key]
+	if !ok {
+		v = &session{}
+		s.sessions[key] = v
+	}
+	return v
+}
+
+// Client returns a client authenticated as the account. A cached session is
+// used if there is one, refreshing it if the access token has expired, and a
+// new session is created otherwise. Sessions are discarded if the PDS
+// rejects them or the app password changes.
+func (s *Sessions) Client(pdsURL, identifier, appPassword string) (*Client, error) {
+	var (
+		c        = NewClient(pdsURL)
+		v        = s.get(c.pdsURL + " " + identifier)
+		h        = sha256.Sum256([]byte(appPassword))
+		password = base64.StdEncoding.EncodeToString(h[:])
+	)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	valid := len(v.AccessJwt) != 0 && v.password == password
+	if valid {
+		c.setSession(&v.sessionResponse)
+		if v.expired(s.now()) {
+			valid = c.RefreshSession() == nil
+		}
+	}
+	if !valid {
+		v.sessionResponse = sessionResponse{}
+		if err := c.CreateSession(identifier, appPassword); err != nil {
+			return nil, err
+		}
+	}
+	v.password = password
+	v.sessionResponse = sessionResponse{
+		AccessJwt:  c.access,
+		RefreshJwt: c.refresh,
+		DID:        c.did,
+		Handle:     c.handle,
+	}
+	access := c.access
+	c.invalidate = func() {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+		if v.AccessJwt == access {
+			v.sessionResponse = sessionResponse{}
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,267 @@
+package bluesky
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPDS is a PDS that issues numbered tokens and counts the sessions
+// created and refreshed. If block is set, requests to create a session are
+// announced on started and wait until block is closed.
+type testPDS struct {
+	*httptest.Server
+	t          *testing.T
+	password   string
+	expires    time.Time
+	tokens     int
+	created    int
+	refreshed  int
+	refreshOK  bool
+	validToken string
+	started    chan struct{}
+	block      chan struct{}
+}
+
+// token creates an unsigned JWT that expires at the specified time.
+func token(name string, expires time.Time) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"sub": name,
+		"exp": expires.Unix(),
+	})
+	return "e30." + base64.RawURLEncoding.EncodeToString(b) + ".sig"
+}
+
+// issue writes a new session to the response.
+func (p *testPDS) issue(w http.ResponseWriter) {
+	p.tokens++
+	p.validToken = token(fmt.Sprintf("access%d", p.tokens), p.expires)
+	json.NewEncoder(w).Encode(&sessionResponse{
+		AccessJwt:  p.validToken,
+		RefreshJwt: token(fmt.Sprintf("refresh%d", p.tokens), p.expires.Add(24*time.Hour)),
+		DID:        "did:plc:test",
+		Handle:     "test.bsky.social",
+	})
+}
+
+// fail writes an XRPC error to the response.
+func fail(w http.ResponseWriter, status int, name string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": name, "message": name})
+}
+
+func newTestPDS(t *testing.T) *testPDS {
+	p := &testPDS{
+		t:         t,
+		password:  "password",
+		expires:   time.Now().Add(2 * time.Hour),
+		refreshOK: true,
+	}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			if p.block != nil {
+				p.started <- struct{}{}
+				<-p.block
+			}
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["identifier"] != "did:plc:test" || body["password"] != p.password {
+				fail(w, http.StatusUnauthorized, "AuthenticationRequired")
+				return
+			}
+			p.created++
+			p.issue(w)
+		case "/xrpc/com.atproto.server.refreshSession":
+			auth := r.Header.Get("Authorization")
+			if !p.refreshOK || auth != "Bearer "+token(fmt.Sprintf("refresh%d", p.tokens), p.expires.Add(24*time.Hour)) {
+				fail(w, http.StatusBadRequest, "ExpiredToken")
+				return
+			}
+			p.refreshed++
+			p.issue(w)
+		case "/xrpc/com.atproto.identity.resolveHandle":
+			if r.Header.Get("Authorization") != "Bearer "+p.validToken {
+				fail(w, http.StatusBadRequest, "ExpiredToken")
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"did": "did:plc:other"})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// login returns a client from the cache, failing the test on error.
+func (p *testPDS) login(s *Sessions, password string) *Client {
+	c, err := s.Client(p.URL, "did:plc:test", password)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return c
+}
+
+func TestSessionReused(t *testing.T) {
+	var (
+		p = newTestPDS(t)
+		s = NewSessions()
+	)
+	for i := 0; i < 3; i++ {
+		c := p.login(s, "password")
+		if _, err := c.ResolveHandle("other.bsky.social"); err != nil {
+			t.Fatal(err)
+		}
+		if c.DID() != "did:plc:test" || c.Handle() != "test.bsky.social" {
+			t.Fatalf("got session for %s (%s)", c.DID(), c.Handle())
+		}
+	}
+	if p.created != 1 || p.refreshed != 0 {
+		t.Fatalf("created %d and refreshed %d sessions", p.created, p.refreshed)
+	}
+}
+
+func TestSessionRefreshed(t *testing.T) {
+	var (
+		p = newTestPDS(t)
+		s = NewSessions()
+	)
+	p.login(s, "password")
+	s.now = func() time.Time { return p.expires }
+	c := p.login(s, "password")
+	if _, err := c.ResolveHandle("other.bsky.social"); err != nil {
+		t.Fatal(err)
+	}
+	if p.created != 1 || p.refreshed != 1 {
+		t.Fatalf("created %d and refreshed %d sessions", p.created, p.refreshed)
+	}
+}
+
+func TestSessionRefreshFails(t *testing.T) {
+	var (
+		p = newTestPDS(t)
+		s = NewSessions()
+	)
+	p.login(s, "password")
+	s.now = func() time.Time { return p.expires }
+	p.refreshOK = false
+	p.login(s, "password")
+	if p.created != 2 || p.refreshed != 0 {
+		t.Fatalf("created %d and refreshed %d sessions", p.created, p.refreshed)
+	}
+}
+
+func TestSessionPasswordChanged(t *testing.T) {
+	var (
+		p = newTestPDS(t)
+		s = NewSessions()
+	)
+	p.login(s, "password")
+	p.password = "new"
+	p.login(s, "new")
+	if p.created != 2 {
+		t.Fatalf("created %d sessions", p.created)
+	}
+}
+
+func TestSessionRejected(t *testing.T) {
+	var (
+		p = newTestPDS(t)
+		s = NewSessions()
+	)
+	c := p.login(s, "password")
+	p.validToken = ""
+	_, err := c.ResolveHandle("other.bsky.social")
+	if e, ok := err.(*Error); !ok || e.Name != "ExpiredToken" {
+		t.Fatalf("got %v", err)
+	}
+	p.login(s, "password")
+	if p.created != 2 {
+		t.Fatalf("created %d sessions", p.created)
+	}
+}
+
+// blockCreate makes requests to create a session wait until the returned
+// function is called. They are released when the test ends in any case so
+// that the server can be closed.
+func (p *testPDS) blockCreate() func() {
+	var once sync.Once
+	p.started = make(chan struct{}, 2)
+	p.block = make(chan struct{})
+	release := func() { once.Do(func() { close(p.block) }) }
+	p.t.Cleanup(release)
+	return release
+}
+
+// loginAsync logs in on another goroutine and sends the error to the
+// returned channel.
+func (p *testPDS) loginAsync(s *Sessions) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		_, err := s.Client(p.URL, "did:plc:test", "password")
+		ch <- err
+	}()
+	return ch
+}
+
+func TestSessionAccountsIndependent(t *testing.T) {
+	var (
+		p1      = newTestPDS(t)
+		p2      = newTestPDS(t)
+		s       = NewSessions()
+		release = p1.blockCreate()
+		ch      = p1.loginAsync(s)
+	)
+	<-p1.started
+	select {
+	case err := <-p2.loginAsync(s):
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("login waited for another account")
+	}
+	release()
+	if err := <-ch; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionCreatedOnce(t *testing.T) {
+	var (
+		p       = newTestPDS(t)
+		s       = NewSessions()
+		release = p.blockCreate()
+		ch1     = p.loginAsync(s)
+		ch2     = p.loginAsync(s)
+	)
+	<-p.started
+	release()
+	for _, ch := range []<-chan error{ch1, ch2} {
+		if err := <-ch; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.created != 1 {
+		t.Fatalf("created %d sessions", p.created)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	expires := time.Unix(1700000000, 0)
+	if v, ok := tokenExpiry(token("a", expires)); !ok || !v.Equal(expires) {
+		t.Fatalf("got %s", v)
+	}
+	for _, v := range []string{"", "a.b", "a.!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte("{}")) + ".c"} {
+		if _, ok := tokenExpiry(v); ok {
+			t.Errorf("%q has an expiry time", v)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nathan-osman/informas/backup"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/media"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// passphraseEnv is the environment variable that may contain the passphrase
+// for encrypting credentials in backups when no file is specified.
+const passphraseEnv = "INFORMAS_BACKUP_PASSPHRASE"
+
+// passphraseFlag specifies the file containing the backup passphrase.
+var passphraseFlag = cli.StringFlag{
+	Name:  "passphrase-file",
+	Usage: "file containing the passphrase for credentials in the archive",
+}
+
+// noPassphraseFlag allows a backup to be written without a passphrase.
+var noPassphraseFlag = cli.BoolFlag{
+	Name:  "no-passphrase",
+	Usage: "store credentials unencrypted in the archive",
+}
+
+// loadPassphrase reads the passphrase from the file or the environment. An
+// empty string is returned if neither was provided.
+func loadPassphrase(c *cli.Context) (string, error) {
+	if f := c.String("passphrase-file"); len(f) != 0 {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return os.Getenv(passphraseEnv), nil
+}
+
+// prepareBackup connects to the database and loads the master keys and the
+// media store used by both backup and restore.
+func prepareBackup(c *cli.Context) (media.Store, string, error) {
+	if c.NArg() != 1 {
+		return nil, "", fmt.Errorf("usage: informas %s FILE", c.Command.Name)
+	}
+	if err := connect(c); err != nil {
+		return nil, "", err
+	}
+	k, err := loadKeyring(c)
+	if err != nil {
+		return nil, "", err
+	}
+	if k == nil {
+		logrus.Warning("no master key provided; encrypted credentials cannot be read or written")
+	}
+	db.SetKeyring(k)
+	store, err := media.NewDiskStore(path.Join(c.GlobalString("data-dir"), "media"))
+	if err != nil {
+		return nil, "", err
+	}
+	p, err := loadPassphrase(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return store, p, nil
+}
+
+// backupAction writes all data to a new archive.
+func backupAction(c *cli.Context) error {
+	store, p, err := prepareBackup(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if len(p) == 0 {
+		if !c.Bool("no-passphrase") {
+			return cli.NewExitError(
+				fmt.Sprintf(
+					"a passphrase must be provided with --passphrase-file or %s to encrypt credentials in the archive; use --no-passphrase to store them unencrypted",
+					passphraseEnv,
+				),
+				1,
+			)
+		}
+		logrus.Warning("no passphrase provided; access tokens, client secrets and passwords are stored UNENCRYPTED in the archive and must be protected accordingly")
+	}
+	filename := c.Args().First()
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if err := backup.Write(f, store, p); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(filename)
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Printf("backup written to %s\n", filename)
+	return nil
+}
+
+// restoreAction loads an archive into an empty database.
+func restoreAction(c *cli.Context) error {
+	store, p, err := prepareBackup(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	defer f.Close()
+	m, err := backup.Restore(f, store, p)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Printf("restored backup created %s\n", m.Created.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// backupCommand writes all data to an archive.
+var backupCommand = cli.Command{
+	Name:      "backup",
+	Usage:     "write the database and uploaded files to an archive",
+	ArgsUsage: "FILE",
+	Flags:     []cli.Flag{passphraseFlag, noPassphraseFlag},
+	Action:    backupAction,
+}
+
+// restoreCommand loads an archive into an empty database.
+var restoreCommand = cli.Command{
+	Name:      "restore",
+	Usage:     "load an archive created by backup into an empty database",
+	ArgsUsage: "FILE",
+	Flags:     []cli.Flag{passphraseFlag},
+	Action:    restoreAction,
+}
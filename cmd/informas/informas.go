@@ -3,13 +3,30 @@ package main
 import (
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/nathan-osman/informas/db"
 	"github.com/nathan-osman/informas/server"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
+// connect establishes a connection to the database and performs all pending
+// migrations.
+func connect(c *cli.Context) error {
+	if err := db.Connect(
+		c.GlobalString("db-name"),
+		c.GlobalString("db-user"),
+		c.GlobalString("db-password"),
+		c.GlobalString("db-host"),
+		c.GlobalInt("db-port"),
+	); err != nil {
+		return err
+	}
+	return db.Migrate()
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "informas"
@@ -47,6 +64,11 @@ func main() {
 			Value: 5432,
 			Usage: "PostgreSQL database port",
 		},
+		cli.StringFlag{
+			Name:   "master-key-file",
+			EnvVar: "INFORMAS_MASTER_KEY_FILE",
+			Usage:  "file containing the master keys for encrypting credentials",
+		},
 		cli.StringFlag{
 			Name:  "http-addr",
 			Value: ":8000",
@@ -57,46 +79,118 @@ func main() {
 			Value: "data",
 			Usage: "path to data directory",
 		},
+		cli.StringFlag{
+			Name:  "https-addr",
+			Value: ":8443",
+			Usage: "address and port to listen on for HTTPS",
+		},
+		cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "path to TLS certificate (reloaded on SIGHUP or change)",
+		},
+		cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "path to TLS private key",
+		},
+		cli.BoolFlag{
+			Name:  "https-redirect",
+			Usage: "redirect HTTP requests to HTTPS",
+		},
+		cli.BoolFlag{
+			Name:  "hsts",
+			Usage: "send the Strict-Transport-Security header over HTTPS",
+		},
+		cli.StringFlag{
+			Name:  "acme-domains",
+			Usage: "comma-separated domains to obtain certificates for via ACME",
+		},
+		cli.StringFlag{
+			Name:  "acme-directory",
+			Value: "https://acme-v02.api.letsencrypt.org/directory",
+			Usage: "ACME directory URL",
+		},
+		cli.StringFlag{
+			Name:  "acme-email",
+			Usage: "contact email for the ACME account",
+		},
+		cli.StringFlag{
+			Name:  "acme-ca-cert",
+			Usage: "CA certificate for the ACME server (e.g. Pebble)",
+		},
 	}
-	app.Action = func(c *cli.Context) {
+	app.Commands = []cli.Command{
+		keysCommand,
+		backupCommand,
+		restoreCommand,
+	}
+	app.Action = func(c *cli.Context) error {
+
+		// Write structured log entries
+		logrus.SetFormatter(&logrus.JSONFormatter{})
 
-		// Connect to the database
-		if err := db.Connect(
-			c.String("db-name"),
-			c.String("db-user"),
-			c.String("db-password"),
-			c.String("db-host"),
-			c.Int("db-port"),
-		); err != nil {
-			cli.HandleExitCoder(err)
+		// Connect to the database and perform all pending migrations
+		if err := connect(c); err != nil {
+			return cli.NewExitError(err.Error(), 1)
 		}
 
-		// Perform all pending migrations
-		if err := db.Migrate(); err != nil {
-			cli.HandleExitCoder(err)
+		// Load the master keys used to encrypt credentials
+		k, err := loadKeyring(c)
+		if err != nil {
+			logrus.WithError(err).Fatal("unable to load master keys")
+		}
+		if k == nil {
+			logrus.Warning("no master key provided; credentials are stored unencrypted")
+		}
+		db.SetKeyring(k)
+
+		// Enable HTTPS if a certificate or ACME domains were provided
+		var tlsOptions *server.TLSOptions
+		if len(c.String("tls-cert")) != 0 || len(c.String("acme-domains")) != 0 {
+			tlsOptions = &server.TLSOptions{
+				Addr:          c.String("https-addr"),
+				CertFile:      c.String("tls-cert"),
+				KeyFile:       c.String("tls-key"),
+				Redirect:      c.Bool("https-redirect"),
+				HSTS:          c.Bool("hsts"),
+				ACMEDirectory: c.String("acme-directory"),
+				ACMEEmail:     c.String("acme-email"),
+				ACMECACert:    c.String("acme-ca-cert"),
+			}
+			if d := c.String("acme-domains"); len(d) != 0 {
+				tlsOptions.ACMEDomains = strings.Split(d, ",")
+			}
 		}
 
 		// Create the server
 		s, err := server.New(
 			c.String("http-addr"),
 			c.String("data-dir"),
+			tlsOptions,
 		)
 		if err != nil {
-			cli.HandleExitCoder(err)
+			return cli.NewExitError(err.Error(), 1)
 		}
 
 		// Start the server
 		if err := s.Start(); err != nil {
-			cli.HandleExitCoder(err)
+			return cli.NewExitError(err.Error(), 1)
 		}
 
-		// Wait for SIGINT
-		q := make(chan os.Signal)
-		signal.Notify(q, syscall.SIGINT)
-		<-q
+		// Wait for SIGINT, reloading the certificate on SIGHUP
+		q := make(chan os.Signal, 1)
+		signal.Notify(q, syscall.SIGINT, syscall.SIGHUP)
+		for sig := range q {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			if err := s.Reload(); err != nil {
+				logrus.WithError(err).Error("unable to reload certificate")
+			}
+		}
 
 		// Shut everything down
 		s.Stop()
+		return nil
 	}
 	app.Run(os.Args)
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/keyring"
+	"github.com/urfave/cli"
+)
+
+// masterKeyEnv is the environment variable that may contain the master keys
+// when no key file is specified.
+const masterKeyEnv = "INFORMAS_MASTER_KEY"
+
+// loadKeyring reads the master keys from the key file or the environment. Nil
+// is returned if neither was provided.
+func loadKeyring(c *cli.Context) (*keyring.Keyring, error) {
+	if f := c.GlobalString("master-key-file"); len(f) != 0 {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		return keyring.Parse(string(b))
+	}
+	if v := os.Getenv(masterKeyEnv); len(v) != 0 {
+		return keyring.Parse(v)
+	}
+	return nil, nil
+}
+
+// keysGenerate prints a new master key. Its version follows the current key
+// so that it can be appended to the existing keys.
+func keysGenerate(c *cli.Context) error {
+	k, err := loadKeyring(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	version := 1
+	if k != nil {
+		version = k.Current() + 1
+	}
+	key, err := keyring.GenerateKey()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Printf("%d:%s\n", version, key)
+	return nil
+}
+
+// keysRotate re-encrypts all credentials with the current master key.
+func keysRotate(c *cli.Context) error {
+	if err := connect(c); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	k, err := loadKeyring(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if k == nil {
+		return cli.NewExitError(
+			fmt.Sprintf("a master key must be provided with --master-key-file or %s", masterKeyEnv),
+			1,
+		)
+	}
+	db.SetKeyring(k)
+	var n int
+	if err := db.Transaction(func(t *db.Token) error {
+		count, err := db.RotateKeys(t)
+		n = count
+		return err
+	}); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Printf("%d rows encrypted with master key version %d\n", n, k.Current())
+	return nil
+}
+
+// keysCommand manages the master keys used to encrypt credentials.
+var keysCommand = cli.Command{
+	Name:  "keys",
+	Usage: "manage the master keys used to encrypt credentials",
+	Subcommands: []cli.Command{
+		{
+			Name:   "generate",
+			Usage:  "print a new master key to add to the existing keys",
+			Action: keysGenerate,
+		},
+		{
+			Name:   "rotate",
+			Usage:  "re-encrypt all credentials with the newest master key",
+			Action: keysRotate,
+		},
+	},
+}
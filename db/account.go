@@ -0,0 +1,199 @@
+package db
+
+import (
+	"fmt"
+)
+
+// Platforms supported for accounts.
+const (
+	PlatformTwitter  = "twitter"
+	PlatformMastodon = "mastodon"
+	PlatformBluesky  = "bluesky"
+)
+
+// Account represents an account on a social network that tweets can be
+// published to. Instance is the base URL of the server for federated
+// platforms. The meaning of the credentials depends on the platform.
+type Account struct {
+	ID           int
+	Platform     string
+	Instance     string
+	RemoteID     string
+	Username     string
+	AccessToken  string
+	AccessSecret string
+}
+
+// migrateAccountsTable executes the SQL necessary to create the Accounts
+// table. Accounts added before other platforms were supported are Twitter
+// accounts.
+func migrateAccountsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Accounts (
+            ID           SERIAL PRIMARY KEY,
+            Platform     VARCHAR(20) NOT NULL,
+            Instance     VARCHAR(200) NOT NULL,
+            RemoteID     VARCHAR(100) NOT NULL,
+            Username     VARCHAR(100) NOT NULL,
+            AccessToken  TEXT NOT NULL,
+            AccessSecret TEXT NOT NULL,
+            UNIQUE (Platform, Instance, RemoteID)
+        )
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Accounts
+        ADD COLUMN IF NOT EXISTS Platform VARCHAR(20) NOT NULL DEFAULT 'twitter',
+        ADD COLUMN IF NOT EXISTS Instance VARCHAR(200) NOT NULL DEFAULT '',
+        DROP CONSTRAINT IF EXISTS accounts_remoteid_key
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        CREATE UNIQUE INDEX IF NOT EXISTS accounts_platform_instance_remoteid_key
+        ON Accounts (Platform, Instance, RemoteID)
+        `,
+	)
+	return err
+}
+
+// AllAccounts retrieves all accounts.
+func AllAccounts(t *Token) ([]*Account, error) {
+	r, err := t.query(
+		`
+        SELECT ID, Platform, Instance, RemoteID, Username, AccessToken, AccessSecret
+        FROM Accounts ORDER BY Platform, Username
+        `,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	accounts := make([]*Account, 0, 1)
+	for r.Next() {
+		a := &Account{}
+		if err := r.Scan(
+			&a.ID,
+			&a.Platform,
+			&a.Instance,
+			&a.RemoteID,
+			&a.Username,
+			&a.AccessToken,
+			&a.AccessSecret,
+		); err != nil {
+			return nil, err
+		}
+		if err := a.decryptCredentials(); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+// FindAccount attempts to retrieve an account using the specified field.
+func FindAccount(t *Token, field string, value interface{}) (*Account, error) {
+	a := &Account{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT ID, Platform, Instance, RemoteID, Username, AccessToken, AccessSecret
+            FROM Accounts WHERE %s = $1
+            `,
+			field,
+		),
+		value,
+	).Scan(
+		&a.ID,
+		&a.Platform,
+		&a.Instance,
+		&a.RemoteID,
+		&a.Username,
+		&a.AccessToken,
+		&a.AccessSecret,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.decryptCredentials(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// decryptCredentials decrypts the credentials after they are read.
+func (a *Account) decryptCredentials() error {
+	accessToken, err := decryptSecret(a.AccessToken, columnAccessToken)
+	if err != nil {
+		return err
+	}
+	accessSecret, err := decryptSecret(a.AccessSecret, columnAccessSecret)
+	if err != nil {
+		return err
+	}
+	a.AccessToken = accessToken
+	a.AccessSecret = accessSecret
+	return nil
+}
+
+// Save updates the object in the database. If an account for the same remote
+// user already exists, its username and credentials are updated instead of
+// creating a duplicate. The credentials are encrypted if a master key was
+// provided.
+func (a *Account) Save(t *Token) error {
+	accessToken, err := encryptSecret(a.AccessToken, columnAccessToken)
+	if err != nil {
+		return err
+	}
+	accessSecret, err := encryptSecret(a.AccessSecret, columnAccessSecret)
+	if err != nil {
+		return err
+	}
+	if a.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Accounts (Platform, Instance, RemoteID, Username, AccessToken, AccessSecret)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (Platform, Instance, RemoteID)
+            DO UPDATE SET Username=$4, AccessToken=$5, AccessSecret=$6
+            RETURNING ID
+            `,
+			a.Platform,
+			a.Instance,
+			a.RemoteID,
+			a.Username,
+			accessToken,
+			accessSecret,
+		).Scan(&a.ID)
+	}
+	_, err = t.exec(
+		`
+        UPDATE Accounts SET Username=$1, AccessToken=$2, AccessSecret=$3
+        WHERE ID = $4
+        `,
+		a.Username,
+		accessToken,
+		accessSecret,
+		a.ID,
+	)
+	return err
+}
+
+// Delete removes the account.
+func (a *Account) Delete(t *Token) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Accounts WHERE ID = $1
+        `,
+		a.ID,
+	)
+	return err
+}
@@ -0,0 +1,83 @@
+package db
+
+// Application contains the OAuth client credentials issued to Informas by a
+// federated instance. They are obtained once per instance and redirect URI
+// and reused for every account added from it.
+type Application struct {
+	ID           int
+	Platform     string
+	Instance     string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+}
+
+// migrateApplicationsTable executes the SQL necessary to create the
+// Applications table.
+func migrateApplicationsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Applications (
+            ID           SERIAL PRIMARY KEY,
+            Platform     VARCHAR(20) NOT NULL,
+            Instance     VARCHAR(200) NOT NULL,
+            RedirectURI  VARCHAR(500) NOT NULL,
+            ClientID     TEXT NOT NULL,
+            ClientSecret TEXT NOT NULL,
+            UNIQUE (Platform, Instance, RedirectURI)
+        )
+        `,
+	)
+	return err
+}
+
+// FindApplication retrieves the credentials for the specified instance that
+// were registered with the redirect URI.
+func FindApplication(t *Token, platform, instance, redirectURI string) (*Application, error) {
+	a := &Application{}
+	err := t.queryRow(
+		`
+        SELECT ID, Platform, Instance, RedirectURI, ClientID, ClientSecret
+        FROM Applications
+        WHERE Platform = $1 AND Instance = $2 AND RedirectURI = $3
+        `,
+		platform,
+		instance,
+		redirectURI,
+	).Scan(
+		&a.ID,
+		&a.Platform,
+		&a.Instance,
+		&a.RedirectURI,
+		&a.ClientID,
+		&a.ClientSecret,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.ClientSecret, err = decryptSecret(a.ClientSecret, columnClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Save inserts the application into the database. The client secret is
+// encrypted if a master key was provided.
+func (a *Application) Save(t *Token) error {
+	clientSecret, err := encryptSecret(a.ClientSecret, columnClientSecret)
+	if err != nil {
+		return err
+	}
+	return t.queryRow(
+		`
+        INSERT INTO Applications (Platform, Instance, RedirectURI, ClientID, ClientSecret)
+        VALUES ($1, $2, $3, $4, $5) RETURNING ID
+        `,
+		a.Platform,
+		a.Instance,
+		a.RedirectURI,
+		a.ClientID,
+		clientSecret,
+	).Scan(&a.ID)
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SchemaVersion identifies the layout of the tables. It must be incremented
+// whenever a table or column is added so that backups can only be restored
+// into a database that has every column they contain.
+const SchemaVersion = 5
+
+// Row is a single row of a table as exported by ExportTable. Keys are the
+// column names in lowercase.
+type Row map[string]interface{}
+
+// backupSecrets lists the columns of each table that contain credentials.
+var backupSecrets = map[string][]string{
+	"Accounts":     {"accesstoken", "accesssecret"},
+	"Applications": {"clientsecret"},
+	"Config":       {"value"},
+	"Webhooks":     {"secret"},
+}
+
+// Tables returns the names of all tables in the order in which they must be
+// restored.
+func Tables() []string {
+	names := []string{}
+	for _, tbl := range tables {
+		names = append(names, tbl.name)
+	}
+	return names
+}
+
+// SecretColumns returns the columns of the table that contain credentials.
+// Config values are all included since credentials are stored alongside the
+// other settings.
+func SecretColumns(name string) []string {
+	return backupSecrets[name]
+}
+
+// findTable ensures that the name refers to a known table so that it can be
+// used in a query.
+func findTable(name string) error {
+	for _, tbl := range tables {
+		if tbl.name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown table %s", name)
+}
+
+// masterKeyContext returns the context used to encrypt a column of the row
+// with the master key or an empty string if the column is never encrypted.
+func masterKeyContext(name, column string, row Row) string {
+	switch name + "." + column {
+	case "Accounts.accesstoken":
+		return columnAccessToken
+	case "Accounts.accesssecret":
+		return columnAccessSecret
+	case "Applications.clientsecret":
+		return columnClientSecret
+	case "Webhooks.secret":
+		return columnWebhookSecret
+	case "Config.value":
+		key, _ := row["key"].(string)
+		return configColumn(key)
+	}
+	return ""
+}
+
+// BeginSnapshot ensures that all tables exported in the transaction are read
+// from the same point in time. It must be called before any other query.
+func BeginSnapshot(t *Token) error {
+	_, err := t.exec(
+		`
+        SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY
+        `,
+	)
+	return err
+}
+
+// IsEmpty determines whether all of the tables are empty.
+func IsEmpty(t *Token) (bool, error) {
+	for _, tbl := range tables {
+		var exists bool
+		if err := t.queryRow(
+			fmt.Sprintf(
+				`
+                SELECT EXISTS (SELECT 1 FROM %s)
+                `,
+				tbl.name,
+			),
+		).Scan(&exists); err != nil {
+			return false, err
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ExportTable passes each row of the table to the provided callback.
+// Credentials encrypted with the master key are decrypted so that the rows
+// can be restored with a different key.
+func ExportTable(t *Token, name string, f func(Row) error) error {
+	if err := findTable(name); err != nil {
+		return err
+	}
+	r, err := t.query(
+		fmt.Sprintf(
+			`
+            SELECT row_to_json(x)::text FROM %s x
+            `,
+			name,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for r.Next() {
+		var v string
+		if err := r.Scan(&v); err != nil {
+			return err
+		}
+		d := json.NewDecoder(strings.NewReader(v))
+		d.UseNumber()
+		row := Row{}
+		if err := d.Decode(&row); err != nil {
+			return err
+		}
+		for _, c := range backupSecrets[name] {
+			context := masterKeyContext(name, c, row)
+			value, ok := row[c].(string)
+			if len(context) == 0 || !ok {
+				continue
+			}
+			v, err := decryptSecret(value, context)
+			if err != nil {
+				return err
+			}
+			row[c] = v
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// tableColumns retrieves the names of the columns in the table.
+func tableColumns(t *Token, name string) (map[string]bool, error) {
+	r, err := t.query(
+		`
+        SELECT column_name FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = $1
+        `,
+		strings.ToLower(name),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	columns := map[string]bool{}
+	for r.Next() {
+		var c string
+		if err := r.Scan(&c); err != nil {
+			return nil, err
+		}
+		columns[c] = true
+	}
+	return columns, r.Err()
+}
+
+// ImportTable inserts the rows returned by the callback into the table until
+// it returns io.EOF. Account, application and webhook credentials are
+// encrypted with the master key. Configuration values are stored as they are,
+// since Config.ProtectSecret encrypts the credentials among them. The sequence
+// for the ID column is advanced past the rows that were inserted.
+func ImportTable(t *Token, name string, next func() (Row, error)) error {
+	if err := findTable(name); err != nil {
+		return err
+	}
+	columns, err := tableColumns(t, name)
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		names := []string{}
+		for c := range row {
+			if !columns[c] {
+				return fmt.Errorf("column %s.%s does not exist", name, c)
+			}
+			names = append(names, c)
+		}
+		sort.Strings(names)
+		if name != "Config" {
+			for _, c := range backupSecrets[name] {
+				context := masterKeyContext(name, c, row)
+				value, ok := row[c].(string)
+				if len(context) == 0 || !ok {
+					continue
+				}
+				v, err := encryptSecret(value, context)
+				if err != nil {
+					return err
+				}
+				row[c] = v
+			}
+		}
+		b := &bytes.Buffer{}
+		if err := json.NewEncoder(b).Encode(row); err != nil {
+			return err
+		}
+		if _, err := t.exec(
+			fmt.Sprintf(
+				`
+                INSERT INTO %s (%s)
+                SELECT %s FROM json_populate_record(NULL::%s, $1::json)
+                `,
+				name,
+				strings.Join(names, ", "),
+				strings.Join(names, ", "),
+				name,
+			),
+			b.String(),
+		); err != nil {
+			return err
+		}
+	}
+	if !columns["id"] {
+		return nil
+	}
+	var v sql.NullInt64
+	return t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT setval(pg_get_serial_sequence($1, 'id'), COALESCE(MAX(ID), 0) + 1, false)
+            FROM %s
+            `,
+			name,
+		),
+		strings.ToLower(name),
+	).Scan(&v)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	tableRegexp      = regexp.MustCompile(`(?:CREATE TABLE IF NOT EXISTS|ALTER TABLE)\s+(\w+)`)
+	referencesRegexp = regexp.MustCompile(`REFERENCES\s+(\w+)`)
+)
+
+// foreignKeys finds the statements in the package that create or alter a
+// table and returns the tables referenced by each one.
+func foreignKeys(t *testing.T) map[string][]string {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	references := map[string][]string{}
+	ast.Inspect(pkgs["db"], func(n ast.Node) bool {
+		l, ok := n.(*ast.BasicLit)
+		if !ok || l.Kind != token.STRING {
+			return true
+		}
+		v, err := strconv.Unquote(l.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := tableRegexp.FindStringSubmatch(v)
+		if m == nil {
+			return true
+		}
+		refs := references[m[1]]
+		for _, r := range referencesRegexp.FindAllStringSubmatch(v, -1) {
+			refs = append(refs, r[1])
+		}
+		references[m[1]] = refs
+		return true
+	})
+	return references
+}
+
+func TestTablesOrder(t *testing.T) {
+	var (
+		references = foreignKeys(t)
+		position   = map[string]int{}
+	)
+	for i, name := range Tables() {
+		position[name] = i
+	}
+	for name, refs := range references {
+		i, ok := position[name]
+		if !ok {
+			t.Errorf("%s is not in the list of tables", name)
+			continue
+		}
+		for _, r := range refs {
+			if j, ok := position[r]; !ok || j >= i {
+				t.Errorf("%s references %s, which is not created before it", name, r)
+			}
+		}
+	}
+}
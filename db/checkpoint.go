@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// Kinds of checkpoint.
+const (
+	CheckpointMentions = "mentions"
+	CheckpointMessages = "messages"
+)
+
+// migrateCheckpointsTable executes the SQL necessary to create the
+// Checkpoints table, which records how far each account's timelines have been
+// fetched.
+func migrateCheckpointsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Checkpoints (
+            AccountID INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            Kind      VARCHAR(20) NOT NULL,
+            Value     VARCHAR(100) NOT NULL,
+            PRIMARY KEY (AccountID, Kind)
+        )
+        `,
+	)
+	return err
+}
+
+// GetCheckpoint retrieves a checkpoint for the account. An empty string is
+// returned if none has been stored.
+func GetCheckpoint(t *Token, accountID int, kind string) (string, error) {
+	var value string
+	err := t.queryRow(
+		`
+        SELECT Value FROM Checkpoints WHERE AccountID = $1 AND Kind = $2
+        `,
+		accountID,
+		kind,
+	).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetCheckpoint stores a checkpoint for the account.
+func SetCheckpoint(t *Token, accountID int, kind, value string) error {
+	_, err := t.exec(
+		`
+        INSERT INTO Checkpoints (AccountID, Kind, Value) VALUES ($1, $2, $3)
+        ON CONFLICT (AccountID, Kind) DO UPDATE SET Value = $3
+        `,
+		accountID,
+		kind,
+		value,
+	)
+	return err
+}
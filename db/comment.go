@@ -0,0 +1,102 @@
+package db
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Comment is a message in the review discussion of a group. Comments are
+// kept after the group's tweets are published so that the discussion can be
+// read later. Author is the name of the user who wrote the comment, which is
+// kept if the user is deleted, and Mentions contains the IDs of the users
+// mentioned in it.
+type Comment struct {
+	ID       int
+	GroupID  int
+	UserID   int
+	Author   string
+	Text     string
+	Mentions []int
+	Created  time.Time
+}
+
+// migrateCommentsTable executes the SQL necessary to create the Comments
+// table.
+func migrateCommentsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Comments (
+            ID       SERIAL PRIMARY KEY,
+            GroupID  INTEGER NOT NULL REFERENCES TweetGroups (ID) ON DELETE CASCADE,
+            UserID   INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+            Author   VARCHAR(40) NOT NULL,
+            Text     TEXT NOT NULL,
+            Mentions INTEGER[] NOT NULL,
+            Created  TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// GroupComments retrieves the comments on a group, oldest first.
+func GroupComments(t *Token, groupID int) ([]*Comment, error) {
+	r, err := t.query(
+		`
+        SELECT ID, GroupID, COALESCE(UserID, 0), Author, Text, Mentions, Created
+        FROM Comments WHERE GroupID = $1
+        ORDER BY Created, ID
+        `,
+		groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	comments := make([]*Comment, 0, 1)
+	for r.Next() {
+		var (
+			c        = &Comment{}
+			mentions []int64
+		)
+		if err := r.Scan(
+			&c.ID,
+			&c.GroupID,
+			&c.UserID,
+			&c.Author,
+			&c.Text,
+			pq.Array(&mentions),
+			&c.Created,
+		); err != nil {
+			return nil, err
+		}
+		for _, id := range mentions {
+			c.Mentions = append(c.Mentions, int(id))
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+// Save inserts the comment into the database and updates the ID. Comments
+// cannot be changed once written.
+func (c *Comment) Save(t *Token) error {
+	mentions := make([]int64, len(c.Mentions))
+	for i, id := range c.Mentions {
+		mentions[i] = int64(id)
+	}
+	c.Created = time.Now().UTC()
+	return t.queryRow(
+		`
+        INSERT INTO Comments (GroupID, UserID, Author, Text, Mentions, Created)
+        VALUES ($1, $2, $3, $4, $5, $6) RETURNING ID
+        `,
+		c.GroupID,
+		c.UserID,
+		c.Author,
+		c.Text,
+		pq.Array(mentions),
+		c.Created,
+	).Scan(&c.ID)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/base64"
 	"strconv"
 	"sync"
+
+	"github.com/nathan-osman/informas/keyring"
 )
 
 // Config provides access to configuration values for the application. In order
@@ -25,6 +27,15 @@ func migrateConfigTable(t *Token) error {
         )
         `,
 	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Config
+        ADD COLUMN IF NOT EXISTS IsSecret BOOLEAN NOT NULL DEFAULT FALSE
+        `,
+	)
 	return err
 }
 
@@ -51,6 +62,14 @@ func NewConfig(t *Token) (*Config, error) {
 	return c, nil
 }
 
+// Has determines whether a value was stored for the specified key.
+func (c *Config) Has(key string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, ok := c.values[key]
+	return ok
+}
+
 // GetString retrieves the string value for the configuration entry with the
 // specified key.
 func (c *Config) GetString(key string) string {
@@ -82,18 +101,24 @@ func (c *Config) GetInt(key string) int {
 
 // SetString stores a new string value for the specified key.
 func (c *Config) SetString(t *Token, key, value string) error {
+	return c.set(t, key, value, false)
+}
+
+// set stores a new value for the specified key. Keys are marked as secret by
+// SetSecret and remain so even if a value is later stored with SetString.
+func (c *Config) set(t *Token, key, value string, isSecret bool) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	_, err := t.exec(
 		`
-		INSERT INTO Config (Key, Value)
-		VALUES ($1, $2)
-		ON CONFLICT (Key)
-		DO UPDATE SET Value=$1
-        WHERE Config.Key = $2
+        INSERT INTO Config (Key, Value, IsSecret)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (Key)
+        DO UPDATE SET Value=$2, IsSecret=Config.IsSecret OR $3
         `,
 		key,
 		value,
+		isSecret,
 	)
 	if err != nil {
 		return err
@@ -111,3 +136,36 @@ func (c *Config) SetBytes(t *Token, key string, value []byte) error {
 func (c *Config) SetInt(t *Token, key string, value int) error {
 	return c.SetString(t, key, strconv.Itoa(value))
 }
+
+// GetSecret retrieves a credential stored with SetSecret. An error is
+// returned if it cannot be decrypted.
+func (c *Config) GetSecret(key string) (string, error) {
+	return decryptSecret(c.GetString(key), configColumn(key))
+}
+
+// SetSecret stores a credential for the specified key, encrypting it if a
+// master key was provided.
+func (c *Config) SetSecret(t *Token, key, value string) error {
+	v, err := encryptSecret(value, configColumn(key))
+	if err != nil {
+		return err
+	}
+	return c.set(t, key, v, true)
+}
+
+// ProtectSecret marks the key as containing a credential so that it is
+// encrypted when the master keys are rotated and encrypts a value that was
+// stored before a master key was provided.
+func (c *Config) ProtectSecret(t *Token, key string) error {
+	v := c.GetString(key)
+	if keys == nil || len(v) == 0 || keyring.IsEncrypted(v) {
+		_, err := t.exec(
+			`
+            UPDATE Config SET IsSecret=TRUE WHERE Key = $1
+            `,
+			key,
+		)
+		return err
+	}
+	return c.SetSecret(t, key, v)
+}
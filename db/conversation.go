@@ -0,0 +1,202 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Conversation states.
+const (
+	ConversationOpen    = "open"
+	ConversationPending = "pending"
+	ConversationClosed  = "closed"
+)
+
+// Conversation is a thread of direct messages between an account and one or
+// more remote users. Participants lists the handles of the remote users,
+// separated by spaces. AssigneeID is 0 when the conversation has not been
+// assigned to anyone.
+type Conversation struct {
+	ID           int
+	AccountID    int
+	RemoteID     string
+	Participants string
+	Status       string
+	AssigneeID   int
+	Updated      time.Time
+}
+
+// migrateConversationsTable executes the SQL necessary to create the
+// Conversations table.
+func migrateConversationsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Conversations (
+            ID           SERIAL PRIMARY KEY,
+            AccountID    INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            RemoteID     VARCHAR(200) NOT NULL,
+            Participants VARCHAR(500) NOT NULL,
+            Status       VARCHAR(20) NOT NULL,
+            AssigneeID   INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+            Updated      TIMESTAMP NOT NULL,
+            UNIQUE (AccountID, RemoteID)
+        )
+        `,
+	)
+	return err
+}
+
+// conversationColumns lists the columns in the order they are scanned.
+const conversationColumns = `ID, AccountID, RemoteID, Participants, Status,
+            COALESCE(AssigneeID, 0), Updated`
+
+// queryConversations retrieves conversations using the provided query.
+func queryConversations(t *Token, query string, args ...interface{}) ([]*Conversation, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	conversations := make([]*Conversation, 0, 1)
+	for r.Next() {
+		c := &Conversation{}
+		if err := r.Scan(
+			&c.ID,
+			&c.AccountID,
+			&c.RemoteID,
+			&c.Participants,
+			&c.Status,
+			&c.AssigneeID,
+			&c.Updated,
+		); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
+}
+
+// ConversationFilter selects the conversations shown in the message inbox. An
+// AccountID or AssigneeID of 0 and an empty Status match all.
+type ConversationFilter struct {
+	AccountIDs []int
+	AccountID  int
+	AssigneeID int
+	Status     string
+	Limit      int
+}
+
+// FilterConversations retrieves the most recently updated conversations
+// matching the filter. Only conversations of the accounts in AccountIDs are
+// ever returned.
+func FilterConversations(t *Token, f *ConversationFilter) ([]*Conversation, error) {
+	ids := make([]int64, len(f.AccountIDs))
+	for i, id := range f.AccountIDs {
+		ids[i] = int64(id)
+	}
+	return queryConversations(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Conversations
+            WHERE AccountID = ANY($1) AND ($2 = 0 OR AccountID = $2)
+                AND ($3 = 0 OR AssigneeID = $3) AND ($4 = '' OR Status = $4)
+            ORDER BY Updated DESC LIMIT $5
+            `,
+			conversationColumns,
+		),
+		pq.Array(ids),
+		f.AccountID,
+		f.AssigneeID,
+		f.Status,
+		f.Limit,
+	)
+}
+
+// FindConversation attempts to retrieve a conversation using the specified
+// field.
+func FindConversation(t *Token, field string, value interface{}) (*Conversation, error) {
+	c := &Conversation{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Conversations WHERE %s = $1
+            `,
+			conversationColumns,
+			field,
+		),
+		value,
+	).Scan(
+		&c.ID,
+		&c.AccountID,
+		&c.RemoteID,
+		&c.Participants,
+		&c.Status,
+		&c.AssigneeID,
+		&c.Updated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Touch records that a message was added to the conversation at the
+// specified time. If reopen is true, the conversation is set to open since
+// the remote user is waiting for a response.
+func (c *Conversation) Touch(t *Token, created time.Time, reopen bool) error {
+	_, err := t.exec(
+		`
+        UPDATE Conversations
+        SET Updated = GREATEST(Updated, $1),
+            Status = CASE WHEN $2 THEN $3 ELSE Status END
+        WHERE ID = $4
+        `,
+		created,
+		reopen,
+		ConversationOpen,
+		c.ID,
+	)
+	return err
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted unless the conversation was already fetched, in which case only
+// the participants are updated and the ID of the existing row is used.
+func (c *Conversation) Save(t *Token) error {
+	if c.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Conversations (AccountID, RemoteID, Participants, Status,
+                AssigneeID, Updated)
+            VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6)
+            ON CONFLICT (AccountID, RemoteID)
+                DO UPDATE SET Participants = EXCLUDED.Participants
+            RETURNING ID
+            `,
+			c.AccountID,
+			c.RemoteID,
+			c.Participants,
+			c.Status,
+			c.AssigneeID,
+			c.Updated,
+		).Scan(&c.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Conversations SET Participants=$1, Status=$2,
+            AssigneeID=NULLIF($3, 0), Updated=$4
+        WHERE ID = $5
+        `,
+		c.Participants,
+		c.Status,
+		c.AssigneeID,
+		c.Updated,
+		c.ID,
+	)
+	return err
+}
@@ -7,7 +7,10 @@ import (
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB
+var (
+	db       *sql.DB
+	migrated bool
+)
 
 // Connect establishes a connection to the PostgreSQL database used for all SQL
 // queries. This function should be called before using any other types or
@@ -48,18 +51,73 @@ func Transaction(f func(*Token) error) error {
 	return nil
 }
 
+// table describes a table and the function that creates it.
+type table struct {
+	name    string
+	migrate func(*Token) error
+}
+
+// tables lists every table in the order in which they are created, which
+// ensures that referenced tables exist first.
+var tables = []table{
+	{"Config", migrateConfigTable},
+	{"Users", migrateUsersTable},
+	{"Media", migrateMediaTable},
+	{"Accounts", migrateAccountsTable},
+	{"Applications", migrateApplicationsTable},
+	{"Slots", migrateSlotsTable},
+	{"TweetGroups", migrateTweetGroupsTable},
+	{"Tweets", migrateTweetsTable},
+	{"TweetParts", migrateTweetPartsTable},
+	{"Pools", migratePoolsTable},
+	{"PoolPosts", migratePoolPostsTable},
+	{"Webhooks", migrateWebhooksTable},
+	{"Deliveries", migrateDeliveriesTable},
+	{"Notifications", migrateNotificationsTable},
+	{"Grants", migrateGrantsTable},
+	{"Checkpoints", migrateCheckpointsTable},
+	{"Mentions", migrateMentionsTable},
+	{"Conversations", migrateConversationsTable},
+	{"Messages", migrateMessagesTable},
+	{"Posts", migratePostsTable},
+	{"Metrics", migrateMetricsTable},
+	{"Drafts", migrateDraftsTable},
+	{"Revisions", migrateRevisionsTable},
+	{"Reviews", migrateReviewsTable},
+	{"Comments", migrateCommentsTable},
+	{"Stages", migrateStagesTable},
+	{"StageUsers", migrateStageUsersTable},
+	{"TrustedUsers", migrateTrustedUsersTable},
+}
+
 // Migrate performs all database migrations.
 func Migrate() error {
-	tableMigrations := []func(*Token) error{
-		migrateConfigTable,
-		migrateUsersTable,
-	}
-	return Transaction(func(t *Token) error {
-		for _, f := range tableMigrations {
-			if err := f(t); err != nil {
+	err := Transaction(func(t *Token) error {
+		for _, tbl := range tables {
+			if err := tbl.migrate(t); err != nil {
 				return err
 			}
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	migrated = true
+	return nil
+}
+
+// IsMigrated indicates whether all database migrations have been performed.
+func IsMigrated() bool {
+	return migrated
+}
+
+// Ping verifies that the database is still reachable.
+func Ping() error {
+	return db.Ping()
+}
+
+// Stats returns statistics for the database connection pool.
+func Stats() sql.DBStats {
+	return db.Stats()
 }
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"time"
+)
+
+// Delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is a single event queued for sending to a webhook. The outcome of
+// the most recent attempt is recorded for display in the delivery log.
+type Delivery struct {
+	ID           int
+	WebhookID    int
+	Event        string
+	Payload      string
+	State        string
+	Attempts     int
+	NextAttempt  time.Time
+	ResponseCode int
+	Error        string
+	Created      time.Time
+}
+
+// migrateDeliveriesTable executes the SQL necessary to create the Deliveries
+// table.
+func migrateDeliveriesTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Deliveries (
+            ID           SERIAL PRIMARY KEY,
+            WebhookID    INTEGER NOT NULL REFERENCES Webhooks (ID) ON DELETE CASCADE,
+            Event        VARCHAR(40) NOT NULL,
+            Payload      TEXT NOT NULL,
+            State        VARCHAR(20) NOT NULL,
+            Attempts     INTEGER NOT NULL,
+            NextAttempt  TIMESTAMP NOT NULL,
+            ResponseCode INTEGER NOT NULL,
+            Error        TEXT NOT NULL,
+            Created      TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// queryDeliveries retrieves deliveries using the provided query.
+func queryDeliveries(t *Token, query string, args ...interface{}) ([]*Delivery, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	deliveries := make([]*Delivery, 0, 1)
+	for r.Next() {
+		d := &Delivery{}
+		if err := r.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.Event,
+			&d.Payload,
+			&d.State,
+			&d.Attempts,
+			&d.NextAttempt,
+			&d.ResponseCode,
+			&d.Error,
+			&d.Created,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries retrieves pending deliveries whose next attempt is before
+// the specified time, oldest first, and postpones their next attempt until
+// the lease expires. Other workers skip them until then, so the deliveries
+// can be sent without holding a transaction open. If the worker stops before
+// recording the outcome, they are sent again once the lease expires.
+func ClaimDeliveries(t *Token, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	return queryDeliveries(
+		t,
+		`
+        UPDATE Deliveries SET NextAttempt = $3
+        WHERE ID IN (
+            SELECT ID FROM Deliveries
+            WHERE State = $1 AND NextAttempt <= $2
+            ORDER BY NextAttempt LIMIT $4
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ID, WebhookID, Event, Payload, State, Attempts, NextAttempt,
+            ResponseCode, Error, Created
+        `,
+		DeliveryPending,
+		now,
+		now.Add(lease),
+		limit,
+	)
+}
+
+// WebhookDeliveries retrieves the most recent deliveries for a webhook.
+func WebhookDeliveries(t *Token, webhookID, limit int) ([]*Delivery, error) {
+	return queryDeliveries(
+		t,
+		`
+        SELECT ID, WebhookID, Event, Payload, State, Attempts, NextAttempt,
+            ResponseCode, Error, Created
+        FROM Deliveries WHERE WebhookID = $1
+        ORDER BY ID DESC LIMIT $2
+        `,
+		webhookID,
+		limit,
+	)
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated.
+func (d *Delivery) Save(t *Token) error {
+	if d.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Deliveries (WebhookID, Event, Payload, State, Attempts,
+                NextAttempt, ResponseCode, Error, Created)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING ID
+            `,
+			d.WebhookID,
+			d.Event,
+			d.Payload,
+			d.State,
+			d.Attempts,
+			d.NextAttempt,
+			d.ResponseCode,
+			d.Error,
+			d.Created,
+		).Scan(&d.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Deliveries SET State=$1, Attempts=$2, NextAttempt=$3,
+            ResponseCode=$4, Error=$5
+        WHERE ID = $6
+        `,
+		d.State,
+		d.Attempts,
+		d.NextAttempt,
+		d.ResponseCode,
+		d.Error,
+		d.ID,
+	)
+	return err
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Draft is the unsaved content of a form that is kept on the server while
+// the user is still writing. Form contains the encoded values of the compose
+// form or, if TweetID is set, of the form used to edit that tweet.
+type Draft struct {
+	ID      int
+	UserID  int
+	TweetID int
+	Form    string
+	Updated time.Time
+}
+
+// migrateDraftsTable executes the SQL necessary to create the Drafts table.
+func migrateDraftsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Drafts (
+            ID      SERIAL PRIMARY KEY,
+            UserID  INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            TweetID INTEGER REFERENCES Tweets (ID) ON DELETE CASCADE,
+            Form    TEXT NOT NULL,
+            Updated TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// queryDrafts retrieves drafts using the provided query.
+func queryDrafts(t *Token, query string, args ...interface{}) ([]*Draft, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	drafts := make([]*Draft, 0, 1)
+	for r.Next() {
+		d := &Draft{}
+		if err := r.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.TweetID,
+			&d.Form,
+			&d.Updated,
+		); err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, d)
+	}
+	return drafts, nil
+}
+
+// UserDrafts retrieves the drafts of new tweets written by a user, most
+// recently changed first.
+func UserDrafts(t *Token, userID int) ([]*Draft, error) {
+	return queryDrafts(
+		t,
+		`
+        SELECT ID, UserID, COALESCE(TweetID, 0), Form, Updated
+        FROM Drafts WHERE UserID = $1 AND TweetID IS NULL
+        ORDER BY Updated DESC
+        `,
+		userID,
+	)
+}
+
+// FindDraft retrieves a draft of a new tweet written by the user.
+func FindDraft(t *Token, userID, draftID int) (*Draft, error) {
+	drafts, err := queryDrafts(
+		t,
+		`
+        SELECT ID, UserID, COALESCE(TweetID, 0), Form, Updated
+        FROM Drafts WHERE UserID = $1 AND ID = $2 AND TweetID IS NULL
+        `,
+		userID,
+		draftID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return drafts[0], nil
+}
+
+// FindTweetDraft retrieves the user's unsaved changes to a tweet.
+func FindTweetDraft(t *Token, userID, tweetID int) (*Draft, error) {
+	drafts, err := queryDrafts(
+		t,
+		`
+        SELECT ID, UserID, COALESCE(TweetID, 0), Form, Updated
+        FROM Drafts WHERE UserID = $1 AND TweetID = $2
+        ORDER BY ID
+        `,
+		userID,
+		tweetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return drafts[0], nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated. The author and tweet cannot be changed.
+func (d *Draft) Save(t *Token) error {
+	d.Updated = time.Now().UTC()
+	if d.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Drafts (UserID, TweetID, Form, Updated)
+            VALUES ($1, NULLIF($2, 0), $3, $4) RETURNING ID
+            `,
+			d.UserID,
+			d.TweetID,
+			d.Form,
+			d.Updated,
+		).Scan(&d.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Drafts SET Form=$1, Updated=$2
+        WHERE ID = $3
+        `,
+		d.Form,
+		d.Updated,
+		d.ID,
+	)
+	return err
+}
+
+// DeleteDraft removes a draft written by the user.
+func DeleteDraft(t *Token, userID, draftID int) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Drafts WHERE UserID = $1 AND ID = $2
+        `,
+		userID,
+		draftID,
+	)
+	return err
+}
@@ -0,0 +1,167 @@
+package db
+
+// Grant allows a user who is not an administrator to work with an account.
+// Administrators have access to all accounts.
+type Grant struct {
+	AccountID int
+	UserID    int
+}
+
+// migrateGrantsTable executes the SQL necessary to create the Grants table.
+func migrateGrantsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Grants (
+            AccountID INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            UserID    INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            PRIMARY KEY (AccountID, UserID)
+        )
+        `,
+	)
+	return err
+}
+
+// queryAccounts retrieves accounts using the provided query.
+func queryAccounts(t *Token, query string, args ...interface{}) ([]*Account, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	accounts := make([]*Account, 0, 1)
+	for r.Next() {
+		a := &Account{}
+		if err := r.Scan(
+			&a.ID,
+			&a.Platform,
+			&a.Instance,
+			&a.RemoteID,
+			&a.Username,
+			&a.AccessToken,
+			&a.AccessSecret,
+		); err != nil {
+			return nil, err
+		}
+		if err := a.decryptCredentials(); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+// queryUsers retrieves users using the provided query.
+func queryUsers(t *Token, query string, args ...interface{}) ([]*User, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	users := make([]*User, 0, 1)
+	for r.Next() {
+		u := &User{}
+		if err := r.Scan(
+			&u.ID,
+			&u.Username,
+			&u.Password,
+			&u.Email,
+			&u.IsAdmin,
+			&u.IsDisabled,
+			&u.TimeZone,
+			&u.DateFormat,
+			&u.Locale,
+			&u.EmailMode,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// UserAccounts retrieves the accounts the user has access to.
+func UserAccounts(t *Token, u *User) ([]*Account, error) {
+	if u.IsAdmin {
+		return AllAccounts(t)
+	}
+	return queryAccounts(
+		t,
+		`
+        SELECT ID, Platform, Instance, RemoteID, Username, AccessToken, AccessSecret
+        FROM Accounts WHERE ID IN (SELECT AccountID FROM Grants WHERE UserID = $1)
+        ORDER BY Platform, Username
+        `,
+		u.ID,
+	)
+}
+
+// GrantedUsers retrieves the users who are not administrators but have been
+// granted access to the account.
+func GrantedUsers(t *Token, accountID int) ([]*User, error) {
+	return queryUsers(
+		t,
+		`
+        SELECT ID, Username, Password, Email, IsAdmin, IsDisabled, TimeZone, DateFormat, Locale, EmailMode
+        FROM Users WHERE ID IN (SELECT UserID FROM Grants WHERE AccountID = $1)
+        ORDER BY Username
+        `,
+		accountID,
+	)
+}
+
+// AccountUsers retrieves every enabled user with access to the account,
+// including administrators.
+func AccountUsers(t *Token, accountID int) ([]*User, error) {
+	return queryUsers(
+		t,
+		`
+        SELECT ID, Username, Password, Email, IsAdmin, IsDisabled, TimeZone, DateFormat, Locale, EmailMode
+        FROM Users WHERE NOT COALESCE(IsDisabled, FALSE) AND (
+            IsAdmin OR ID IN (SELECT UserID FROM Grants WHERE AccountID = $1)
+        )
+        ORDER BY Username
+        `,
+		accountID,
+	)
+}
+
+// HasAccess determines whether the user may work with the account.
+func HasAccess(t *Token, u *User, accountID int) (bool, error) {
+	if u.IsAdmin {
+		return true, nil
+	}
+	var ok bool
+	err := t.queryRow(
+		`
+        SELECT EXISTS (SELECT 1 FROM Grants WHERE AccountID = $1 AND UserID = $2)
+        `,
+		accountID,
+		u.ID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// Save inserts the grant into the database if it does not already exist.
+func (g *Grant) Save(t *Token) error {
+	_, err := t.exec(
+		`
+        INSERT INTO Grants (AccountID, UserID) VALUES ($1, $2)
+        ON CONFLICT DO NOTHING
+        `,
+		g.AccountID,
+		g.UserID,
+	)
+	return err
+}
+
+// Delete removes the grant.
+func (g *Grant) Delete(t *Token) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Grants WHERE AccountID = $1 AND UserID = $2
+        `,
+		g.AccountID,
+		g.UserID,
+	)
+	return err
+}
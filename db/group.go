@@ -0,0 +1,193 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TweetGroup is a draft posted to one or more accounts. Each account receives
+// its own tweet with an independent status, while approval applies to the
+// group as a whole. Status, Stage, Approvals, Started and Escalated record
+// the progress of the group through the combined approval policy of its
+// accounts.
+type TweetGroup struct {
+	ID        int
+	UserID    int
+	Created   time.Time
+	Status    string
+	Stage     int
+	Approvals []int
+	Started   time.Time
+	Escalated bool
+}
+
+// migrateTweetGroupsTable executes the SQL necessary to create the
+// TweetGroups table.
+func migrateTweetGroupsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS TweetGroups (
+            ID      SERIAL PRIMARY KEY,
+            UserID  INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Created TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE TweetGroups
+        ADD COLUMN IF NOT EXISTS Status    VARCHAR(20) NOT NULL DEFAULT 'approved',
+        ADD COLUMN IF NOT EXISTS Stage     SMALLINT NOT NULL DEFAULT 0,
+        ADD COLUMN IF NOT EXISTS Approvals INTEGER[] NOT NULL DEFAULT '{}',
+        ADD COLUMN IF NOT EXISTS Started   TIMESTAMP NOT NULL DEFAULT '1970-01-01',
+        ADD COLUMN IF NOT EXISTS Escalated BOOLEAN NOT NULL DEFAULT FALSE
+        `,
+	)
+	return err
+}
+
+// migrateGroupStatus sets the status of the groups created before approval
+// policies were introduced from the status of their tweets, so that pending
+// groups still wait for approval and rejected groups stay rejected. It must
+// run after every tweet has been placed in a group.
+func migrateGroupStatus(t *Token) error {
+	_, err := t.exec(
+		`
+        UPDATE TweetGroups SET Status = Tweets.Status, Started = TweetGroups.Created
+        FROM Tweets
+        WHERE Tweets.GroupID = TweetGroups.ID
+        AND Tweets.Status IN ($1, $2)
+        AND TweetGroups.Status = 'approved'
+        AND TweetGroups.Started = '1970-01-01'
+        `,
+		TweetPending,
+		TweetRejected,
+	)
+	return err
+}
+
+// tweetGroupColumns lists the columns in the order they are scanned.
+const tweetGroupColumns = `ID, UserID, Created, Status, Stage, Approvals, Started, Escalated`
+
+// queryTweetGroups retrieves groups using the provided query.
+func queryTweetGroups(t *Token, query string, args ...interface{}) ([]*TweetGroup, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	groups := make([]*TweetGroup, 0, 1)
+	for r.Next() {
+		var (
+			g         = &TweetGroup{}
+			approvals []int64
+		)
+		if err := r.Scan(
+			&g.ID,
+			&g.UserID,
+			&g.Created,
+			&g.Status,
+			&g.Stage,
+			pq.Array(&approvals),
+			&g.Started,
+			&g.Escalated,
+		); err != nil {
+			return nil, err
+		}
+		for _, id := range approvals {
+			g.Approvals = append(g.Approvals, int(id))
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// ClaimPendingTweetGroups retrieves the groups that are waiting for approval
+// and locks them until the transaction ends. Groups locked by another
+// transaction are skipped.
+func ClaimPendingTweetGroups(t *Token, status string) ([]*TweetGroup, error) {
+	return queryTweetGroups(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM TweetGroups WHERE Status = $1
+            ORDER BY ID
+            FOR UPDATE SKIP LOCKED
+            `,
+			tweetGroupColumns,
+		),
+		status,
+	)
+}
+
+// FindTweetGroup retrieves the group with the specified ID, locking it until
+// the transaction ends so that approvals are recorded one at a time.
+func FindTweetGroup(t *Token, id int) (*TweetGroup, error) {
+	groups, err := queryTweetGroups(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM TweetGroups WHERE ID = $1
+            FOR UPDATE
+            `,
+			tweetGroupColumns,
+		),
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return groups[0], nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated. Only the approval state of an existing group
+// can be changed.
+func (g *TweetGroup) Save(t *Token) error {
+	approvals := make([]int64, len(g.Approvals))
+	for i, id := range g.Approvals {
+		approvals[i] = int64(id)
+	}
+	if g.ID == 0 {
+		g.Created = time.Now().UTC()
+		return t.queryRow(
+			`
+            INSERT INTO TweetGroups (UserID, Created, Status, Stage, Approvals,
+                Started, Escalated)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ID
+            `,
+			g.UserID,
+			g.Created,
+			g.Status,
+			g.Stage,
+			pq.Array(approvals),
+			g.Started,
+			g.Escalated,
+		).Scan(&g.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE TweetGroups SET Status=$1, Stage=$2, Approvals=$3, Started=$4,
+            Escalated=$5
+        WHERE ID = $6
+        `,
+		g.Status,
+		g.Stage,
+		pq.Array(approvals),
+		g.Started,
+		g.Escalated,
+		g.ID,
+	)
+	return err
+}
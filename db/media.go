@@ -0,0 +1,111 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Media represents a file uploaded for attaching to a tweet. The contents of
+// the file are kept in a media.Store under the specified key.
+type Media struct {
+	ID          int
+	UserID      int
+	Key         string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	AltText     string
+	Created     time.Time
+}
+
+// migrateMediaTable executes the SQL necessary to create the Media table.
+func migrateMediaTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Media (
+            ID          SERIAL PRIMARY KEY,
+            UserID      INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Key         VARCHAR(80) NOT NULL UNIQUE,
+            ContentType VARCHAR(40) NOT NULL,
+            Size        BIGINT NOT NULL,
+            Width       INTEGER NOT NULL,
+            Height      INTEGER NOT NULL,
+            AltText     VARCHAR(1000) NOT NULL,
+            Created     TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// FindMedia attempts to retrieve media using the specified field.
+func FindMedia(t *Token, field string, value interface{}) (*Media, error) {
+	m := &Media{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT ID, UserID, Key, ContentType, Size, Width, Height, AltText, Created
+            FROM Media WHERE %s = $1
+            `,
+			field,
+		),
+		value,
+	).Scan(
+		&m.ID,
+		&m.UserID,
+		&m.Key,
+		&m.ContentType,
+		&m.Size,
+		&m.Width,
+		&m.Height,
+		&m.AltText,
+		&m.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated.
+func (m *Media) Save(t *Token) error {
+	if m.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Media (UserID, Key, ContentType, Size, Width, Height, AltText, Created)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ID
+            `,
+			m.UserID,
+			m.Key,
+			m.ContentType,
+			m.Size,
+			m.Width,
+			m.Height,
+			m.AltText,
+			m.Created,
+		).Scan(&m.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Media SET AltText=$1
+        WHERE ID = $2
+        `,
+		m.AltText,
+		m.ID,
+	)
+	return err
+}
+
+// Delete removes the media from the database. The caller is responsible for
+// removing the file from the store.
+func (m *Media) Delete(t *Token) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Media WHERE ID = $1
+        `,
+		m.ID,
+	)
+	return err
+}
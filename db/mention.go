@@ -0,0 +1,204 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Mention is a post on a platform that mentions or replies to an account.
+// AssigneeID is 0 when the mention has not been assigned to anyone.
+type Mention struct {
+	ID         int
+	AccountID  int
+	RemoteID   string
+	Author     string
+	Text       string
+	URL        string
+	InReplyTo  string
+	Created    time.Time
+	AssigneeID int
+	IsDone     bool
+}
+
+// migrateMentionsTable executes the SQL necessary to create the Mentions
+// table.
+func migrateMentionsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Mentions (
+            ID         SERIAL PRIMARY KEY,
+            AccountID  INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            RemoteID   VARCHAR(200) NOT NULL,
+            Author     VARCHAR(200) NOT NULL,
+            Text       TEXT NOT NULL,
+            URL        VARCHAR(500) NOT NULL,
+            InReplyTo  VARCHAR(200) NOT NULL,
+            Created    TIMESTAMP NOT NULL,
+            AssigneeID INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+            IsDone     BOOLEAN NOT NULL,
+            UNIQUE (AccountID, RemoteID)
+        )
+        `,
+	)
+	return err
+}
+
+// mentionColumns lists the columns in the order they are scanned.
+const mentionColumns = `ID, AccountID, RemoteID, Author, Text, URL, InReplyTo, Created,
+            COALESCE(AssigneeID, 0), IsDone`
+
+// queryMentions retrieves mentions using the provided query.
+func queryMentions(t *Token, query string, args ...interface{}) ([]*Mention, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	mentions := make([]*Mention, 0, 1)
+	for r.Next() {
+		m := &Mention{}
+		if err := r.Scan(
+			&m.ID,
+			&m.AccountID,
+			&m.RemoteID,
+			&m.Author,
+			&m.Text,
+			&m.URL,
+			&m.InReplyTo,
+			&m.Created,
+			&m.AssigneeID,
+			&m.IsDone,
+		); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, nil
+}
+
+// MentionFilter selects the mentions shown in the inbox. An AccountID or
+// AssigneeID of 0 matches all.
+type MentionFilter struct {
+	AccountIDs []int
+	AccountID  int
+	AssigneeID int
+	IsDone     bool
+	Limit      int
+}
+
+// FilterMentions retrieves the most recent mentions matching the filter.
+// Only mentions of the accounts in AccountIDs are ever returned.
+func FilterMentions(t *Token, f *MentionFilter) ([]*Mention, error) {
+	ids := make([]int64, len(f.AccountIDs))
+	for i, id := range f.AccountIDs {
+		ids[i] = int64(id)
+	}
+	return queryMentions(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Mentions
+            WHERE AccountID = ANY($1) AND ($2 = 0 OR AccountID = $2)
+                AND ($3 = 0 OR AssigneeID = $3) AND IsDone = $4
+            ORDER BY Created DESC LIMIT $5
+            `,
+			mentionColumns,
+		),
+		pq.Array(ids),
+		f.AccountID,
+		f.AssigneeID,
+		f.IsDone,
+		f.Limit,
+	)
+}
+
+// AuthorMentions retrieves the other mentions of an account by the same
+// author, for showing a mention in context.
+func AuthorMentions(t *Token, m *Mention, limit int) ([]*Mention, error) {
+	return queryMentions(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Mentions
+            WHERE AccountID = $1 AND Author = $2 AND ID != $3
+            ORDER BY Created DESC LIMIT $4
+            `,
+			mentionColumns,
+		),
+		m.AccountID,
+		m.Author,
+		m.ID,
+		limit,
+	)
+}
+
+// FindMention attempts to retrieve a mention using the specified field.
+func FindMention(t *Token, field string, value interface{}) (*Mention, error) {
+	m := &Mention{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Mentions WHERE %s = $1
+            `,
+			mentionColumns,
+			field,
+		),
+		value,
+	).Scan(
+		&m.ID,
+		&m.AccountID,
+		&m.RemoteID,
+		&m.Author,
+		&m.Text,
+		&m.URL,
+		&m.InReplyTo,
+		&m.Created,
+		&m.AssigneeID,
+		&m.IsDone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted unless the mention was already fetched. Only the assignee and
+// state of an existing mention can be changed.
+func (m *Mention) Save(t *Token) error {
+	if m.ID == 0 {
+		_, err := t.exec(
+			`
+            INSERT INTO Mentions (AccountID, RemoteID, Author, Text, URL, InReplyTo,
+                Created, AssigneeID, IsDone)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9)
+            ON CONFLICT (AccountID, RemoteID) DO NOTHING
+            `,
+			m.AccountID,
+			m.RemoteID,
+			m.Author,
+			m.Text,
+			m.URL,
+			m.InReplyTo,
+			m.Created,
+			m.AssigneeID,
+			m.IsDone,
+		)
+		return err
+	}
+	_, err := t.exec(
+		`
+        UPDATE Mentions SET AssigneeID=NULLIF($1, 0), IsDone=$2
+        WHERE ID = $3
+        `,
+		m.AssigneeID,
+		m.IsDone,
+		m.ID,
+	)
+	return err
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Message is a direct message in a conversation or an internal note. Notes
+// are only visible in Informas and are never sent. UserID is the user who
+// wrote a note or sent the message from Informas and is 0 for messages that
+// were fetched from the platform.
+type Message struct {
+	ID             int
+	ConversationID int
+	RemoteID       string
+	UserID         int
+	Sender         string
+	Text           string
+	IsInbound      bool
+	IsNote         bool
+	Created        time.Time
+}
+
+// migrateMessagesTable executes the SQL necessary to create the Messages
+// table. Notes do not have a remote ID, which is stored as NULL so that they
+// are not affected by the unique constraint.
+func migrateMessagesTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Messages (
+            ID             SERIAL PRIMARY KEY,
+            ConversationID INTEGER NOT NULL REFERENCES Conversations (ID) ON DELETE CASCADE,
+            RemoteID       VARCHAR(200),
+            UserID         INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+            Sender         VARCHAR(200) NOT NULL,
+            Text           TEXT NOT NULL,
+            IsInbound      BOOLEAN NOT NULL,
+            IsNote         BOOLEAN NOT NULL,
+            Created        TIMESTAMP NOT NULL,
+            UNIQUE (ConversationID, RemoteID)
+        )
+        `,
+	)
+	return err
+}
+
+// ConversationMessages retrieves the messages and notes in a conversation,
+// oldest first.
+func ConversationMessages(t *Token, conversationID int) ([]*Message, error) {
+	r, err := t.query(
+		`
+        SELECT ID, ConversationID, COALESCE(RemoteID, ''), COALESCE(UserID, 0),
+            Sender, Text, IsInbound, IsNote, Created
+        FROM Messages WHERE ConversationID = $1
+        ORDER BY Created, ID
+        `,
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	messages := make([]*Message, 0, 1)
+	for r.Next() {
+		m := &Message{}
+		if err := r.Scan(
+			&m.ID,
+			&m.ConversationID,
+			&m.RemoteID,
+			&m.UserID,
+			&m.Sender,
+			&m.Text,
+			&m.IsInbound,
+			&m.IsNote,
+			&m.Created,
+		); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// Save inserts the message into the database unless it was already fetched,
+// in which case the ID remains 0. Messages cannot be changed once stored.
+func (m *Message) Save(t *Token) error {
+	err := t.queryRow(
+		`
+        INSERT INTO Messages (ConversationID, RemoteID, UserID, Sender, Text,
+            IsInbound, IsNote, Created)
+        VALUES ($1, NULLIF($2, ''), NULLIF($3, 0), $4, $5, $6, $7, $8)
+        ON CONFLICT (ConversationID, RemoteID) DO NOTHING
+        RETURNING ID
+        `,
+		m.ConversationID,
+		m.RemoteID,
+		m.UserID,
+		m.Sender,
+		m.Text,
+		m.IsInbound,
+		m.IsNote,
+		m.Created,
+	).Scan(&m.ID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
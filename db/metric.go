@@ -0,0 +1,174 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Metric is a snapshot of the engagement with a post at a point in time.
+// Replies and Impressions are -1 when the platform does not provide them.
+type Metric struct {
+	PostID      int
+	Recorded    time.Time
+	Likes       int
+	Reposts     int
+	Replies     int
+	Impressions int
+}
+
+// Engagement returns the total number of interactions with the post.
+func (m *Metric) Engagement() int {
+	e := m.Likes + m.Reposts
+	if m.Replies > 0 {
+		e += m.Replies
+	}
+	return e
+}
+
+// migrateMetricsTable executes the SQL necessary to create the Metrics table.
+func migrateMetricsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Metrics (
+            PostID      INTEGER NOT NULL REFERENCES Posts (ID) ON DELETE CASCADE,
+            Recorded    TIMESTAMP NOT NULL,
+            Likes       INTEGER NOT NULL,
+            Reposts     INTEGER NOT NULL,
+            Replies     INTEGER,
+            Impressions INTEGER,
+            PRIMARY KEY (PostID, Recorded)
+        )
+        `,
+	)
+	return err
+}
+
+// metricColumns lists the columns in the order they are scanned.
+const metricColumns = `Metrics.PostID, Metrics.Recorded, Metrics.Likes, Metrics.Reposts,
+            COALESCE(Metrics.Replies, -1), COALESCE(Metrics.Impressions, -1)`
+
+// PostMetrics retrieves the snapshots of a post, oldest first.
+func PostMetrics(t *Token, postID int) ([]*Metric, error) {
+	r, err := t.query(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Metrics WHERE PostID = $1
+            ORDER BY Recorded
+            `,
+			metricColumns,
+		),
+		postID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	metrics := make([]*Metric, 0, 1)
+	for r.Next() {
+		m := &Metric{}
+		if err := r.Scan(
+			&m.PostID,
+			&m.Recorded,
+			&m.Likes,
+			&m.Reposts,
+			&m.Replies,
+			&m.Impressions,
+		); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// PostStats is a post along with its most recent snapshot.
+type PostStats struct {
+	Post   *Post
+	Metric *Metric
+}
+
+// Orders for AccountPostStats.
+const (
+	OrderPublished  = "Published"
+	OrderEngagement = "Engagement"
+)
+
+// AccountPostStats retrieves the posts of an account published after the
+// specified time along with their most recent snapshots. Posts that have not
+// been measured yet are omitted. A limit of 0 returns all posts.
+func AccountPostStats(t *Token, accountID int, since time.Time, order string, limit int) ([]*PostStats, error) {
+	orderBy := "Posts.Published DESC"
+	if order == OrderEngagement {
+		orderBy = "Metrics.Likes + Metrics.Reposts + COALESCE(Metrics.Replies, 0) DESC"
+	}
+	r, err := t.query(
+		fmt.Sprintf(
+			`
+            SELECT %s, %s
+            FROM Posts
+            JOIN (
+                SELECT DISTINCT ON (PostID) * FROM Metrics
+                ORDER BY PostID, Recorded DESC
+            ) AS Metrics ON Metrics.PostID = Posts.ID
+            WHERE Posts.AccountID = $1 AND Posts.Published > $2
+            ORDER BY %s
+            LIMIT NULLIF($3, 0)
+            `,
+			postColumns,
+			metricColumns,
+			orderBy,
+		),
+		accountID,
+		since,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	stats := make([]*PostStats, 0, 1)
+	for r.Next() {
+		s := &PostStats{
+			Post:   &Post{},
+			Metric: &Metric{},
+		}
+		if err := r.Scan(
+			&s.Post.ID,
+			&s.Post.AccountID,
+			&s.Post.RemoteID,
+			&s.Post.Text,
+			&s.Post.URL,
+			&s.Post.Published,
+			&s.Post.NextRefresh,
+			&s.Metric.PostID,
+			&s.Metric.Recorded,
+			&s.Metric.Likes,
+			&s.Metric.Reposts,
+			&s.Metric.Replies,
+			&s.Metric.Impressions,
+		); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// Save inserts the snapshot into the database.
+func (m *Metric) Save(t *Token) error {
+	_, err := t.exec(
+		`
+        INSERT INTO Metrics (PostID, Recorded, Likes, Reposts, Replies, Impressions)
+        VALUES ($1, $2, $3, $4, NULLIF($5, -1), NULLIF($6, -1))
+        ON CONFLICT (PostID, Recorded) DO NOTHING
+        `,
+		m.PostID,
+		m.Recorded,
+		m.Likes,
+		m.Reposts,
+		m.Replies,
+		m.Impressions,
+	)
+	return err
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"time"
+)
+
+// Notification is a message for a user about something that needs their
+// attention. URL is the page the user is taken to when opening it. Failed
+// attempts to email it are counted in EmailAttempts and the next attempt is
+// made after NextEmail.
+type Notification struct {
+	ID            int
+	UserID        int
+	Kind          string
+	Message       string
+	URL           string
+	IsRead        bool
+	IsEmailed     bool
+	EmailAttempts int
+	NextEmail     time.Time
+	Created       time.Time
+}
+
+// migrateNotificationsTable executes the SQL necessary to create the
+// Notifications table.
+func migrateNotificationsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Notifications (
+            ID            SERIAL PRIMARY KEY,
+            UserID        INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Kind          VARCHAR(40) NOT NULL,
+            Message       TEXT NOT NULL,
+            URL           VARCHAR(200) NOT NULL,
+            IsRead        BOOLEAN NOT NULL,
+            IsEmailed     BOOLEAN NOT NULL,
+            EmailAttempts INTEGER NOT NULL DEFAULT 0,
+            NextEmail     TIMESTAMP NOT NULL DEFAULT '1970-01-01',
+            Created       TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// queryNotifications retrieves notifications using the provided query.
+func queryNotifications(t *Token, query string, args ...interface{}) ([]*Notification, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	notifications := make([]*Notification, 0, 1)
+	for r.Next() {
+		n := &Notification{}
+		if err := r.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.Kind,
+			&n.Message,
+			&n.URL,
+			&n.IsRead,
+			&n.IsEmailed,
+			&n.EmailAttempts,
+			&n.NextEmail,
+			&n.Created,
+		); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// UserNotifications retrieves the most recent notifications for a user,
+// optionally limited to those that have not been read.
+func UserNotifications(t *Token, userID int, unreadOnly bool, limit int) ([]*Notification, error) {
+	return queryNotifications(
+		t,
+		`
+        SELECT ID, UserID, Kind, Message, URL, IsRead, IsEmailed, EmailAttempts,
+            NextEmail, Created
+        FROM Notifications WHERE UserID = $1 AND (NOT IsRead OR NOT $2)
+        ORDER BY ID DESC LIMIT $3
+        `,
+		userID,
+		unreadOnly,
+		limit,
+	)
+}
+
+// UnemailedNotifications retrieves notifications that have not yet been
+// considered for email and are due for an attempt, oldest first. The rows are
+// locked until the transaction ends and rows locked by other transactions
+// are skipped.
+func UnemailedNotifications(t *Token, now time.Time) ([]*Notification, error) {
+	return queryNotifications(
+		t,
+		`
+        SELECT ID, UserID, Kind, Message, URL, IsRead, IsEmailed, EmailAttempts,
+            NextEmail, Created
+        FROM Notifications WHERE NOT IsEmailed AND NextEmail <= $1
+        ORDER BY ID
+        FOR UPDATE SKIP LOCKED
+        `,
+		now,
+	)
+}
+
+// UnreadNotificationCount returns the number of unread notifications for a
+// user.
+func UnreadNotificationCount(t *Token, userID int) (int, error) {
+	var count int
+	err := t.queryRow(
+		`
+        SELECT COUNT(*) FROM Notifications WHERE UserID = $1 AND NOT IsRead
+        `,
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead marks all of a user's notifications as read.
+func MarkNotificationsRead(t *Token, userID int) error {
+	_, err := t.exec(
+		`
+        UPDATE Notifications SET IsRead = TRUE WHERE UserID = $1
+        `,
+		userID,
+	)
+	return err
+}
+
+// FindNotification retrieves a notification belonging to a user.
+func FindNotification(t *Token, userID, id int) (*Notification, error) {
+	n := &Notification{}
+	err := t.queryRow(
+		`
+        SELECT ID, UserID, Kind, Message, URL, IsRead, IsEmailed, EmailAttempts,
+            NextEmail, Created
+        FROM Notifications WHERE UserID = $1 AND ID = $2
+        `,
+		userID,
+		id,
+	).Scan(
+		&n.ID,
+		&n.UserID,
+		&n.Kind,
+		&n.Message,
+		&n.URL,
+		&n.IsRead,
+		&n.IsEmailed,
+		&n.EmailAttempts,
+		&n.NextEmail,
+		&n.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated. Only IsRead is updated for existing rows,
+// since the mailer changes the email columns with SaveEmail.
+func (n *Notification) Save(t *Token) error {
+	if n.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Notifications (UserID, Kind, Message, URL, IsRead, IsEmailed, Created)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ID
+            `,
+			n.UserID,
+			n.Kind,
+			n.Message,
+			n.URL,
+			n.IsRead,
+			n.IsEmailed,
+			n.Created,
+		).Scan(&n.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Notifications SET IsRead=$1
+        WHERE ID = $2
+        `,
+		n.IsRead,
+		n.ID,
+	)
+	return err
+}
+
+// SaveEmail updates the columns that record whether the notification was
+// emailed.
+func (n *Notification) SaveEmail(t *Token) error {
+	_, err := t.exec(
+		`
+        UPDATE Notifications SET IsEmailed=$1, EmailAttempts=$2, NextEmail=$3
+        WHERE ID = $4
+        `,
+		n.IsEmailed,
+		n.EmailAttempts,
+		n.NextEmail,
+		n.ID,
+	)
+	return err
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Pool is an evergreen queue for an account. Each time the schedule fires,
+// one of its posts is published. A post is not repeated until MinInterval
+// hours have passed since it was last published. Schedule is a cron
+// expression or a phrase understood by the schedule package, interpreted in
+// TimeZone unless it names a time zone itself.
+type Pool struct {
+	ID          int
+	AccountID   int
+	UserID      int
+	Name        string
+	Schedule    string
+	TimeZone    string
+	MinInterval int
+	NextRun     time.Time
+}
+
+// PoolPost is a post in an evergreen pool. LastSent is the zero time if the
+// post has never been published.
+type PoolPost struct {
+	ID       int
+	PoolID   int
+	Text     string
+	LastSent time.Time
+}
+
+// migratePoolsTable executes the SQL necessary to create the Pools table.
+func migratePoolsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Pools (
+            ID          SERIAL PRIMARY KEY,
+            AccountID   INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            UserID      INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Name        VARCHAR(100) NOT NULL,
+            Schedule    VARCHAR(200) NOT NULL,
+            TimeZone    VARCHAR(40) NOT NULL,
+            MinInterval INTEGER NOT NULL,
+            NextRun     TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// migratePoolPostsTable executes the SQL necessary to create the PoolPosts
+// table.
+func migratePoolPostsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS PoolPosts (
+            ID       SERIAL PRIMARY KEY,
+            PoolID   INTEGER NOT NULL REFERENCES Pools (ID) ON DELETE CASCADE,
+            Text     TEXT NOT NULL,
+            LastSent TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// queryPools retrieves pools using the provided query.
+func queryPools(t *Token, query string, args ...interface{}) ([]*Pool, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	pools := make([]*Pool, 0, 1)
+	for r.Next() {
+		p := &Pool{}
+		if err := r.Scan(
+			&p.ID,
+			&p.AccountID,
+			&p.UserID,
+			&p.Name,
+			&p.Schedule,
+			&p.TimeZone,
+			&p.MinInterval,
+			&p.NextRun,
+		); err != nil {
+			return nil, err
+		}
+		pools = append(pools, p)
+	}
+	return pools, nil
+}
+
+// AccountPools retrieves the evergreen pools for an account.
+func AccountPools(t *Token, accountID int) ([]*Pool, error) {
+	return queryPools(
+		t,
+		`
+        SELECT ID, AccountID, UserID, Name, Schedule, TimeZone, MinInterval, NextRun
+        FROM Pools WHERE AccountID = $1
+        ORDER BY Name
+        `,
+		accountID,
+	)
+}
+
+// ClaimDuePools retrieves the pools whose schedule has fired and locks them
+// until the transaction ends. Pools locked by another transaction are
+// skipped.
+func ClaimDuePools(t *Token, now time.Time) ([]*Pool, error) {
+	return queryPools(
+		t,
+		`
+        SELECT ID, AccountID, UserID, Name, Schedule, TimeZone, MinInterval, NextRun
+        FROM Pools WHERE NextRun <= $1
+        ORDER BY NextRun
+        FOR UPDATE SKIP LOCKED
+        `,
+		now,
+	)
+}
+
+// FindPool retrieves the pool with the specified ID from an account.
+func FindPool(t *Token, accountID, poolID int) (*Pool, error) {
+	pools, err := queryPools(
+		t,
+		`
+        SELECT ID, AccountID, UserID, Name, Schedule, TimeZone, MinInterval, NextRun
+        FROM Pools WHERE AccountID = $1 AND ID = $2
+        `,
+		accountID,
+		poolID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return pools[0], nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated.
+func (p *Pool) Save(t *Token) error {
+	if p.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Pools (AccountID, UserID, Name, Schedule, TimeZone,
+                MinInterval, NextRun)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ID
+            `,
+			p.AccountID,
+			p.UserID,
+			p.Name,
+			p.Schedule,
+			p.TimeZone,
+			p.MinInterval,
+			p.NextRun,
+		).Scan(&p.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Pools SET Name=$1, Schedule=$2, TimeZone=$3, MinInterval=$4,
+            NextRun=$5
+        WHERE ID = $6
+        `,
+		p.Name,
+		p.Schedule,
+		p.TimeZone,
+		p.MinInterval,
+		p.NextRun,
+		p.ID,
+	)
+	return err
+}
+
+// DeletePool removes the pool with the specified ID from an account.
+func DeletePool(t *Token, accountID, poolID int) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Pools WHERE AccountID = $1 AND ID = $2
+        `,
+		accountID,
+		poolID,
+	)
+	return err
+}
+
+// PoolPosts retrieves the posts in a pool, starting with those that have
+// gone the longest without being published.
+func PoolPosts(t *Token, poolID int) ([]*PoolPost, error) {
+	r, err := t.query(
+		`
+        SELECT ID, PoolID, Text, LastSent
+        FROM PoolPosts WHERE PoolID = $1
+        ORDER BY LastSent, ID
+        `,
+		poolID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	posts := make([]*PoolPost, 0, 1)
+	for r.Next() {
+		p := &PoolPost{}
+		if err := r.Scan(
+			&p.ID,
+			&p.PoolID,
+			&p.Text,
+			&p.LastSent,
+		); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated.
+func (p *PoolPost) Save(t *Token) error {
+	if p.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO PoolPosts (PoolID, Text, LastSent)
+            VALUES ($1, $2, $3) RETURNING ID
+            `,
+			p.PoolID,
+			p.Text,
+			p.LastSent,
+		).Scan(&p.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE PoolPosts SET Text=$1, LastSent=$2
+        WHERE ID = $3
+        `,
+		p.Text,
+		p.LastSent,
+		p.ID,
+	)
+	return err
+}
+
+// DeletePoolPost removes the post with the specified ID from a pool.
+func DeletePoolPost(t *Token, poolID, postID int) error {
+	_, err := t.exec(
+		`
+        DELETE FROM PoolPosts WHERE PoolID = $1 AND ID = $2
+        `,
+		poolID,
+		postID,
+	)
+	return err
+}
+
+// RecentTexts retrieves the text of the first part of each tweet for an
+// account that was scheduled after the specified time and not rejected.
+func RecentTexts(t *Token, accountID int, since time.Time) ([]string, error) {
+	r, err := t.query(
+		`
+        SELECT TweetParts.Text
+        FROM TweetParts JOIN Tweets ON TweetParts.TweetID = Tweets.ID
+        WHERE Tweets.AccountID = $1 AND Tweets.Scheduled > $2 AND Tweets.Status != $3
+            AND TweetParts.Position = 1
+        `,
+		accountID,
+		since,
+		TweetRejected,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	texts := []string{}
+	for r.Next() {
+		var v string
+		if err := r.Scan(&v); err != nil {
+			return nil, err
+		}
+		texts = append(texts, v)
+	}
+	return texts, r.Err()
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Post is a post published by an account whose engagement is tracked.
+// NextRefresh is the time at which its metrics should next be retrieved.
+type Post struct {
+	ID          int
+	AccountID   int
+	RemoteID    string
+	Text        string
+	URL         string
+	Published   time.Time
+	NextRefresh time.Time
+}
+
+// migratePostsTable executes the SQL necessary to create the Posts table.
+func migratePostsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Posts (
+            ID          SERIAL PRIMARY KEY,
+            AccountID   INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            RemoteID    VARCHAR(200) NOT NULL,
+            Text        TEXT NOT NULL,
+            URL         VARCHAR(500) NOT NULL,
+            Published   TIMESTAMP NOT NULL,
+            NextRefresh TIMESTAMP NOT NULL,
+            UNIQUE (AccountID, RemoteID)
+        )
+        `,
+	)
+	return err
+}
+
+// postColumns lists the columns in the order they are scanned.
+const postColumns = `Posts.ID, Posts.AccountID, Posts.RemoteID, Posts.Text, Posts.URL,
+            Posts.Published, Posts.NextRefresh`
+
+// DuePosts retrieves posts of the account published after the specified time
+// whose metrics need to be retrieved, oldest refresh first.
+func DuePosts(t *Token, accountID int, since time.Time, limit int) ([]*Post, error) {
+	r, err := t.query(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Posts
+            WHERE AccountID = $1 AND Published > $2 AND NextRefresh <= $3
+            ORDER BY NextRefresh LIMIT $4
+            `,
+			postColumns,
+		),
+		accountID,
+		since,
+		time.Now().UTC(),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	posts := make([]*Post, 0, 1)
+	for r.Next() {
+		p := &Post{}
+		if err := r.Scan(
+			&p.ID,
+			&p.AccountID,
+			&p.RemoteID,
+			&p.Text,
+			&p.URL,
+			&p.Published,
+			&p.NextRefresh,
+		); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, nil
+}
+
+// FindPost attempts to retrieve a post using the specified field.
+func FindPost(t *Token, field string, value interface{}) (*Post, error) {
+	p := &Post{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Posts WHERE %s = $1
+            `,
+			postColumns,
+			field,
+		),
+		value,
+	).Scan(
+		&p.ID,
+		&p.AccountID,
+		&p.RemoteID,
+		&p.Text,
+		&p.URL,
+		&p.Published,
+		&p.NextRefresh,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted unless the post is already tracked. Only the text and the time of
+// the next refresh of an existing post can be changed.
+func (p *Post) Save(t *Token) error {
+	if p.ID == 0 {
+		_, err := t.exec(
+			`
+            INSERT INTO Posts (AccountID, RemoteID, Text, URL, Published, NextRefresh)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (AccountID, RemoteID) DO NOTHING
+            `,
+			p.AccountID,
+			p.RemoteID,
+			p.Text,
+			p.URL,
+			p.Published,
+			p.NextRefresh,
+		)
+		return err
+	}
+	_, err := t.exec(
+		`
+        UPDATE Posts SET Text=$1, NextRefresh=$2 WHERE ID = $3
+        `,
+		p.Text,
+		p.NextRefresh,
+		p.ID,
+	)
+	return err
+}
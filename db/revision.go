@@ -0,0 +1,146 @@
+package db
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Revision is the text of each part of a tweet as saved by a user. A
+// revision is recorded when the tweet is written and each time it is edited
+// or restored.
+type Revision struct {
+	ID      int
+	TweetID int
+	UserID  int
+	Created time.Time
+	Texts   []string
+}
+
+// Review records when a user last approved or rejected a group so that they
+// can be shown what changed since then.
+type Review struct {
+	GroupID  int
+	UserID   int
+	Reviewed time.Time
+}
+
+// migrateRevisionsTable executes the SQL necessary to create the Revisions
+// table.
+func migrateRevisionsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Revisions (
+            ID      SERIAL PRIMARY KEY,
+            TweetID INTEGER NOT NULL REFERENCES Tweets (ID) ON DELETE CASCADE,
+            UserID  INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Created TIMESTAMP NOT NULL,
+            Texts   TEXT[] NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// migrateReviewsTable executes the SQL necessary to create the Reviews
+// table.
+func migrateReviewsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Reviews (
+            GroupID  INTEGER NOT NULL REFERENCES TweetGroups (ID) ON DELETE CASCADE,
+            UserID   INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Reviewed TIMESTAMP NOT NULL,
+            PRIMARY KEY (GroupID, UserID)
+        )
+        `,
+	)
+	return err
+}
+
+// TweetRevisions retrieves the revisions of a tweet, oldest first.
+func TweetRevisions(t *Token, tweetID int) ([]*Revision, error) {
+	r, err := t.query(
+		`
+        SELECT ID, TweetID, UserID, Created, Texts
+        FROM Revisions WHERE TweetID = $1
+        ORDER BY ID
+        `,
+		tweetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	revisions := make([]*Revision, 0, 1)
+	for r.Next() {
+		rev := &Revision{}
+		if err := r.Scan(
+			&rev.ID,
+			&rev.TweetID,
+			&rev.UserID,
+			&rev.Created,
+			pq.Array(&rev.Texts),
+		); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// Save inserts the revision into the database and updates the ID. Created is
+// set to the current time unless it was provided. Revisions cannot be
+// changed once recorded.
+func (rev *Revision) Save(t *Token) error {
+	if rev.Created.IsZero() {
+		rev.Created = time.Now().UTC()
+	}
+	return t.queryRow(
+		`
+        INSERT INTO Revisions (TweetID, UserID, Created, Texts)
+        VALUES ($1, $2, $3, $4) RETURNING ID
+        `,
+		rev.TweetID,
+		rev.UserID,
+		rev.Created,
+		pq.Array(rev.Texts),
+	).Scan(&rev.ID)
+}
+
+// FindReview retrieves the time at which the user last reviewed a group.
+func FindReview(t *Token, groupID, userID int) (*Review, error) {
+	rv := &Review{}
+	err := t.queryRow(
+		`
+        SELECT GroupID, UserID, Reviewed
+        FROM Reviews WHERE GroupID = $1 AND UserID = $2
+        `,
+		groupID,
+		userID,
+	).Scan(
+		&rv.GroupID,
+		&rv.UserID,
+		&rv.Reviewed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Save records the review, replacing any earlier review of the group by the
+// same user.
+func (rv *Review) Save(t *Token) error {
+	_, err := t.exec(
+		`
+        INSERT INTO Reviews (GroupID, UserID, Reviewed)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (GroupID, UserID) DO UPDATE SET Reviewed = $3
+        `,
+		rv.GroupID,
+		rv.UserID,
+		rv.Reviewed,
+	)
+	return err
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/nathan-osman/informas/keyring"
+)
+
+// Columns containing credentials, which are used as the context when
+// encrypting them so that values cannot be copied between columns.
+const (
+	columnAccessToken   = "Accounts.AccessToken"
+	columnAccessSecret  = "Accounts.AccessSecret"
+	columnClientSecret  = "Applications.ClientSecret"
+	columnWebhookSecret = "Webhooks.Secret"
+)
+
+// configColumn returns the context for encrypting a configuration value.
+func configColumn(key string) string {
+	return "Config." + key
+}
+
+// keys encrypts credentials before they are written to the database. If it is
+// nil, credentials are stored as they are.
+var keys *keyring.Keyring
+
+// errNoMasterKey is returned when an encrypted value is read without a
+// master key.
+var errNoMasterKey = errors.New("a master key is required to read encrypted credentials")
+
+// SetKeyring sets the master keys used to encrypt credentials. This function
+// should be called before any credentials are read or written.
+func SetKeyring(k *keyring.Keyring) {
+	keys = k
+}
+
+// encryptSecret encrypts a credential for the specified column. Empty values
+// are stored as they are.
+func encryptSecret(value, column string) (string, error) {
+	if keys == nil || len(value) == 0 {
+		return value, nil
+	}
+	return keys.Encrypt(value, column)
+}
+
+// decryptSecret decrypts a credential read from the specified column.
+func decryptSecret(value, column string) (string, error) {
+	if !keyring.IsEncrypted(value) {
+		return value, nil
+	}
+	if keys == nil {
+		return "", errNoMasterKey
+	}
+	return keys.Decrypt(value, column)
+}
+
+// rotateSecret decrypts a credential and encrypts it with the current master
+// key. The second return value is false if the value does not need to change.
+func rotateSecret(value, column string) (string, bool, error) {
+	if len(value) == 0 || keyring.KeyVersion(value) == keys.Current() {
+		return value, false, nil
+	}
+	v, err := decryptSecret(value, column)
+	if err != nil {
+		return "", false, err
+	}
+	v, err = keys.Encrypt(v, column)
+	if err != nil {
+		return "", false, err
+	}
+	return v, true, nil
+}
+
+// rotateAccounts re-encrypts the credentials of all accounts.
+func rotateAccounts(t *Token) (int, error) {
+	r, err := t.query(
+		`
+        SELECT ID, AccessToken, AccessSecret FROM Accounts FOR UPDATE
+        `,
+	)
+	if err != nil {
+		return 0, err
+	}
+	accounts := []*Account{}
+	for r.Next() {
+		a := &Account{}
+		if err := r.Scan(&a.ID, &a.AccessToken, &a.AccessSecret); err != nil {
+			r.Close()
+			return 0, err
+		}
+		accounts = append(accounts, a)
+	}
+	r.Close()
+	n := 0
+	for _, a := range accounts {
+		accessToken, c1, err := rotateSecret(a.AccessToken, columnAccessToken)
+		if err != nil {
+			return 0, err
+		}
+		accessSecret, c2, err := rotateSecret(a.AccessSecret, columnAccessSecret)
+		if err != nil {
+			return 0, err
+		}
+		if !c1 && !c2 {
+			continue
+		}
+		if _, err := t.exec(
+			`
+            UPDATE Accounts SET AccessToken=$1, AccessSecret=$2 WHERE ID = $3
+            `,
+			accessToken,
+			accessSecret,
+			a.ID,
+		); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// rotateApplications re-encrypts the client secrets of all applications.
+func rotateApplications(t *Token) (int, error) {
+	r, err := t.query(
+		`
+        SELECT ID, ClientSecret FROM Applications FOR UPDATE
+        `,
+	)
+	if err != nil {
+		return 0, err
+	}
+	secrets := map[int]string{}
+	for r.Next() {
+		var (
+			id     int
+			secret string
+		)
+		if err := r.Scan(&id, &secret); err != nil {
+			r.Close()
+			return 0, err
+		}
+		secrets[id] = secret
+	}
+	r.Close()
+	n := 0
+	for id, secret := range secrets {
+		secret, changed, err := rotateSecret(secret, columnClientSecret)
+		if err != nil {
+			return 0, err
+		}
+		if !changed {
+			continue
+		}
+		if _, err := t.exec(
+			`
+            UPDATE Applications SET ClientSecret=$1 WHERE ID = $2
+            `,
+			secret,
+			id,
+		); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// rotateConfig encrypts configuration values that were stored with
+// Config.SetSecret or marked by Config.ProtectSecret.
+func rotateConfig(t *Token) (int, error) {
+	r, err := t.query(
+		`
+        SELECT Key, Value FROM Config WHERE IsSecret OR Value LIKE 'enc:%' FOR UPDATE
+        `,
+	)
+	if err != nil {
+		return 0, err
+	}
+	values := map[string]string{}
+	for r.Next() {
+		var key, value string
+		if err := r.Scan(&key, &value); err != nil {
+			r.Close()
+			return 0, err
+		}
+		values[key] = value
+	}
+	r.Close()
+	n := 0
+	for key, value := range values {
+		value, changed, err := rotateSecret(value, configColumn(key))
+		if err != nil {
+			return 0, err
+		}
+		if !changed {
+			continue
+		}
+		if _, err := t.exec(
+			`
+            UPDATE Config SET Value=$1 WHERE Key = $2
+            `,
+			value,
+			key,
+		); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// rotateWebhooks re-encrypts the secrets used to sign webhook requests.
+func rotateWebhooks(t *Token) (int, error) {
+	r, err := t.query(
+		`
+        SELECT ID, Secret FROM Webhooks FOR UPDATE
+        `,
+	)
+	if err != nil {
+		return 0, err
+	}
+	secrets := map[int]string{}
+	for r.Next() {
+		var (
+			id     int
+			secret string
+		)
+		if err := r.Scan(&id, &secret); err != nil {
+			r.Close()
+			return 0, err
+		}
+		secrets[id] = secret
+	}
+	r.Close()
+	n := 0
+	for id, secret := range secrets {
+		secret, changed, err := rotateSecret(secret, columnWebhookSecret)
+		if err != nil {
+			return 0, err
+		}
+		if !changed {
+			continue
+		}
+		if _, err := t.exec(
+			`
+            UPDATE Webhooks SET Secret=$1 WHERE ID = $2
+            `,
+			secret,
+			id,
+		); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RotateKeys encrypts all credentials that are not yet encrypted with the
+// current master key, including those stored before encryption was enabled.
+// The number of rows that changed is returned.
+func RotateKeys(t *Token) (int, error) {
+	if keys == nil {
+		return 0, errNoMasterKey
+	}
+	n := 0
+	for _, f := range []func(*Token) (int, error){
+		rotateAccounts,
+		rotateApplications,
+		rotateConfig,
+		rotateWebhooks,
+	} {
+		count, err := f(t)
+		if err != nil {
+			return 0, err
+		}
+		n += count
+	}
+	return n, nil
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nathan-osman/informas/keyring"
+)
+
+// testKeyrings creates a keyring with a key of version 1 and another with the
+// same key and a new key of version 2, as used before and after rotation.
+func testKeyrings(t *testing.T) (*keyring.Keyring, *keyring.Keyring) {
+	entries := []string{}
+	for _, v := range []int{1, 2} {
+		key, err := keyring.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, fmt.Sprintf("%d:%s", v, key))
+	}
+	k1, err := keyring.Parse(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := keyring.Parse(strings.Join(entries, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k1, k2
+}
+
+// setTestKeyring uses the keyring until the test finishes.
+func setTestKeyring(t *testing.T, k *keyring.Keyring) {
+	old := keys
+	SetKeyring(k)
+	t.Cleanup(func() { SetKeyring(old) })
+}
+
+func TestRotateSecret(t *testing.T) {
+	k1, k2 := testKeyrings(t)
+	setTestKeyring(t, k1)
+	old, err := encryptSecret("secret", columnAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err := rotateSecret(old, columnAccessToken); err != nil || changed {
+		t.Fatalf("value with the current key changed: %v", err)
+	}
+	SetKeyring(k2)
+	for _, v := range []string{"secret", old} {
+		rotated, changed, err := rotateSecret(v, columnAccessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed || keyring.KeyVersion(rotated) != 2 {
+			t.Fatalf("%q was not rotated", v)
+		}
+		if v, err := decryptSecret(rotated, columnAccessToken); err != nil || v != "secret" {
+			t.Fatalf("got %q, %v", v, err)
+		}
+	}
+	if _, changed, err := rotateSecret("", columnAccessToken); err != nil || changed {
+		t.Fatal("empty value changed")
+	}
+	if _, err := decryptSecret(old, columnAccessSecret); err == nil {
+		t.Fatal("value was decrypted for another column")
+	}
+}
+
+// testDatabase connects to the PostgreSQL database named by
+// INFORMAS_TEST_DB_NAME, skipping the test if it is not set. The database must
+// be empty since every table is truncated when the test finishes.
+func testDatabase(t *testing.T) {
+	name := os.Getenv("INFORMAS_TEST_DB_NAME")
+	if len(name) == 0 {
+		t.Skip("INFORMAS_TEST_DB_NAME is not set")
+	}
+	host := os.Getenv("INFORMAS_TEST_DB_HOST")
+	if len(host) == 0 {
+		host = "localhost"
+	}
+	if err := Connect(
+		name,
+		os.Getenv("INFORMAS_TEST_DB_USER"),
+		os.Getenv("INFORMAS_TEST_DB_PASSWORD"),
+		host,
+		5432,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := IsEmpty(&Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty {
+		t.Fatalf("%s is not empty", name)
+	}
+	t.Cleanup(func() {
+		if _, err := (&Token{}).exec(
+			fmt.Sprintf(
+				"TRUNCATE %s RESTART IDENTITY CASCADE",
+				strings.Join(Tables(), ", "),
+			),
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// storedToken reads the access token of the account as it is stored.
+func storedToken(t *testing.T, id int) string {
+	var v string
+	if err := (&Token{}).queryRow(
+		`
+        SELECT AccessToken FROM Accounts WHERE ID = $1
+        `,
+		id,
+	).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRotateKeys(t *testing.T) {
+	testDatabase(t)
+	k1, k2 := testKeyrings(t)
+	setTestKeyring(t, nil)
+	a := &Account{
+		Platform:     PlatformMastodon,
+		Instance:     "https://example.com",
+		RemoteID:     "1",
+		Username:     "test",
+		AccessToken:  "token",
+		AccessSecret: "secret",
+	}
+	if err := a.Save(&Token{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RotateKeys(&Token{}); err != errNoMasterKey {
+		t.Fatalf("got %v without a master key", err)
+	}
+	for i, k := range []*keyring.Keyring{k1, k2} {
+		SetKeyring(k)
+		n, err := RotateKeys(&Token{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("rotated %d rows", n)
+		}
+		if v := storedToken(t, a.ID); keyring.KeyVersion(v) != i+1 {
+			t.Fatalf("%q is not encrypted with version %d", v, i+1)
+		}
+		stored, err := FindAccount(&Token{}, "ID", a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.AccessToken != "token" || stored.AccessSecret != "secret" {
+			t.Fatalf("got %q and %q", stored.AccessToken, stored.AccessSecret)
+		}
+	}
+	if n, err := RotateKeys(&Token{}); err != nil || n != 0 {
+		t.Fatalf("rotated %d rows again: %v", n, err)
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+// Slot is a weekly time at which an account publishes queued tweets. The
+// time is interpreted in the slot's time zone.
+type Slot struct {
+	ID        int
+	AccountID int
+	Weekday   int
+	Hour      int
+	Minute    int
+	TimeZone  string
+}
+
+// migrateSlotsTable executes the SQL necessary to create the Slots table.
+func migrateSlotsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Slots (
+            ID        SERIAL PRIMARY KEY,
+            AccountID INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            Weekday   SMALLINT NOT NULL,
+            Hour      SMALLINT NOT NULL,
+            Minute    SMALLINT NOT NULL,
+            TimeZone  VARCHAR(40) NOT NULL,
+            UNIQUE (AccountID, Weekday, Hour, Minute)
+        )
+        `,
+	)
+	return err
+}
+
+// AccountSlots retrieves the slots for an account in chronological order.
+func AccountSlots(t *Token, accountID int) ([]*Slot, error) {
+	r, err := t.query(
+		`
+        SELECT ID, AccountID, Weekday, Hour, Minute, TimeZone
+        FROM Slots WHERE AccountID = $1
+        ORDER BY Weekday, Hour, Minute
+        `,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	slots := make([]*Slot, 0, 1)
+	for r.Next() {
+		s := &Slot{}
+		if err := r.Scan(
+			&s.ID,
+			&s.AccountID,
+			&s.Weekday,
+			&s.Hour,
+			&s.Minute,
+			&s.TimeZone,
+		); err != nil {
+			return nil, err
+		}
+		slots = append(slots, s)
+	}
+	return slots, nil
+}
+
+// Save inserts the slot into the database.
+func (s *Slot) Save(t *Token) error {
+	return t.queryRow(
+		`
+        INSERT INTO Slots (AccountID, Weekday, Hour, Minute, TimeZone)
+        VALUES ($1, $2, $3, $4, $5) RETURNING ID
+        `,
+		s.AccountID,
+		s.Weekday,
+		s.Hour,
+		s.Minute,
+		s.TimeZone,
+	).Scan(&s.ID)
+}
+
+// DeleteSlot removes the slot with the specified ID from an account.
+func DeleteSlot(t *Token, accountID, slotID int) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Slots WHERE AccountID = $1 AND ID = $2
+        `,
+		accountID,
+		slotID,
+	)
+	return err
+}
@@ -0,0 +1,238 @@
+package db
+
+// Stage is a step in the approval policy of an account. Tweets pass through
+// the stages in order of position. Approvers may approve the stage and, once
+// it has waited for EscalateHours, so may the users in EscalateTo. An
+// EscalateHours of 0 disables escalation.
+type Stage struct {
+	ID            int
+	AccountID     int
+	Position      int
+	Name          string
+	Required      int
+	EscalateHours int
+	Approvers     []int
+	EscalateTo    []int
+}
+
+// migrateStagesTable executes the SQL necessary to create the Stages table.
+func migrateStagesTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Stages (
+            ID            SERIAL PRIMARY KEY,
+            AccountID     INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            Position      INTEGER NOT NULL,
+            Name          VARCHAR(100) NOT NULL,
+            Required      SMALLINT NOT NULL,
+            EscalateHours INTEGER NOT NULL
+        )
+        `,
+	)
+	return err
+}
+
+// migrateStageUsersTable executes the SQL necessary to create the StageUsers
+// table, which contains the approvers and escalation users of each stage.
+func migrateStageUsersTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS StageUsers (
+            StageID      INTEGER NOT NULL REFERENCES Stages (ID) ON DELETE CASCADE,
+            UserID       INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            IsEscalation BOOLEAN NOT NULL,
+            PRIMARY KEY (StageID, UserID)
+        )
+        `,
+	)
+	return err
+}
+
+// migrateTrustedUsersTable executes the SQL necessary to create the
+// TrustedUsers table, which contains the users whose tweets for an account
+// do not require approval.
+func migrateTrustedUsersTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS TrustedUsers (
+            AccountID INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            UserID    INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            PRIMARY KEY (AccountID, UserID)
+        )
+        `,
+	)
+	return err
+}
+
+// AccountStages retrieves the approval stages for an account in order,
+// including their users.
+func AccountStages(t *Token, accountID int) ([]*Stage, error) {
+	r, err := t.query(
+		`
+        SELECT ID, AccountID, Position, Name, Required, EscalateHours
+        FROM Stages WHERE AccountID = $1
+        ORDER BY Position
+        `,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stages := make([]*Stage, 0, 1)
+	byID := map[int]*Stage{}
+	for r.Next() {
+		s := &Stage{}
+		if err := r.Scan(
+			&s.ID,
+			&s.AccountID,
+			&s.Position,
+			&s.Name,
+			&s.Required,
+			&s.EscalateHours,
+		); err != nil {
+			r.Close()
+			return nil, err
+		}
+		stages = append(stages, s)
+		byID[s.ID] = s
+	}
+	r.Close()
+	r, err = t.query(
+		`
+        SELECT StageUsers.StageID, StageUsers.UserID, StageUsers.IsEscalation
+        FROM StageUsers
+        INNER JOIN Stages ON Stages.ID = StageUsers.StageID
+        INNER JOIN Users ON Users.ID = StageUsers.UserID
+        WHERE Stages.AccountID = $1
+        ORDER BY Users.Username
+        `,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for r.Next() {
+		var (
+			stageID, userID int
+			isEscalation    bool
+		)
+		if err := r.Scan(&stageID, &userID, &isEscalation); err != nil {
+			return nil, err
+		}
+		s := byID[stageID]
+		if isEscalation {
+			s.EscalateTo = append(s.EscalateTo, userID)
+		} else {
+			s.Approvers = append(s.Approvers, userID)
+		}
+	}
+	return stages, nil
+}
+
+// DeleteStage removes an approval stage from an account.
+func DeleteStage(t *Token, accountID, id int) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Stages WHERE AccountID = $1 AND ID = $2
+        `,
+		accountID,
+		id,
+	)
+	return err
+}
+
+// Save inserts the stage after the existing stages of the account, along
+// with its users. Users listed as approvers are not also added for
+// escalation.
+func (s *Stage) Save(t *Token) error {
+	if err := t.queryRow(
+		`
+        INSERT INTO Stages (AccountID, Position, Name, Required, EscalateHours)
+        SELECT $1, COALESCE(MAX(Position), 0) + 1, $2, $3, $4
+        FROM Stages WHERE AccountID = $1
+        RETURNING ID, Position
+        `,
+		s.AccountID,
+		s.Name,
+		s.Required,
+		s.EscalateHours,
+	).Scan(&s.ID, &s.Position); err != nil {
+		return err
+	}
+	for _, id := range s.Approvers {
+		if err := s.addUser(t, id, false); err != nil {
+			return err
+		}
+	}
+	for _, id := range s.EscalateTo {
+		if err := s.addUser(t, id, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addUser adds a user to the stage unless they were already added.
+func (s *Stage) addUser(t *Token, userID int, isEscalation bool) error {
+	_, err := t.exec(
+		`
+        INSERT INTO StageUsers (StageID, UserID, IsEscalation) VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING
+        `,
+		s.ID,
+		userID,
+		isEscalation,
+	)
+	return err
+}
+
+// TrustedUsers retrieves the IDs of users whose tweets for the account do
+// not require approval.
+func TrustedUsers(t *Token, accountID int) ([]int, error) {
+	r, err := t.query(
+		`
+        SELECT UserID FROM TrustedUsers WHERE AccountID = $1
+        `,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	ids := []int{}
+	for r.Next() {
+		var id int
+		if err := r.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SetTrustedUsers replaces the users whose tweets for the account do not
+// require approval.
+func SetTrustedUsers(t *Token, accountID int, userIDs []int) error {
+	if _, err := t.exec(
+		`
+        DELETE FROM TrustedUsers WHERE AccountID = $1
+        `,
+		accountID,
+	); err != nil {
+		return err
+	}
+	for _, id := range userIDs {
+		if _, err := t.exec(
+			`
+            INSERT INTO TrustedUsers (AccountID, UserID) VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+            `,
+			accountID,
+			id,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tweet states.
+const (
+	TweetPending   = "pending"
+	TweetScheduled = "scheduled"
+	TweetSent      = "sent"
+	TweetFailed    = "failed"
+	TweetRejected  = "rejected"
+)
+
+// Tweet is a single tweet or a thread written for an account as part of a
+// group. Once the group is approved, its parts are published in order after
+// the scheduled time, each replying to the one before it. When a part cannot
+// be published the remaining parts are held back; Attempts and NextAttempt
+// track the automatic retries and a failed tweet resumes from the first part
+// that was not published. If InReplyTo is set, the first part replies to that
+// remote post. Visibility and ContentWarning apply to every part on platforms
+// that support them and are ignored elsewhere.
+type Tweet struct {
+	ID             int
+	GroupID        int
+	AccountID      int
+	UserID         int
+	InReplyTo      string
+	Visibility     string
+	ContentWarning string
+	Status         string
+	Scheduled      time.Time
+	Attempts       int
+	NextAttempt    time.Time
+	Error          string
+	Created        time.Time
+	Updated        time.Time
+}
+
+// TweetPart is one tweet in a thread. RemoteID is set once the part has been
+// published.
+type TweetPart struct {
+	ID       int
+	TweetID  int
+	Position int
+	Text     string
+	MediaIDs []int
+	RemoteID string
+}
+
+// migrateTweetsTable executes the SQL necessary to create the Tweets table.
+func migrateTweetsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Tweets (
+            ID          SERIAL PRIMARY KEY,
+            AccountID   INTEGER NOT NULL REFERENCES Accounts (ID) ON DELETE CASCADE,
+            UserID      INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+            Status      VARCHAR(20) NOT NULL,
+            Scheduled   TIMESTAMP NOT NULL,
+            Attempts    INTEGER NOT NULL,
+            NextAttempt TIMESTAMP NOT NULL,
+            Error       TEXT NOT NULL,
+            Created     TIMESTAMP NOT NULL,
+            Updated     TIMESTAMP NOT NULL
+        )
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Tweets
+        ADD COLUMN IF NOT EXISTS GroupID        INTEGER REFERENCES TweetGroups (ID) ON DELETE CASCADE,
+        ADD COLUMN IF NOT EXISTS InReplyTo      VARCHAR(200) NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS Visibility     VARCHAR(20) NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS ContentWarning TEXT NOT NULL DEFAULT ''
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	if err := migrateTweetGroups(t); err != nil {
+		return err
+	}
+	if err := migrateGroupStatus(t); err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Tweets ALTER COLUMN GroupID SET NOT NULL
+        `,
+	)
+	return err
+}
+
+// migrateTweetGroups places each tweet written before groups were introduced
+// in a group of its own, created by the tweet's author at the same time.
+func migrateTweetGroups(t *Token) error {
+	r, err := t.query(
+		`
+        SELECT ID, UserID, Created
+        FROM Tweets WHERE GroupID IS NULL
+        ORDER BY ID
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tweets := []*Tweet{}
+	for r.Next() {
+		tw := &Tweet{}
+		if err := r.Scan(&tw.ID, &tw.UserID, &tw.Created); err != nil {
+			return err
+		}
+		tweets = append(tweets, tw)
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	for _, tw := range tweets {
+		if err := t.queryRow(
+			`
+            INSERT INTO TweetGroups (UserID, Created)
+            VALUES ($1, $2) RETURNING ID
+            `,
+			tw.UserID,
+			tw.Created,
+		).Scan(&tw.GroupID); err != nil {
+			return err
+		}
+		if _, err := t.exec(
+			`
+            UPDATE Tweets SET GroupID = $1 WHERE ID = $2
+            `,
+			tw.GroupID,
+			tw.ID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTweetPartsTable executes the SQL necessary to create the TweetParts
+// table.
+func migrateTweetPartsTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS TweetParts (
+            ID       SERIAL PRIMARY KEY,
+            TweetID  INTEGER NOT NULL REFERENCES Tweets (ID) ON DELETE CASCADE,
+            Position SMALLINT NOT NULL,
+            Text     TEXT NOT NULL,
+            MediaIDs INTEGER[] NOT NULL,
+            RemoteID VARCHAR(200) NOT NULL,
+            UNIQUE (TweetID, Position)
+        )
+        `,
+	)
+	return err
+}
+
+// tweetColumns lists the columns in the order they are scanned.
+const tweetColumns = `ID, GroupID, AccountID, UserID, InReplyTo, Visibility,
+            ContentWarning, Status, Scheduled, Attempts, NextAttempt, Error,
+            Created, Updated`
+
+// queryTweets retrieves tweets using the provided query.
+func queryTweets(t *Token, query string, args ...interface{}) ([]*Tweet, error) {
+	r, err := t.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tweets := make([]*Tweet, 0, 1)
+	for r.Next() {
+		tw := &Tweet{}
+		if err := r.Scan(
+			&tw.ID,
+			&tw.GroupID,
+			&tw.AccountID,
+			&tw.UserID,
+			&tw.InReplyTo,
+			&tw.Visibility,
+			&tw.ContentWarning,
+			&tw.Status,
+			&tw.Scheduled,
+			&tw.Attempts,
+			&tw.NextAttempt,
+			&tw.Error,
+			&tw.Created,
+			&tw.Updated,
+		); err != nil {
+			return nil, err
+		}
+		tweets = append(tweets, tw)
+	}
+	return tweets, nil
+}
+
+// ClaimTweets retrieves scheduled tweets that are due to be published before
+// the specified time, oldest first, and postpones their next attempt until
+// the lease expires so that other workers skip them. If the worker stops
+// before recording the outcome, the remaining parts are published once the
+// lease expires.
+func ClaimTweets(t *Token, now time.Time, lease time.Duration, limit int) ([]*Tweet, error) {
+	return queryTweets(
+		t,
+		fmt.Sprintf(
+			`
+            UPDATE Tweets SET NextAttempt = $3
+            WHERE ID IN (
+                SELECT ID FROM Tweets
+                WHERE Status = $1 AND Scheduled <= $2 AND NextAttempt <= $2
+                ORDER BY Scheduled LIMIT $4
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING %s
+            `,
+			tweetColumns,
+		),
+		TweetScheduled,
+		now,
+		now.Add(lease),
+		limit,
+	)
+}
+
+// RenewLease postpones the next attempt of a claimed tweet until the
+// specified time and updates NextAttempt. The lease is only renewed if no
+// other worker has claimed the tweet since, which is detected by comparing
+// NextAttempt; sql.ErrNoRows is returned if the lease has been lost.
+func (tw *Tweet) RenewLease(t *Token, until time.Time) error {
+	return t.queryRow(
+		`
+        UPDATE Tweets SET NextAttempt = $1
+        WHERE ID = $2 AND Status = $3 AND NextAttempt = $4
+        RETURNING NextAttempt
+        `,
+		until,
+		tw.ID,
+		TweetScheduled,
+		tw.NextAttempt,
+	).Scan(&tw.NextAttempt)
+}
+
+// RecentTweets retrieves the most recent tweets for the specified accounts.
+func RecentTweets(t *Token, accountIDs []int, limit int) ([]*Tweet, error) {
+	ids := make([]int64, len(accountIDs))
+	for i, id := range accountIDs {
+		ids[i] = int64(id)
+	}
+	return queryTweets(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Tweets WHERE AccountID = ANY($1)
+            ORDER BY Created DESC LIMIT $2
+            `,
+			tweetColumns,
+		),
+		pq.Array(ids),
+		limit,
+	)
+}
+
+// ReplyTweets retrieves the tweets written for an account in reply to the
+// remote post.
+func ReplyTweets(t *Token, accountID int, remoteID string) ([]*Tweet, error) {
+	return queryTweets(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Tweets WHERE AccountID = $1 AND InReplyTo = $2
+            ORDER BY Created
+            `,
+			tweetColumns,
+		),
+		accountID,
+		remoteID,
+	)
+}
+
+// GroupTweets retrieves the tweets in a group.
+func GroupTweets(t *Token, groupID int) ([]*Tweet, error) {
+	return queryTweets(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Tweets WHERE GroupID = $1
+            ORDER BY ID
+            `,
+			tweetColumns,
+		),
+		groupID,
+	)
+}
+
+// AccountTweets retrieves the tweets for an account that are scheduled within
+// the specified period, in the order they are scheduled. Rejected tweets are
+// not included.
+func AccountTweets(t *Token, accountID int, from, to time.Time) ([]*Tweet, error) {
+	return queryTweets(
+		t,
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Tweets
+            WHERE AccountID = $1 AND Scheduled >= $2 AND Scheduled < $3 AND Status != $4
+            ORDER BY Scheduled
+            `,
+			tweetColumns,
+		),
+		accountID,
+		from,
+		to,
+		TweetRejected,
+	)
+}
+
+// QueuedTimes retrieves the times after the specified one at which tweets
+// for an account are waiting to be published.
+func QueuedTimes(t *Token, accountID int, after time.Time) ([]time.Time, error) {
+	r, err := t.query(
+		`
+        SELECT Scheduled FROM Tweets
+        WHERE AccountID = $1 AND Scheduled > $2 AND Status IN ($3, $4)
+        `,
+		accountID,
+		after,
+		TweetPending,
+		TweetScheduled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	times := []time.Time{}
+	for r.Next() {
+		var v time.Time
+		if err := r.Scan(&v); err != nil {
+			return nil, err
+		}
+		times = append(times, v)
+	}
+	return times, r.Err()
+}
+
+// QueueDepth returns the number of tweets that are waiting for approval and
+// the number waiting to be published.
+func QueueDepth(t *Token) (int, int, error) {
+	var pending, scheduled int
+	err := t.queryRow(
+		`
+        SELECT
+            COUNT(*) FILTER (WHERE Status = $1),
+            COUNT(*) FILTER (WHERE Status = $2)
+        FROM Tweets
+        `,
+		TweetPending,
+		TweetScheduled,
+	).Scan(&pending, &scheduled)
+	return pending, scheduled, err
+}
+
+// FindTweet attempts to retrieve a tweet using the specified field.
+func FindTweet(t *Token, field string, value interface{}) (*Tweet, error) {
+	tw := &Tweet{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT %s
+            FROM Tweets WHERE %s = $1
+            `,
+			tweetColumns,
+			field,
+		),
+		value,
+	).Scan(
+		&tw.ID,
+		&tw.GroupID,
+		&tw.AccountID,
+		&tw.UserID,
+		&tw.InReplyTo,
+		&tw.Visibility,
+		&tw.ContentWarning,
+		&tw.Status,
+		&tw.Scheduled,
+		&tw.Attempts,
+		&tw.NextAttempt,
+		&tw.Error,
+		&tw.Created,
+		&tw.Updated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated. The group, account and author cannot be
+// changed.
+func (tw *Tweet) Save(t *Token) error {
+	tw.Updated = time.Now().UTC()
+	if tw.ID == 0 {
+		tw.Created = tw.Updated
+		return t.queryRow(
+			`
+            INSERT INTO Tweets (GroupID, AccountID, UserID, InReplyTo, Visibility,
+                ContentWarning, Status, Scheduled, Attempts, NextAttempt, Error,
+                Created, Updated)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+            RETURNING ID
+            `,
+			tw.GroupID,
+			tw.AccountID,
+			tw.UserID,
+			tw.InReplyTo,
+			tw.Visibility,
+			tw.ContentWarning,
+			tw.Status,
+			tw.Scheduled,
+			tw.Attempts,
+			tw.NextAttempt,
+			tw.Error,
+			tw.Created,
+			tw.Updated,
+		).Scan(&tw.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE Tweets SET Status=$1, Scheduled=$2, Attempts=$3, NextAttempt=$4,
+            Error=$5, Updated=$6
+        WHERE ID = $7
+        `,
+		tw.Status,
+		tw.Scheduled,
+		tw.Attempts,
+		tw.NextAttempt,
+		tw.Error,
+		tw.Updated,
+		tw.ID,
+	)
+	return err
+}
+
+// TweetParts retrieves the parts of a tweet in order.
+func TweetParts(t *Token, tweetID int) ([]*TweetPart, error) {
+	r, err := t.query(
+		`
+        SELECT ID, TweetID, Position, Text, MediaIDs, RemoteID
+        FROM TweetParts WHERE TweetID = $1
+        ORDER BY Position
+        `,
+		tweetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	parts := make([]*TweetPart, 0, 1)
+	for r.Next() {
+		var (
+			p        = &TweetPart{}
+			mediaIDs []int64
+		)
+		if err := r.Scan(
+			&p.ID,
+			&p.TweetID,
+			&p.Position,
+			&p.Text,
+			pq.Array(&mediaIDs),
+			&p.RemoteID,
+		); err != nil {
+			return nil, err
+		}
+		for _, id := range mediaIDs {
+			p.MediaIDs = append(p.MediaIDs, int(id))
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated.
+func (p *TweetPart) Save(t *Token) error {
+	mediaIDs := make([]int64, len(p.MediaIDs))
+	for i, id := range p.MediaIDs {
+		mediaIDs[i] = int64(id)
+	}
+	if p.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO TweetParts (TweetID, Position, Text, MediaIDs, RemoteID)
+            VALUES ($1, $2, $3, $4, $5) RETURNING ID
+            `,
+			p.TweetID,
+			p.Position,
+			p.Text,
+			pq.Array(mediaIDs),
+			p.RemoteID,
+		).Scan(&p.ID)
+	}
+	_, err := t.exec(
+		`
+        UPDATE TweetParts SET Text=$1, MediaIDs=$2, RemoteID=$3
+        WHERE ID = $4
+        `,
+		p.Text,
+		pq.Array(mediaIDs),
+		p.RemoteID,
+		p.ID,
+	)
+	return err
+}
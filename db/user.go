@@ -15,8 +15,19 @@ type User struct {
 	Email      string
 	IsAdmin    bool
 	IsDisabled bool
+	TimeZone   string
+	DateFormat string
+	Locale     string
+	EmailMode  string
 }
 
+// Email notification modes for users.
+const (
+	EmailOff     = "off"
+	EmailInstant = "instant"
+	EmailDigest  = "digest"
+)
+
 // migrateUsersTable executes the SQL necessary to create the Users table.
 func migrateUsersTable(t *Token) error {
 	_, err := t.exec(
@@ -31,6 +42,18 @@ func migrateUsersTable(t *Token) error {
         )
         `,
 	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Users
+        ADD COLUMN IF NOT EXISTS TimeZone   VARCHAR(40) NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS DateFormat VARCHAR(40) NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS Locale     VARCHAR(20) NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS EmailMode  VARCHAR(10) NOT NULL DEFAULT 'instant'
+        `,
+	)
 	return err
 }
 
@@ -38,7 +61,7 @@ func migrateUsersTable(t *Token) error {
 func AllUsers(t *Token, sort string) ([]*User, error) {
 	r, err := t.query(
 		`
-        SELECT ID, Username, Password, Email, IsAdmin, IsDisabled
+        SELECT ID, Username, Password, Email, IsAdmin, IsDisabled, TimeZone, DateFormat, Locale, EmailMode
         FROM Users ORDER BY $1
         `,
 		sort,
@@ -56,6 +79,10 @@ func AllUsers(t *Token, sort string) ([]*User, error) {
 			&u.Email,
 			&u.IsAdmin,
 			&u.IsDisabled,
+			&u.TimeZone,
+			&u.DateFormat,
+			&u.Locale,
+			&u.EmailMode,
 		); err != nil {
 			return nil, err
 		}
@@ -70,7 +97,7 @@ func FindUser(t *Token, field string, value interface{}) (*User, error) {
 	err := t.queryRow(
 		fmt.Sprintf(
 			`
-            SELECT ID, Username, Password, Email, IsAdmin, IsDisabled
+            SELECT ID, Username, Password, Email, IsAdmin, IsDisabled, TimeZone, DateFormat, Locale, EmailMode
             FROM Users WHERE %s = $1
             `,
 			field,
@@ -83,6 +110,10 @@ func FindUser(t *Token, field string, value interface{}) (*User, error) {
 		&u.Email,
 		&u.IsAdmin,
 		&u.IsDisabled,
+		&u.TimeZone,
+		&u.DateFormat,
+		&u.Locale,
+		&u.EmailMode,
 	)
 	if err != nil {
 		return nil, err
@@ -97,14 +128,18 @@ func (u *User) Save(t *Token) error {
 		var id int
 		err := t.queryRow(
 			`
-            INSERT INTO Users (Username, Password, Email, IsAdmin, IsDisabled)
-            VALUES ($1, $2, $3, $4, $5) RETURNING ID
+            INSERT INTO Users (Username, Password, Email, IsAdmin, IsDisabled, TimeZone, DateFormat, Locale, EmailMode)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING ID
             `,
 			u.Username,
 			u.Password,
 			u.Email,
 			u.IsAdmin,
 			u.IsDisabled,
+			u.TimeZone,
+			u.DateFormat,
+			u.Locale,
+			u.EmailMode,
 		).Scan(&id)
 		if err != nil {
 			return err
@@ -114,14 +149,19 @@ func (u *User) Save(t *Token) error {
 	} else {
 		_, err := t.exec(
 			`
-            UPDATE Users SET Username=$1, Password=$2, Email=$3, IsAdmin=$4, IsDisabled=$5
-            WHERE ID = $6
+            UPDATE Users SET Username=$1, Password=$2, Email=$3, IsAdmin=$4, IsDisabled=$5,
+                TimeZone=$6, DateFormat=$7, Locale=$8, EmailMode=$9
+            WHERE ID = $10
             `,
 			u.Username,
 			u.Password,
 			u.Email,
 			u.IsAdmin,
 			u.IsDisabled,
+			u.TimeZone,
+			u.DateFormat,
+			u.Locale,
+			u.EmailMode,
 			u.ID,
 		)
 		return err
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Webhook is an external URL that receives a signed JSON request whenever one
+// of the selected events occurs. Events are stored as a comma-separated list.
+type Webhook struct {
+	ID        int
+	URL       string
+	Secret    string
+	Events    string
+	IsEnabled bool
+}
+
+// migrateWebhooksTable executes the SQL necessary to create the Webhooks
+// table.
+func migrateWebhooksTable(t *Token) error {
+	_, err := t.exec(
+		`
+        CREATE TABLE IF NOT EXISTS Webhooks (
+            ID        SERIAL PRIMARY KEY,
+            URL       VARCHAR(500) NOT NULL,
+            Secret    TEXT NOT NULL,
+            Events    VARCHAR(500) NOT NULL,
+            IsEnabled BOOLEAN NOT NULL
+        )
+        `,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = t.exec(
+		`
+        ALTER TABLE Webhooks ALTER COLUMN Secret TYPE TEXT
+        `,
+	)
+	return err
+}
+
+// HasEvent determines whether the webhook is subscribed to the event.
+func (w *Webhook) HasEvent(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// AllWebhooks retrieves all webhooks.
+func AllWebhooks(t *Token) ([]*Webhook, error) {
+	r, err := t.query(
+		`
+        SELECT ID, URL, Secret, Events, IsEnabled
+        FROM Webhooks ORDER BY ID
+        `,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	webhooks := make([]*Webhook, 0, 1)
+	for r.Next() {
+		w := &Webhook{}
+		if err := r.Scan(
+			&w.ID,
+			&w.URL,
+			&w.Secret,
+			&w.Events,
+			&w.IsEnabled,
+		); err != nil {
+			return nil, err
+		}
+		if w.Secret, err = decryptSecret(w.Secret, columnWebhookSecret); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// FindWebhook attempts to retrieve a webhook using the specified field.
+func FindWebhook(t *Token, field string, value interface{}) (*Webhook, error) {
+	w := &Webhook{}
+	err := t.queryRow(
+		fmt.Sprintf(
+			`
+            SELECT ID, URL, Secret, Events, IsEnabled
+            FROM Webhooks WHERE %s = $1
+            `,
+			field,
+		),
+		value,
+	).Scan(
+		&w.ID,
+		&w.URL,
+		&w.Secret,
+		&w.Events,
+		&w.IsEnabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if w.Secret, err = decryptSecret(w.Secret, columnWebhookSecret); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Save updates the object in the database. If the ID is set to 0, a new row is
+// inserted and the ID updated. The secret is encrypted if a master key was
+// provided.
+func (w *Webhook) Save(t *Token) error {
+	secret, err := encryptSecret(w.Secret, columnWebhookSecret)
+	if err != nil {
+		return err
+	}
+	if w.ID == 0 {
+		return t.queryRow(
+			`
+            INSERT INTO Webhooks (URL, Secret, Events, IsEnabled)
+            VALUES ($1, $2, $3, $4) RETURNING ID
+            `,
+			w.URL,
+			secret,
+			w.Events,
+			w.IsEnabled,
+		).Scan(&w.ID)
+	}
+	_, err = t.exec(
+		`
+        UPDATE Webhooks SET URL=$1, Secret=$2, Events=$3, IsEnabled=$4
+        WHERE ID = $5
+        `,
+		w.URL,
+		secret,
+		w.Events,
+		w.IsEnabled,
+		w.ID,
+	)
+	return err
+}
+
+// Delete removes the webhook and its deliveries.
+func (w *Webhook) Delete(t *Token) error {
+	_, err := t.exec(
+		`
+        DELETE FROM Webhooks WHERE ID = $1
+        `,
+		w.ID,
+	)
+	return err
+}
@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"unicode"
+)
+
+// Op is the kind of a change.
+type Op int
+
+// Kinds of change. Equal text is in both versions, Delete text only in the
+// old one and Insert text only in the new one.
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Change is a run of text that was kept, removed or added.
+type Change struct {
+	Op   Op
+	Text string
+}
+
+// tokenize splits text into words and the whitespace between them so that
+// changes are shown a word at a time.
+func tokenize(text string) []string {
+	var (
+		tokens = []string{}
+		start  = 0
+		space  = false
+	)
+	for i, r := range text {
+		s := unicode.IsSpace(r)
+		if i != 0 && s != space {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		space = s
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// appendChange adds text to the changes, merging it with the last change if
+// they have the same kind.
+func appendChange(changes []Change, op Op, text string) []Change {
+	if n := len(changes); n != 0 && changes[n-1].Op == op {
+		changes[n-1].Text += text
+		return changes
+	}
+	return append(changes, Change{Op: op, Text: text})
+}
+
+// Words returns the changes that turn a into b, found from the longest
+// common sequence of words. Deletions are listed before the insertions that
+// replace them.
+func Words(a, b string) []Change {
+	var (
+		x = tokenize(a)
+		y = tokenize(b)
+		l = make([][]int, len(x)+1)
+	)
+	for i := range l {
+		l[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] >= l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+	var (
+		changes = []Change{}
+		i, j    int
+	)
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			changes = appendChange(changes, Equal, x[i])
+			i++
+			j++
+		case j == len(y) || (i < len(x) && l[i+1][j] >= l[i][j+1]):
+			changes = appendChange(changes, Delete, x[i])
+			i++
+		default:
+			changes = appendChange(changes, Insert, y[j])
+			j++
+		}
+	}
+	return changes
+}
+
+// Changed determines whether any of the changes add or remove text.
+func Changed(changes []Change) bool {
+	for _, c := range changes {
+		if c.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
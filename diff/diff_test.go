@@ -0,0 +1,51 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		a           string
+		b           string
+		expected    []Change
+	}{
+		{"unchanged", "hello world", "hello world", []Change{{Equal, "hello world"}}},
+		{"both empty", "", "", []Change{}},
+		{"added", "", "hello", []Change{{Insert, "hello"}}},
+		{"removed", "hello", "", []Change{{Delete, "hello"}}},
+		{
+			"word replaced",
+			"launch on Monday",
+			"launch on Tuesday",
+			[]Change{{Equal, "launch on "}, {Delete, "Monday"}, {Insert, "Tuesday"}},
+		},
+		{
+			"word inserted",
+			"a new release",
+			"a new beta release",
+			[]Change{{Equal, "a new "}, {Insert, "beta "}, {Equal, "release"}},
+		},
+		{
+			"line break",
+			"one two",
+			"one\ntwo",
+			[]Change{{Equal, "one"}, {Delete, " "}, {Insert, "\n"}, {Equal, "two"}},
+		},
+	} {
+		if v := Words(tc.a, tc.b); !reflect.DeepEqual(v, tc.expected) {
+			t.Errorf("%s: got %v", tc.description, v)
+		}
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Words("same text", "same text")) {
+		t.Error("identical text reported as changed")
+	}
+	if !Changed(Words("same text", "other text")) {
+		t.Error("different text reported as unchanged")
+	}
+}
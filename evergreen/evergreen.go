@@ -0,0 +1,37 @@
+package evergreen
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalize prepares text for comparison so that posts differing only in
+// surrounding whitespace or Unicode normalization are treated as duplicates.
+func normalize(text string) string {
+	return strings.TrimSpace(norm.NFC.String(text))
+}
+
+// Choose returns the first of the posts that was last published at least
+// minInterval ago and whose text does not match any of the recent tweets,
+// which the platform would reject as a duplicate. The posts should be in the
+// order returned by db.PoolPosts so that the pool is cycled through. Nil is
+// returned if every post must be skipped.
+func Choose(posts []*db.PoolPost, recent []string, minInterval time.Duration, now time.Time) *db.PoolPost {
+	texts := map[string]bool{}
+	for _, text := range recent {
+		texts[normalize(text)] = true
+	}
+	for _, p := range posts {
+		if !p.LastSent.IsZero() && now.Sub(p.LastSent) < minInterval {
+			continue
+		}
+		if texts[normalize(p.Text)] {
+			continue
+		}
+		return p
+	}
+	return nil
+}
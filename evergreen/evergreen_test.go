@@ -0,0 +1,46 @@
+package evergreen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+func TestChoose(t *testing.T) {
+	var (
+		now   = time.Date(2026, 3, 29, 9, 0, 0, 0, time.UTC)
+		never = &db.PoolPost{ID: 1, Text: "never sent"}
+		old   = &db.PoolPost{ID: 2, Text: "sent last week", LastSent: now.Add(-7 * 24 * time.Hour)}
+		fresh = &db.PoolPost{ID: 3, Text: "sent yesterday", LastSent: now.Add(-24 * time.Hour)}
+	)
+	for _, tc := range []struct {
+		description string
+		posts       []*db.PoolPost
+		recent      []string
+		minInterval time.Duration
+		expected    *db.PoolPost
+	}{
+		{"least recently sent first", []*db.PoolPost{never, old, fresh}, nil, 48 * time.Hour, never},
+		{"within the minimum interval", []*db.PoolPost{fresh, old}, nil, 48 * time.Hour, old},
+		{"no minimum interval", []*db.PoolPost{fresh}, nil, 0, fresh},
+		{"duplicate of a recent tweet", []*db.PoolPost{never, old}, []string{"never sent"}, 48 * time.Hour, old},
+		{"duplicate after normalization", []*db.PoolPost{never}, []string{"  never sent\n"}, 0, nil},
+		{"nothing may be sent", []*db.PoolPost{fresh}, nil, 48 * time.Hour, nil},
+		{"empty pool", nil, nil, 0, nil},
+	} {
+		if p := Choose(tc.posts, tc.recent, tc.minInterval, now); p != tc.expected {
+			t.Errorf("%s: got %v, expected %v", tc.description, p, tc.expected)
+		}
+	}
+}
+
+func TestChooseNFC(t *testing.T) {
+	var (
+		composed   = &db.PoolPost{Text: "caf\u00e9"}
+		decomposed = "cafe\u0301"
+	)
+	if p := Choose([]*db.PoolPost{composed}, []string{decomposed}, 0, time.Now()); p != nil {
+		t.Fatal("normalized duplicate was chosen")
+	}
+}
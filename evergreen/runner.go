@@ -0,0 +1,160 @@
+package evergreen
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/approval"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// checkInterval determines how often pools are checked for schedules
+	// that have fired
+	checkInterval = time.Minute
+
+	// duplicateWindow is how far back tweets are compared with a post to
+	// avoid publishing the same text twice
+	duplicateWindow = 7 * 24 * time.Hour
+
+	// retryDelay postpones a pool whose schedule can no longer be parsed
+	retryDelay = 24 * time.Hour
+)
+
+// Runner publishes a post from each evergreen pool when its schedule fires.
+// Missed runs are not made up, so a pool publishes at most one post per
+// check.
+type Runner struct {
+	wake    func()
+	log     *logrus.Entry
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewRunner creates a new runner and begins checking pools. The wake function
+// is called after tweets are queued so that they are published immediately.
+func NewRunner(wake func()) *Runner {
+	r := &Runner{
+		wake:    wake,
+		log:     logrus.WithField("context", "evergreen"),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go r.run()
+	return r
+}
+
+// queue creates an approved tweet for the post. Pools are managed by
+// administrators, so their posts do not need approval.
+func (r *Runner) queue(t *db.Token, p *db.Pool, post *db.PoolPost, now time.Time) error {
+	g := &db.TweetGroup{
+		UserID:  p.UserID,
+		Status:  string(approval.StatusApproved),
+		Started: now,
+	}
+	if err := g.Save(t); err != nil {
+		return err
+	}
+	tw := &db.Tweet{
+		GroupID:     g.ID,
+		AccountID:   p.AccountID,
+		UserID:      p.UserID,
+		Status:      db.TweetScheduled,
+		Scheduled:   now,
+		NextAttempt: now,
+	}
+	if err := tw.Save(t); err != nil {
+		return err
+	}
+	if err := (&db.TweetPart{
+		TweetID:  tw.ID,
+		Position: 1,
+		Text:     post.Text,
+	}).Save(t); err != nil {
+		return err
+	}
+	post.LastSent = now
+	return post.Save(t)
+}
+
+// publish queues the next post from the pool, if any may be published, and
+// calculates when the schedule fires next. It returns true if a post was
+// queued.
+func (r *Runner) publish(t *db.Token, p *db.Pool, now time.Time) (bool, error) {
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	s, err := schedule.Parse(p.Schedule, loc)
+	if err != nil {
+		r.log.WithError(err).WithField("pool", p.ID).Warning("invalid schedule")
+		p.NextRun = now.Add(retryDelay)
+		return false, p.Save(t)
+	}
+	posts, err := db.PoolPosts(t, p.ID)
+	if err != nil {
+		return false, err
+	}
+	recent, err := db.RecentTexts(t, p.AccountID, now.Add(-duplicateWindow))
+	if err != nil {
+		return false, err
+	}
+	post := Choose(posts, recent, time.Duration(p.MinInterval)*time.Hour, now)
+	if post != nil {
+		if err := r.queue(t, p, post, now); err != nil {
+			return false, err
+		}
+	}
+	p.NextRun = s.Next(now)
+	return post != nil, p.Save(t)
+}
+
+// process checks every pool whose schedule has fired.
+func (r *Runner) process() error {
+	var (
+		now    = time.Now().UTC()
+		queued = false
+	)
+	if err := db.Transaction(func(t *db.Token) error {
+		pools, err := db.ClaimDuePools(t, now)
+		if err != nil {
+			return err
+		}
+		for _, p := range pools {
+			ok, err := r.publish(t, p, now)
+			if err != nil {
+				return err
+			}
+			queued = queued || ok
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if queued {
+		r.wake()
+	}
+	return nil
+}
+
+// run checks pools until stopped.
+func (r *Runner) run() {
+	defer close(r.stopped)
+	for {
+		if err := r.process(); err != nil {
+			r.log.WithError(err).Error("unable to check evergreen pools")
+		}
+		select {
+		case <-time.After(checkInterval):
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops checking pools.
+func (r *Runner) Close() {
+	close(r.stop)
+	<-r.stopped
+}
@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Catalog holds the translated messages for a single language.
+type Catalog struct {
+	tag      language.Tag
+	messages map[string]string
+}
+
+// Language returns the BCP 47 tag of the catalog, such as "de".
+func (c *Catalog) Language() string {
+	return c.tag.String()
+}
+
+// T translates a message. If arguments are provided, the translation is used
+// as a format string. Messages without a translation are returned unchanged,
+// so a nil catalog always produces English.
+func (c *Catalog) T(msgid string, args ...interface{}) string {
+	s := msgid
+	if c != nil {
+		if v, ok := c.messages[msgid]; ok {
+			s = v
+		}
+	}
+	if len(args) != 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// Bundle contains the catalogs for all available languages. English is the
+// language the messages are written in and is always available.
+type Bundle struct {
+	catalogs []*Catalog
+	matcher  language.Matcher
+}
+
+// Load reads each of the <language>.po files in the specified directory. A
+// missing directory is not an error and results in a bundle with only
+// English available.
+func Load(dir string) (*Bundle, error) {
+	b := &Bundle{
+		catalogs: []*Catalog{
+			{tag: language.English, messages: map[string]string{}},
+		},
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || path.Ext(f.Name()) != ".po" {
+			continue
+		}
+		tag, err := language.Parse(strings.TrimSuffix(f.Name(), ".po"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f.Name(), err)
+		}
+		r, err := os.Open(path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		messages, err := ParsePO(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f.Name(), err)
+		}
+		b.catalogs = append(b.catalogs, &Catalog{
+			tag:      tag,
+			messages: messages,
+		})
+	}
+	tags := make([]language.Tag, len(b.catalogs))
+	for i, c := range b.catalogs {
+		tags[i] = c.tag
+	}
+	b.matcher = language.NewMatcher(tags)
+	return b, nil
+}
+
+// Languages returns the tags of all available languages.
+func (b *Bundle) Languages() []string {
+	l := make([]string, len(b.catalogs))
+	for i, c := range b.catalogs {
+		l[i] = c.Language()
+	}
+	return l
+}
+
+// Match selects the catalog that best suits the preferences, which are tried
+// in order and may be either single tags or Accept-Language header values.
+// Empty and invalid preferences are ignored and English is used if nothing
+// matches.
+func (b *Bundle) Match(prefs ...string) *Catalog {
+	for _, p := range prefs {
+		if len(p) == 0 {
+			continue
+		}
+		tags, _, err := language.ParseAcceptLanguage(p)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		if _, i, c := b.matcher.Match(tags...); c != language.No {
+			return b.catalogs[i]
+		}
+	}
+	return b.catalogs[0]
+}
@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParsePO reads the messages from a gettext .po file. Only singular messages
+// are supported; entries marked fuzzy and those without a translation are
+// skipped so that the original text is shown instead.
+func ParsePO(r io.Reader) (map[string]string, error) {
+	var (
+		messages = map[string]string{}
+		scanner  = bufio.NewScanner(r)
+		msgid    string
+		msgstr   string
+		fuzzy    bool
+		cur      *string
+		line     int
+	)
+	flush := func() {
+		if len(msgid) != 0 && len(msgstr) != 0 && !fuzzy {
+			messages[msgid] = msgstr
+		}
+		msgid, msgstr, fuzzy, cur = "", "", false, nil
+	}
+	for scanner.Scan() {
+		line++
+		l := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(l) == 0:
+			flush()
+		case strings.HasPrefix(l, "#"):
+			if cur != nil {
+				flush()
+			}
+			if strings.HasPrefix(l, "#,") && strings.Contains(l, "fuzzy") {
+				fuzzy = true
+			}
+		case strings.HasPrefix(l, "msgid "):
+			if cur != nil {
+				flush()
+			}
+			cur = &msgid
+			l = strings.TrimPrefix(l, "msgid ")
+			fallthrough
+		case strings.HasPrefix(l, "msgstr "):
+			if strings.HasPrefix(l, "msgstr ") {
+				cur = &msgstr
+				l = strings.TrimPrefix(l, "msgstr ")
+			}
+			fallthrough
+		case strings.HasPrefix(l, `"`):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: string outside of an entry", line)
+			}
+			s, err := strconv.Unquote(l)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			*cur += s
+		default:
+			return nil, fmt.Errorf("line %d: unsupported keyword", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return messages, nil
+}
@@ -0,0 +1,193 @@
+package inbox
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathan-osman/informas/bluesky"
+	"github.com/nathan-osman/informas/db"
+)
+
+const (
+	// blueskyPageSize is the number of notifications, conversations or
+	// messages requested at once
+	blueskyPageSize = 50
+
+	// blueskyMaxPages limits how far back notifications and conversations are
+	// read
+	blueskyMaxPages = 10
+)
+
+// blueskySource reads notifications for mentions and replies as well as chat
+// conversations. Notifications do not support since_id, so the checkpoint for
+// mentions is the time the newest notification was indexed and pages are read
+// until an older one is found.
+type blueskySource struct {
+	account *db.Account
+}
+
+func newBlueskySource(a *db.Account) *blueskySource {
+	return &blueskySource{
+		account: a,
+	}
+}
+
+// Mentions implements Source.
+func (b *blueskySource) Mentions(checkpoint string) ([]*db.Mention, string, error) {
+	c, err := bluesky.Login(b.account.Instance, b.account.RemoteID, b.account.AccessToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var since time.Time
+	if len(checkpoint) != 0 {
+		t, err := time.Parse(time.RFC3339Nano, checkpoint)
+		if err != nil {
+			return nil, "", err
+		}
+		since = t
+	}
+	var (
+		mentions = []*db.Mention{}
+		newest   = since
+		cursor   string
+	)
+pages:
+	for i := 0; i < blueskyMaxPages; i++ {
+		notifications, next, err := c.ListNotifications(cursor, blueskyPageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, n := range notifications {
+			if !n.IndexedAt.After(since) {
+				break pages
+			}
+			if n.IndexedAt.After(newest) {
+				newest = n.IndexedAt
+			}
+			if n.Reason != bluesky.ReasonMention && n.Reason != bluesky.ReasonReply ||
+				n.Record == nil || n.Author == nil {
+				continue
+			}
+			m := &db.Mention{
+				AccountID: b.account.ID,
+				RemoteID:  n.URI,
+				Author:    "@" + n.Author.Handle,
+				Text:      n.Record.Text,
+				URL:       n.WebURL(),
+				Created:   n.IndexedAt.UTC(),
+			}
+			if t, err := time.Parse(time.RFC3339Nano, n.Record.CreatedAt); err == nil {
+				m.Created = t.UTC()
+			}
+			if n.Record.Reply != nil {
+				m.InReplyTo = n.Record.Reply.Parent.URI
+			}
+			mentions = append(mentions, m)
+		}
+		if len(next) == 0 || since.IsZero() {
+			break
+		}
+		cursor = next
+	}
+	if !newest.IsZero() {
+		checkpoint = newest.UTC().Format(time.RFC3339Nano)
+	}
+	return mentions, checkpoint, nil
+}
+
+// Conversations implements Source. The checkpoint is the newest revision of
+// any conversation and pages are read until an older one is found.
+func (b *blueskySource) Conversations(checkpoint string) ([]*Thread, string, error) {
+	c, err := bluesky.Login(b.account.Instance, b.account.RemoteID, b.account.AccessToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var (
+		threads = []*Thread{}
+		newest  = checkpoint
+		cursor  string
+	)
+pages:
+	for i := 0; i < blueskyMaxPages; i++ {
+		convos, next, err := c.ListConvos(cursor, blueskyPageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, convo := range convos {
+			if convo.Rev <= checkpoint {
+				break pages
+			}
+			if convo.Rev > newest {
+				newest = convo.Rev
+			}
+			th, err := b.thread(c, convo)
+			if err != nil {
+				return nil, "", err
+			}
+			threads = append(threads, th)
+		}
+		if len(next) == 0 || len(checkpoint) == 0 {
+			break
+		}
+		cursor = next
+	}
+	return threads, newest, nil
+}
+
+// thread retrieves the most recent messages in a conversation.
+func (b *blueskySource) thread(c *bluesky.Client, convo *bluesky.Convo) (*Thread, error) {
+	messages, err := c.GetMessages(convo.ID, blueskyPageSize)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		handles      = map[string]string{}
+		participants = []string{}
+	)
+	for _, m := range convo.Members {
+		handles[m.DID] = "@" + m.Handle
+		if m.DID != c.DID() {
+			participants = append(participants, "@"+m.Handle)
+		}
+	}
+	th := &Thread{
+		Conversation: &db.Conversation{
+			AccountID:    b.account.ID,
+			RemoteID:     convo.ID,
+			Participants: strings.Join(participants, " "),
+			Status:       db.ConversationOpen,
+			Updated:      time.Now().UTC(),
+		},
+	}
+	for _, m := range messages {
+		th.Messages = append(th.Messages, &db.Message{
+			RemoteID:  m.ID,
+			Sender:    handles[m.Sender.DID],
+			Text:      m.Text,
+			IsInbound: m.Sender.DID != c.DID(),
+			Created:   m.SentAt.UTC(),
+		})
+	}
+	if len(messages) != 0 {
+		th.Conversation.Updated = messages[0].SentAt.UTC()
+	}
+	return th, nil
+}
+
+// Send implements Source.
+func (b *blueskySource) Send(conversation *db.Conversation, inReplyTo, text string) (*db.Message, error) {
+	c, err := bluesky.Login(b.account.Instance, b.account.RemoteID, b.account.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.SendMessage(conversation.RemoteID, text)
+	if err != nil {
+		return nil, err
+	}
+	return &db.Message{
+		RemoteID: m.ID,
+		Sender:   "@" + c.Handle(),
+		Text:     m.Text,
+		Created:  m.SentAt.UTC(),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package inbox
+
+import (
+	"errors"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+)
+
+// Thread is a conversation along with the messages that were fetched for it.
+type Thread struct {
+	Conversation *db.Conversation
+	Messages     []*db.Message
+}
+
+// Source retrieves posts and direct messages for an account on a single
+// platform.
+type Source interface {
+
+	// Mentions returns the mentions and replies that arrived after the
+	// checkpoint, along with a new checkpoint to pass on the next call. An
+	// empty checkpoint retrieves the most recent page.
+	Mentions(checkpoint string) ([]*db.Mention, string, error)
+
+	// Conversations returns the conversations with direct messages that
+	// arrived after the checkpoint, along with a new checkpoint to pass on
+	// the next call. Messages that were already fetched may be included.
+	Conversations(checkpoint string) ([]*Thread, string, error)
+
+	// Send sends a direct message to the participants of the conversation.
+	// InReplyTo is the remote ID of the latest message, which is needed on
+	// platforms where direct messages are threaded.
+	Send(c *db.Conversation, inReplyTo, text string) (*db.Message, error)
+}
+
+// New creates a source for the specified account. The publisher options
+// provide the Twitter application credentials.
+func New(a *db.Account, o *publisher.Options) (Source, error) {
+	switch a.Platform {
+	case db.PlatformTwitter:
+		return newTwitterSource(a, o), nil
+	case db.PlatformMastodon:
+		return newMastodonSource(a), nil
+	case db.PlatformBluesky:
+		return newBlueskySource(a), nil
+	}
+	return nil, errors.New("unsupported platform")
+}
+
+// newerID determines whether the numeric ID a is greater than b. IDs are
+// compared as strings since they may not fit in an int64.
+func newerID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
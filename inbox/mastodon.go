@@ -0,0 +1,125 @@
+package inbox
+
+import (
+	"strings"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/mastodon"
+)
+
+// mastodonSource reads mention notifications and direct statuses. The
+// checkpoint for mentions is the ID of the newest notification.
+type mastodonSource struct {
+	account *db.Account
+	client  *mastodon.Client
+}
+
+func newMastodonSource(a *db.Account) *mastodonSource {
+	return &mastodonSource{
+		account: a,
+		client:  mastodon.NewClient(a.Instance, a.AccessToken),
+	}
+}
+
+// Mentions implements Source.
+func (m *mastodonSource) Mentions(checkpoint string) ([]*db.Mention, string, error) {
+	notifications, err := m.client.Mentions(checkpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	mentions := []*db.Mention{}
+	for _, n := range notifications {
+		s := n.Status
+		if s == nil || s.Account == nil {
+			continue
+		}
+		mentions = append(mentions, &db.Mention{
+			AccountID: m.account.ID,
+			RemoteID:  s.ID,
+			Author:    "@" + s.Account.Acct,
+			Text:      s.Text(),
+			URL:       s.WebURL(),
+			InReplyTo: s.InReplyToID,
+			Created:   s.CreatedAt.UTC(),
+		})
+	}
+	if len(notifications) != 0 {
+		checkpoint = notifications[0].ID
+	}
+	return mentions, checkpoint, nil
+}
+
+// Conversations implements Source. Only the latest status of a conversation
+// is returned, so the rest of the thread is read from its context. The
+// checkpoint is the ID of the newest status.
+func (m *mastodonSource) Conversations(checkpoint string) ([]*Thread, string, error) {
+	conversations, err := m.client.Conversations()
+	if err != nil {
+		return nil, "", err
+	}
+	var (
+		threads = []*Thread{}
+		newest  = checkpoint
+	)
+	for _, c := range conversations {
+		last := c.LastStatus
+		if last == nil || !newerID(last.ID, checkpoint) {
+			continue
+		}
+		if newerID(last.ID, newest) {
+			newest = last.ID
+		}
+		ctx, err := m.client.StatusContext(last.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		participants := []string{}
+		for _, a := range c.Accounts {
+			participants = append(participants, "@"+a.Acct)
+		}
+		th := &Thread{
+			Conversation: &db.Conversation{
+				AccountID:    m.account.ID,
+				RemoteID:     c.ID,
+				Participants: strings.Join(participants, " "),
+				Status:       db.ConversationOpen,
+				Updated:      last.CreatedAt.UTC(),
+			},
+		}
+		statuses := append(append(ctx.Ancestors, last), ctx.Descendants...)
+		for _, s := range statuses {
+			if s.Visibility != mastodon.VisibilityDirect || s.Account == nil {
+				continue
+			}
+			th.Messages = append(th.Messages, &db.Message{
+				RemoteID:  s.ID,
+				Sender:    "@" + s.Account.Acct,
+				Text:      s.Text(),
+				IsInbound: s.Account.ID != m.account.RemoteID,
+				Created:   s.CreatedAt.UTC(),
+			})
+		}
+		threads = append(threads, th)
+	}
+	return threads, newest, nil
+}
+
+// Send implements Source. Direct messages are statuses that are only visible
+// to the accounts they mention, so the participants are mentioned at the
+// start of the text.
+func (m *mastodonSource) Send(c *db.Conversation, inReplyTo, text string) (*db.Message, error) {
+	s, err := m.client.PostStatus(&mastodon.NewStatus{
+		Text:        c.Participants + " " + text,
+		InReplyToID: inReplyTo,
+		Visibility:  mastodon.VisibilityDirect,
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	return &db.Message{
+		RemoteID: s.ID,
+		Sender:   "@" + m.account.Username,
+		Text:     s.Text(),
+		Created:  s.CreatedAt.UTC(),
+	}, nil
+}
@@ -0,0 +1,137 @@
+package inbox
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/sirupsen/logrus"
+)
+
+// pollInterval determines how often each account is checked for mentions and
+// direct messages.
+const pollInterval = 2 * time.Minute
+
+// Poller periodically fetches mentions and direct messages of every account
+// into the database.
+type Poller struct {
+	options func() (*publisher.Options, error)
+	log     *logrus.Entry
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewPoller creates a new poller and begins fetching. The options
+// are retrieved before each check so that changes take effect without a
+// restart.
+func NewPoller(options func() (*publisher.Options, error)) *Poller {
+	p := &Poller{
+		options: options,
+		log:     logrus.WithField("context", "inbox"),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go p.run()
+	return p
+}
+
+// fetchMentions retrieves new mentions for a single account. The checkpoint is
+// only advanced once the mentions have been stored.
+func (p *Poller) fetchMentions(s Source, a *db.Account) error {
+	checkpoint, err := db.GetCheckpoint(&db.Token{}, a.ID, db.CheckpointMentions)
+	if err != nil {
+		return err
+	}
+	mentions, checkpoint, err := s.Mentions(checkpoint)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(t *db.Token) error {
+		for _, m := range mentions {
+			if err := m.Save(t); err != nil {
+				return err
+			}
+		}
+		return db.SetCheckpoint(t, a.ID, db.CheckpointMentions, checkpoint)
+	})
+}
+
+// fetchConversations retrieves new direct messages for a single account. A
+// conversation that receives a new message from a remote user is reopened.
+func (p *Poller) fetchConversations(s Source, a *db.Account) error {
+	checkpoint, err := db.GetCheckpoint(&db.Token{}, a.ID, db.CheckpointMessages)
+	if err != nil {
+		return err
+	}
+	threads, checkpoint, err := s.Conversations(checkpoint)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(t *db.Token) error {
+		for _, th := range threads {
+			c := th.Conversation
+			if err := c.Save(t); err != nil {
+				return err
+			}
+			for _, m := range th.Messages {
+				m.ConversationID = c.ID
+				if err := m.Save(t); err != nil {
+					return err
+				}
+				if m.ID == 0 {
+					continue
+				}
+				if err := c.Touch(t, m.Created, m.IsInbound); err != nil {
+					return err
+				}
+			}
+		}
+		return db.SetCheckpoint(t, a.ID, db.CheckpointMessages, checkpoint)
+	})
+}
+
+// poll fetches mentions and direct messages for all accounts.
+func (p *Poller) poll() {
+	accounts, err := db.AllAccounts(&db.Token{})
+	if err != nil {
+		p.log.WithError(err).Error("unable to retrieve accounts")
+		return
+	}
+	o, err := p.options()
+	if err != nil {
+		p.log.WithError(err).Error("unable to retrieve credentials")
+		return
+	}
+	for _, a := range accounts {
+		s, err := New(a, o)
+		if err != nil {
+			p.log.WithError(err).WithField("account", a.ID).Warning("unable to create source")
+			continue
+		}
+		if err := p.fetchMentions(s, a); err != nil {
+			p.log.WithError(err).WithField("account", a.ID).Warning("unable to fetch mentions")
+		}
+		if err := p.fetchConversations(s, a); err != nil {
+			p.log.WithError(err).WithField("account", a.ID).Warning("unable to fetch direct messages")
+		}
+	}
+}
+
+// run fetches mentions and direct messages until stopped.
+func (p *Poller) run() {
+	defer close(p.stopped)
+	for {
+		p.poll()
+		select {
+		case <-time.After(pollInterval):
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops fetching.
+func (p *Poller) Close() {
+	close(p.stop)
+	<-p.stopped
+}
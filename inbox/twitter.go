@@ -0,0 +1,189 @@
+package inbox
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/twitter"
+)
+
+// twitterMaxPages limits how far back direct messages are read.
+const twitterMaxPages = 5
+
+// twitterSource reads the mentions timeline and direct messages. The
+// checkpoint for mentions is the ID of the newest tweet.
+type twitterSource struct {
+	account *db.Account
+	client  *twitter.Client
+}
+
+func newTwitterSource(a *db.Account, o *publisher.Options) *twitterSource {
+	return &twitterSource{
+		account: a,
+		client: twitter.NewClient(
+			o.TwitterConsumerKey,
+			o.TwitterConsumerSecret,
+			a.AccessToken,
+			a.AccessSecret,
+		),
+	}
+}
+
+// Mentions implements Source.
+func (t *twitterSource) Mentions(checkpoint string) ([]*db.Mention, string, error) {
+	tweets, err := t.client.MentionsTimeline(checkpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	mentions := []*db.Mention{}
+	for _, tweet := range tweets {
+		var (
+			text       = tweet.FullText
+			screenName string
+		)
+		if len(text) == 0 {
+			text = tweet.Text
+		}
+		if tweet.User != nil {
+			screenName = tweet.User.ScreenName
+		}
+		created, err := tweet.Created()
+		if err != nil {
+			created = time.Now()
+		}
+		mentions = append(mentions, &db.Mention{
+			AccountID: t.account.ID,
+			RemoteID:  tweet.IDString,
+			Author:    "@" + screenName,
+			Text:      text,
+			URL:       "https://twitter.com/" + screenName + "/status/" + tweet.IDString,
+			InReplyTo: tweet.InReplyToIDString,
+			Created:   created.UTC(),
+		})
+	}
+	if len(tweets) != 0 {
+		checkpoint = tweets[0].IDString
+	}
+	return mentions, checkpoint, nil
+}
+
+// Conversations implements Source. Twitter only returns direct messages from
+// the last 30 days and has no since_id, so pages are read until an event
+// older than the checkpoint, which is the ID of the newest event, is found.
+func (t *twitterSource) Conversations(checkpoint string) ([]*Thread, string, error) {
+	var (
+		threads = map[string]*Thread{}
+		order   = []string{}
+		newest  = checkpoint
+		cursor  string
+	)
+pages:
+	for i := 0; i < twitterMaxPages; i++ {
+		events, next, err := t.client.DirectMessages(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, e := range events {
+			if !newerID(e.ID, checkpoint) {
+				break pages
+			}
+			if newerID(e.ID, newest) {
+				newest = e.ID
+			}
+			if e.MessageCreate == nil {
+				continue
+			}
+			var (
+				isInbound = e.MessageCreate.SenderID != t.account.RemoteID
+				remoteID  = e.MessageCreate.Target.RecipientID
+				sender    = "@" + t.account.Username
+			)
+			if isInbound {
+				remoteID = e.MessageCreate.SenderID
+				sender = remoteID
+			}
+			created, err := e.Created()
+			if err != nil {
+				created = time.Now()
+			}
+			th, ok := threads[remoteID]
+			if !ok {
+				th = &Thread{
+					Conversation: &db.Conversation{
+						AccountID:    t.account.ID,
+						RemoteID:     remoteID,
+						Participants: remoteID,
+						Status:       db.ConversationOpen,
+						Updated:      created.UTC(),
+					},
+				}
+				threads[remoteID] = th
+				order = append(order, remoteID)
+			}
+			th.Messages = append(th.Messages, &db.Message{
+				RemoteID:  e.ID,
+				Sender:    sender,
+				Text:      e.MessageCreate.MessageData.Text,
+				IsInbound: isInbound,
+				Created:   created.UTC(),
+			})
+		}
+		if len(next) == 0 || len(checkpoint) == 0 {
+			break
+		}
+		cursor = next
+	}
+	if err := t.resolveParticipants(threads, order); err != nil {
+		return nil, "", err
+	}
+	result := []*Thread{}
+	for _, remoteID := range order {
+		result = append(result, threads[remoteID])
+	}
+	return result, newest, nil
+}
+
+// resolveParticipants looks up the screen names of the remote users, since
+// direct message events only include their IDs. The IDs are shown for users
+// that cannot be found.
+func (t *twitterSource) resolveParticipants(threads map[string]*Thread, ids []string) error {
+	for len(ids) != 0 {
+		n := len(ids)
+		if n > 100 {
+			n = 100
+		}
+		users, err := t.client.LookupUsers(ids[:n])
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			th, ok := threads[u.IDString]
+			if !ok {
+				continue
+			}
+			th.Conversation.Participants = "@" + u.ScreenName
+			for _, m := range th.Messages {
+				if m.IsInbound {
+					m.Sender = "@" + u.ScreenName
+				}
+			}
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// Send implements Source.
+func (t *twitterSource) Send(c *db.Conversation, inReplyTo, text string) (*db.Message, error) {
+	e, err := t.client.SendDirectMessage(c.RemoteID, text)
+	if err != nil {
+		return nil, err
+	}
+	return &db.Message{
+		RemoteID: e.ID,
+		Sender:   "@" + t.account.Username,
+		Text:     text,
+		Created:  time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,215 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// prefix identifies values that were encrypted with a keyring
+	prefix = "enc:"
+
+	// keySize is the length of master and data keys, selecting AES-256
+	keySize = 32
+)
+
+var (
+	errInvalidKey   = errors.New("master key must be 32 bytes encoded as base64")
+	errInvalidValue = errors.New("encrypted value is malformed")
+)
+
+// Keyring contains the versioned master keys used for envelope encryption.
+// Each value is encrypted with its own random data key, which is in turn
+// encrypted with the current master key. Older master keys are kept so that
+// values encrypted with them can still be decrypted until they are rotated.
+type Keyring struct {
+	keys    map[int][]byte
+	current int
+}
+
+// Parse reads master keys in the form "version:key", where the version is a
+// positive integer and the key is 32 random bytes encoded as base64. Entries
+// are separated by whitespace or commas and lines starting with "#" are
+// ignored. The key with the highest version is used for encryption.
+func Parse(s string) (*Keyring, error) {
+	k := &Keyring{
+		keys: make(map[int][]byte),
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, entry := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		}) {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("master key %q has no version", entry)
+			}
+			version, err := strconv.Atoi(parts[0])
+			if err != nil || version < 1 {
+				return nil, fmt.Errorf("master key version %q is invalid", parts[0])
+			}
+			if _, ok := k.keys[version]; ok {
+				return nil, fmt.Errorf("master key version %d appears twice", version)
+			}
+			key, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil || len(key) != keySize {
+				return nil, errInvalidKey
+			}
+			k.keys[version] = key
+			if version > k.current {
+				k.current = version
+			}
+		}
+	}
+	if len(k.keys) == 0 {
+		return nil, errors.New("no master keys were provided")
+	}
+	return k, nil
+}
+
+// GenerateKey creates a new random master key encoded as base64.
+func GenerateKey() (string, error) {
+	b := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Current returns the version of the key used for encryption.
+func (k *Keyring) Current() int {
+	return k.current
+}
+
+// Versions returns the versions of all keys, oldest first.
+func (k *Keyring) Versions() []int {
+	versions := []int{}
+	for v := range k.keys {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// IsEncrypted determines whether the value was encrypted with a keyring.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyVersion returns the version of the master key that the value was
+// encrypted with or 0 if it is not encrypted.
+func KeyVersion(value string) int {
+	if !IsEncrypted(value) {
+		return 0
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, prefix), ":", 2)
+	v, _ := strconv.Atoi(parts[0])
+	return v
+}
+
+// seal encrypts the plaintext with AES-GCM, prepending the nonce.
+func seal(key, plaintext, context []byte) ([]byte, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, context), nil
+}
+
+// open decrypts a value created by seal.
+func open(key, ciphertext, context []byte) ([]byte, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errInvalidValue
+	}
+	n := gcm.NonceSize()
+	return gcm.Open(nil, ciphertext[:n], ciphertext[n:], context)
+}
+
+// Encrypt encrypts the plaintext with a new data key and the current master
+// key. The context, such as the name of the column, must be provided again to
+// decrypt the value so that it cannot be moved elsewhere.
+func (k *Keyring) Encrypt(plaintext, context string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(context))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext), []byte(context))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"%s%d:%s:%s",
+		prefix,
+		k.current,
+		base64.RawURLEncoding.EncodeToString(wrapped),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+	), nil
+}
+
+// Decrypt decrypts a value created by Encrypt. Values that are not encrypted
+// are returned unchanged so that existing data can be read until it has been
+// rotated.
+func (k *Keyring) Decrypt(value, context string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errInvalidValue
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", errInvalidValue
+	}
+	masterKey, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("master key version %d is not available", version)
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errInvalidValue
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errInvalidValue
+	}
+	dataKey, err := open(masterKey, wrapped, []byte(context))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, ciphertext, []byte(context))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,148 @@
+package keyring
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testKeys creates a key for each version, encoded for Parse.
+func testKeys(t *testing.T, versions ...int) []string {
+	keys := []string{}
+	for _, v := range versions {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, fmt.Sprintf("%d:%s", v, key))
+	}
+	return keys
+}
+
+// mustParse parses the keys, failing the test on error.
+func mustParse(t *testing.T, keys ...string) *Keyring {
+	k, err := Parse(strings.Join(keys, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := mustParse(t, testKeys(t, 1)...)
+	v1, err := k.Encrypt("secret", "Accounts.AccessToken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := k.Encrypt("secret", "Accounts.AccessToken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(v1) || KeyVersion(v1) != 1 || strings.Contains(v1, "secret") {
+		t.Fatalf("%q is not encrypted with version 1", v1)
+	}
+	if v1 == v2 {
+		t.Fatal("values share a data key or nonce")
+	}
+	for _, v := range []string{v1, v2} {
+		plaintext, err := k.Decrypt(v, "Accounts.AccessToken")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext != "secret" {
+			t.Fatalf("got %q", plaintext)
+		}
+	}
+}
+
+func TestPlaintext(t *testing.T) {
+	k := mustParse(t, testKeys(t, 1)...)
+	v, err := k.Decrypt("secret", "Accounts.AccessToken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "secret" || KeyVersion(v) != 0 {
+		t.Fatalf("got %q", v)
+	}
+}
+
+func TestWrongContext(t *testing.T) {
+	k := mustParse(t, testKeys(t, 1)...)
+	v, err := k.Encrypt("secret", "Accounts.AccessToken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Decrypt(v, "Accounts.AccessSecret"); err == nil {
+		t.Fatal("value was decrypted for another column")
+	}
+}
+
+func TestTampered(t *testing.T) {
+	k := mustParse(t, testKeys(t, 1)...)
+	v, err := k.Encrypt("secret", "Webhooks.Secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tampered := range []string{
+		v[:len(v)-2],
+		strings.Replace(v, "enc:1:", "enc:x:", 1),
+		"enc:1:abc",
+	} {
+		if _, err := k.Decrypt(tampered, "Webhooks.Secret"); err == nil {
+			t.Errorf("%q was decrypted", tampered)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	var (
+		keys = testKeys(t, 1, 2)
+		k1   = mustParse(t, keys[0])
+		k2   = mustParse(t, keys...)
+		k3   = mustParse(t, keys[1])
+	)
+	old, err := k1.Encrypt("secret", "Config.SMTPPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.Current() != 2 {
+		t.Fatalf("current version is %d", k2.Current())
+	}
+	v, err := k2.Decrypt(old, "Config.SMTPPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := k2.Encrypt(v, "Config.SMTPPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyVersion(rotated) != 2 {
+		t.Fatalf("rotated value uses version %d", KeyVersion(rotated))
+	}
+	if _, err := k3.Decrypt(old, "Config.SMTPPassword"); err == nil {
+		t.Fatal("value was decrypted without its master key")
+	}
+	if v, err := k3.Decrypt(rotated, "Config.SMTPPassword"); err != nil || v != "secret" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	keys := testKeys(t, 1, 3)
+	k := mustParse(t, "# old key", keys[0]+", "+keys[1])
+	if k.Current() != 3 || fmt.Sprint(k.Versions()) != "[1 3]" {
+		t.Fatalf("got versions %v with %d current", k.Versions(), k.Current())
+	}
+	for _, s := range []string{
+		"",
+		"# no keys",
+		strings.TrimPrefix(keys[0], "1:"),
+		"0" + strings.TrimPrefix(keys[0], "1"),
+		"1:c2hvcnQ=",
+		keys[0] + "\n" + keys[0],
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%q was parsed", s)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// scopes lists the permissions requested when registering the application.
+const scopes = "read write"
+
+// App contains the credentials issued when registering the application with
+// an instance.
+type App struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// postForm sends a form-encoded request and decodes the response.
+func (c *Client) postForm(path string, v url.Values, resp interface{}) error {
+	req, err := c.newRequest(http.MethodPost, path, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = c.do(req, resp)
+	return err
+}
+
+// RegisterApp registers the application with the instance. This only needs to
+// be done once per instance.
+func (c *Client) RegisterApp(name, redirectURI, website string) (*App, error) {
+	v := url.Values{}
+	v.Set("client_name", name)
+	v.Set("redirect_uris", redirectURI)
+	v.Set("scopes", scopes)
+	if len(website) != 0 {
+		v.Set("website", website)
+	}
+	a := &App{}
+	if err := c.postForm("/api/v1/apps", v, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AuthorizeURL returns the URL that the user must visit to grant access to
+// their account. The state is returned unmodified in the callback.
+func (c *Client) AuthorizeURL(a *App, redirectURI, state string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", a.ClientID)
+	v.Set("redirect_uri", redirectURI)
+	v.Set("scope", scopes)
+	v.Set("state", state)
+	return c.instanceURL + "/oauth/authorize?" + v.Encode()
+}
+
+// ExchangeCode obtains an access token using the code from the callback.
+func (c *Client) ExchangeCode(a *App, redirectURI, code string) (string, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("client_id", a.ClientID)
+	v.Set("client_secret", a.ClientSecret)
+	v.Set("redirect_uri", redirectURI)
+	v.Set("scope", scopes)
+	v.Set("code", code)
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.postForm("/oauth/token", v, &resp); err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
@@ -0,0 +1,84 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// timeout limits the time taken by each request. Media is uploaded in a
+// single request, so it leaves enough time for a large video.
+const timeout = 5 * time.Minute
+
+// Client makes requests to the API of a single Mastodon instance. The access
+// token may be empty for requests that do not require authentication.
+type Client struct {
+	client      *http.Client
+	instanceURL string
+	accessToken string
+}
+
+// NewClient creates a client for the instance at the specified URL.
+func NewClient(instanceURL, accessToken string) *Client {
+	return &Client{
+		client:      &http.Client{Timeout: timeout},
+		instanceURL: strings.TrimRight(instanceURL, "/"),
+		accessToken: accessToken,
+	}
+}
+
+// Error is returned when the API responds with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns a description of the error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("mastodon: %d %s", e.StatusCode, e.Message)
+}
+
+// newRequest creates a request for the specified API path.
+func (c *Client) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.instanceURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.accessToken) != 0 {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+	return req, nil
+}
+
+// do sends the request and decodes the JSON response into v, which may be nil
+// if the response body is not needed. The status code is returned so that
+// callers can distinguish between different successful responses.
+func (c *Client) do(req *http.Request, v interface{}) (int, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := http.StatusText(resp.StatusCode)
+		if json.Unmarshal(b, &e) == nil && len(e.Error) != 0 {
+			msg = e.Error
+		}
+		return resp.StatusCode, &Error{
+			StatusCode: resp.StatusCode,
+			Message:    msg,
+		}
+	}
+	if v == nil {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
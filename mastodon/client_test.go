@@ -0,0 +1,197 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestServer creates an instance that handles requests with the provided
+// handlers, keyed by method and path. Unknown requests fail the test.
+func newTestServer(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[r.Method+" "+r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// writeJSON sends the value as a JSON response with the status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestRegisterApp(t *testing.T) {
+	s := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/v1/apps": func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			expected := map[string]string{
+				"client_name":   "Informas",
+				"redirect_uris": "https://informas.example.com/accounts/mastodon/callback",
+				"scopes":        scopes,
+				"website":       "https://informas.example.com/",
+			}
+			for k, v := range expected {
+				if r.PostForm.Get(k) != v {
+					t.Errorf("%s: got %q, expected %q", k, r.PostForm.Get(k), v)
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]string{
+				"client_id":     "id",
+				"client_secret": "secret",
+			})
+		},
+	})
+	a, err := NewClient(s.URL, "").RegisterApp(
+		"Informas",
+		"https://informas.example.com/accounts/mastodon/callback",
+		"https://informas.example.com/",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ClientID != "id" || a.ClientSecret != "secret" {
+		t.Fatalf("unexpected app %+v", a)
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	s := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /oauth/token": func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			expected := map[string]string{
+				"grant_type":    "authorization_code",
+				"client_id":     "id",
+				"client_secret": "secret",
+				"redirect_uri":  "https://informas.example.com/accounts/mastodon/callback",
+				"code":          "code",
+			}
+			for k, v := range expected {
+				if r.PostForm.Get(k) != v {
+					t.Errorf("%s: got %q, expected %q", k, r.PostForm.Get(k), v)
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]string{
+				"access_token": "token",
+				"token_type":   "Bearer",
+			})
+		},
+	})
+	token, err := NewClient(s.URL, "").ExchangeCode(
+		&App{ClientID: "id", ClientSecret: "secret"},
+		"https://informas.example.com/accounts/mastodon/callback",
+		"code",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token" {
+		t.Fatalf("got token %q", token)
+	}
+}
+
+func TestPostStatusWithMedia(t *testing.T) {
+	polled := false
+	s := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/v2/media": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+			}
+			f, h, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(f)
+			if h.Filename != "image.png" || string(b) != "data" {
+				t.Errorf("got file %q with %q", h.Filename, b)
+			}
+			if r.FormValue("description") != "alt text" {
+				t.Errorf("got description %q", r.FormValue("description"))
+			}
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{
+				"id":  "1",
+				"url": nil,
+			})
+		},
+		"GET /api/v1/media/1": func(w http.ResponseWriter, r *http.Request) {
+			polled = true
+			writeJSON(w, http.StatusOK, map[string]string{
+				"id":  "1",
+				"url": "https://files.example.com/1.png",
+			})
+		},
+		"POST /api/v1/statuses": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Idempotency-Key") != "key" {
+				t.Errorf("got idempotency key %q", r.Header.Get("Idempotency-Key"))
+			}
+			r.ParseForm()
+			if r.PostForm.Get("status") != "Hello" ||
+				!reflect.DeepEqual(r.PostForm["media_ids[]"], []string{"1"}) ||
+				r.PostForm.Get("in_reply_to_id") != "5" ||
+				r.PostForm.Get("spoiler_text") != "cw" ||
+				r.PostForm.Get("sensitive") != "true" {
+				t.Errorf("unexpected form %v", r.PostForm)
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"id": "10"})
+		},
+	})
+	c := NewClient(s.URL, "token")
+	a, err := c.UploadMedia(strings.NewReader("data"), "image.png", "alt text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !polled || a.URL == nil {
+		t.Fatal("processing was not awaited")
+	}
+	st, err := c.PostStatus(&NewStatus{
+		Text:        "Hello",
+		MediaIDs:    []string{a.ID},
+		InReplyToID: "5",
+		SpoilerText: "cw",
+		Sensitive:   true,
+	}, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ID != "10" {
+		t.Fatalf("got status %q", st.ID)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		{http.StatusUnprocessableEntity, `{"error":"Validation failed: Text can't be blank"}`, "Validation failed: Text can't be blank"},
+		{http.StatusUnauthorized, `{"error":"The access token is invalid"}`, "The access token is invalid"},
+		{http.StatusInternalServerError, `<html>oops</html>`, "Internal Server Error"},
+	} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+		_, err := NewClient(s.URL, "token").PostStatus(&NewStatus{Text: "Hello"}, "")
+		s.Close()
+		e, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("%d: got %v, expected *Error", tc.status, err)
+		}
+		if e.StatusCode != tc.status || e.Message != tc.expected {
+			t.Errorf("%d: got %d %q, expected %q", tc.status, e.StatusCode, e.Message, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Conversation is a thread of direct statuses. Accounts lists the
+// participants other than the authenticated account.
+type Conversation struct {
+	ID         string     `json:"id"`
+	Accounts   []*Account `json:"accounts"`
+	LastStatus *Status    `json:"last_status"`
+}
+
+// Context contains the statuses before and after a status in its thread.
+type Context struct {
+	Ancestors   []*Status `json:"ancestors"`
+	Descendants []*Status `json:"descendants"`
+}
+
+// Conversations returns the conversations of the account, most recently
+// updated first.
+func (c *Client) Conversations() ([]*Conversation, error) {
+	v := url.Values{}
+	v.Set("limit", "40")
+	req, err := c.newRequest(http.MethodGet, "/api/v1/conversations?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	conversations := []*Conversation{}
+	if _, err := c.do(req, &conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// StatusContext returns the statuses in the same thread as the status with the
+// specified ID.
+func (c *Client) StatusContext(id string) (*Context, error) {
+	req, err := c.newRequest(http.MethodGet, "/api/v1/statuses/"+url.PathEscape(id)+"/context", nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &Context{}
+	if _, err := c.do(req, ctx); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
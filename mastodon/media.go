@@ -0,0 +1,72 @@
+package mastodon
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// mediaPollInterval determines how often the status of media that is still
+// being processed is checked.
+const mediaPollInterval = 2 * time.Second
+
+// mediaPollAttempts limits how long to wait for processing to complete.
+const mediaPollAttempts = 60
+
+// Attachment describes uploaded media.
+type Attachment struct {
+	ID  string  `json:"id"`
+	URL *string `json:"url"`
+}
+
+// UploadMedia uploads a file with the specified description (alt text) and
+// waits for the instance to finish processing it.
+func (c *Client) UploadMedia(r io.Reader, filename, description string) (*Attachment, error) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	go func() {
+		err := func() error {
+			if len(description) != 0 {
+				if err := w.WriteField("description", description); err != nil {
+					return err
+				}
+			}
+			p, err := w.CreateFormFile("file", filename)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(p, r); err != nil {
+				return err
+			}
+			return w.Close()
+		}()
+		pw.CloseWithError(err)
+	}()
+	req, err := c.newRequest(http.MethodPost, "/api/v2/media", pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	a := &Attachment{}
+	status, err := c.do(req, a)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; status == http.StatusAccepted || status == http.StatusPartialContent; i++ {
+		if i == mediaPollAttempts {
+			return nil, errors.New("mastodon: timed out waiting for media processing")
+		}
+		time.Sleep(mediaPollInterval)
+		req, err := c.newRequest(http.MethodGet, "/api/v1/media/"+a.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if status, err = c.do(req, a); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,34 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Notification describes an event concerning the account, such as a mention.
+type Notification struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Status *Status `json:"status"`
+}
+
+// Mentions returns the most recent notifications of statuses mentioning the
+// account, newest first. If sinceID is not empty, only newer notifications
+// are returned.
+func (c *Client) Mentions(sinceID string) ([]*Notification, error) {
+	v := url.Values{}
+	v.Set("types[]", "mention")
+	v.Set("limit", "40")
+	if len(sinceID) != 0 {
+		v.Set("since_id", sinceID)
+	}
+	req, err := c.newRequest(http.MethodGet, "/api/v1/notifications?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	notifications := []*Notification{}
+	if _, err := c.do(req, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
@@ -0,0 +1,155 @@
+package mastodon
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Visibility determines who can see a status.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+	VisibilityDirect   = "direct"
+)
+
+// Account describes a Mastodon account.
+type Account struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Acct     string `json:"acct"`
+}
+
+// Status describes a post. Content is HTML.
+type Status struct {
+	ID              string    `json:"id"`
+	URL             string    `json:"url"`
+	Content         string    `json:"content"`
+	CreatedAt       time.Time `json:"created_at"`
+	InReplyToID     string    `json:"in_reply_to_id"`
+	Visibility      string    `json:"visibility"`
+	Account         *Account  `json:"account"`
+	FavouritesCount int       `json:"favourites_count"`
+	ReblogsCount    int       `json:"reblogs_count"`
+	RepliesCount    int       `json:"replies_count"`
+}
+
+var (
+	breakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p>`)
+	tagRegexp   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Text converts the HTML content of the status to plain text.
+func (s *Status) Text() string {
+	c := breakRegexp.ReplaceAllString(s.Content, "\n")
+	c = tagRegexp.ReplaceAllString(c, "")
+	return strings.TrimSpace(html.UnescapeString(c))
+}
+
+// WebURL returns the address of the status if it uses HTTP or HTTPS and an
+// empty string otherwise, so that links from remote servers are safe to
+// display.
+func (s *Status) WebURL() string {
+	if strings.HasPrefix(s.URL, "https://") || strings.HasPrefix(s.URL, "http://") {
+		return s.URL
+	}
+	return ""
+}
+
+// NewStatus contains the parameters for posting a status. SpoilerText is
+// displayed as a content warning in front of the text.
+type NewStatus struct {
+	Text        string
+	MediaIDs    []string
+	InReplyToID string
+	Visibility  string
+	SpoilerText string
+	Sensitive   bool
+}
+
+// VerifyCredentials returns the account the client is authenticated as.
+func (c *Client) VerifyCredentials() (*Account, error) {
+	req, err := c.newRequest(http.MethodGet, "/api/v1/accounts/verify_credentials", nil)
+	if err != nil {
+		return nil, err
+	}
+	a := &Account{}
+	if _, err := c.do(req, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// PostStatus publishes a new status. The idempotency key prevents the status
+// from being posted twice if the request is retried.
+func (c *Client) PostStatus(s *NewStatus, idempotencyKey string) (*Status, error) {
+	v := url.Values{}
+	v.Set("status", s.Text)
+	for _, id := range s.MediaIDs {
+		v.Add("media_ids[]", id)
+	}
+	if len(s.InReplyToID) != 0 {
+		v.Set("in_reply_to_id", s.InReplyToID)
+	}
+	if len(s.Visibility) != 0 {
+		v.Set("visibility", s.Visibility)
+	}
+	if len(s.SpoilerText) != 0 {
+		v.Set("spoiler_text", s.SpoilerText)
+	}
+	if s.Sensitive {
+		v.Set("sensitive", "true")
+	}
+	req, err := c.newRequest(http.MethodPost, "/api/v1/statuses", strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(idempotencyKey) != 0 {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	st := &Status{}
+	if _, err := c.do(req, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// AccountStatuses returns the most recent statuses posted by the account,
+// newest first. Boosts and replies are excluded.
+func (c *Client) AccountStatuses(accountID string) ([]*Status, error) {
+	v := url.Values{}
+	v.Set("limit", "40")
+	v.Set("exclude_reblogs", "true")
+	v.Set("exclude_replies", "true")
+	req, err := c.newRequest(
+		http.MethodGet,
+		"/api/v1/accounts/"+url.PathEscape(accountID)+"/statuses?"+v.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []*Status{}
+	if _, err := c.do(req, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// GetStatus returns the status with the specified ID.
+func (c *Client) GetStatus(id string) (*Status, error) {
+	req, err := c.newRequest(http.MethodGet, "/api/v1/statuses/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{}
+	if _, err := c.do(req, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,163 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var errInvalidMP4 = errors.New("invalid MP4 file")
+
+// mp4Box describes a box within an MP4 file. The offset and size refer to the
+// payload of the box, excluding the header.
+type mp4Box struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+// readMP4Boxes reads the headers of the boxes between start and end.
+func readMP4Boxes(r io.ReadSeeker, start, end int64) ([]mp4Box, error) {
+	var (
+		boxes  []mp4Box
+		header = make([]byte, 8)
+	)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		var (
+			size       = int64(binary.BigEndian.Uint32(header[0:4]))
+			headerSize = int64(8)
+		)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := io.ReadFull(r, header); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return nil, errInvalidMP4
+		}
+		boxes = append(boxes, mp4Box{
+			typ:    string(header[4:8]),
+			offset: offset + headerSize,
+			size:   size - headerSize,
+		})
+		offset += size
+	}
+	return boxes, nil
+}
+
+// readMP4Payload reads the payload of a box into memory. Only small boxes
+// should be read this way.
+func readMP4Payload(r io.ReadSeeker, b mp4Box) ([]byte, error) {
+	if b.size > 4096 {
+		return nil, errInvalidMP4
+	}
+	if _, err := r.Seek(b.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	p := make([]byte, b.size)
+	if _, err := io.ReadFull(r, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseMVHD extracts the duration from a movie header box.
+func parseMVHD(p []byte) (time.Duration, error) {
+	var timescale, duration uint64
+	switch {
+	case len(p) >= 20 && p[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(p[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(p[16:20]))
+	case len(p) >= 32 && p[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(p[20:24]))
+		duration = binary.BigEndian.Uint64(p[24:32])
+	default:
+		return 0, errInvalidMP4
+	}
+	if timescale == 0 {
+		return 0, errInvalidMP4
+	}
+	return time.Duration(duration) * time.Second / time.Duration(timescale), nil
+}
+
+// parseTKHD extracts the dimensions from a track header box. They are stored
+// as 16.16 fixed-point values in the last eight bytes.
+func parseTKHD(p []byte) (int, int, error) {
+	if len(p) < 84 {
+		return 0, 0, errInvalidMP4
+	}
+	var (
+		w = binary.BigEndian.Uint32(p[len(p)-8 : len(p)-4])
+		h = binary.BigEndian.Uint32(p[len(p)-4:])
+	)
+	return int(w >> 16), int(h >> 16), nil
+}
+
+// inspectMP4 determines the dimensions of the first video track and the
+// duration of an MP4 file.
+func inspectMP4(r io.ReadSeeker) (*Info, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	top, err := readMP4Boxes(r, 0, end)
+	if err != nil {
+		return nil, err
+	}
+	i := &Info{ContentType: "video/mp4"}
+	for _, b := range top {
+		if b.typ != "moov" {
+			continue
+		}
+		moov, err := readMP4Boxes(r, b.offset, b.offset+b.size)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range moov {
+			switch m.typ {
+			case "mvhd":
+				p, err := readMP4Payload(r, m)
+				if err != nil {
+					return nil, err
+				}
+				if i.Duration, err = parseMVHD(p); err != nil {
+					return nil, err
+				}
+			case "trak":
+				if i.Width != 0 {
+					continue
+				}
+				trak, err := readMP4Boxes(r, m.offset, m.offset+m.size)
+				if err != nil {
+					return nil, err
+				}
+				for _, t := range trak {
+					if t.typ != "tkhd" {
+						continue
+					}
+					p, err := readMP4Payload(r, t)
+					if err != nil {
+						return nil, err
+					}
+					if i.Width, i.Height, err = parseTKHD(p); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		return i, nil
+	}
+	return nil, errInvalidMP4
+}
@@ -0,0 +1,95 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Store persists the contents of uploaded files. Implementations may write to
+// the local filesystem or to a remote blob store.
+type Store interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Stat(key string) (int64, error)
+	Delete(key string) error
+}
+
+// DiskStore keeps files in a directory on the local filesystem.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore creates a store in the specified directory, creating it if it
+// does not exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// filename converts a key to a path within the directory, rejecting keys that
+// would escape it.
+func (d *DiskStore) filename(key string) (string, error) {
+	if len(key) == 0 || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", errors.New("invalid key")
+	}
+	return path.Join(d.dir, key), nil
+}
+
+// Put writes the contents of the reader to a new file. The file is written to
+// a temporary location first so that a partial upload is never visible.
+func (d *DiskStore) Put(key string, r io.Reader) error {
+	filename, err := d.filename(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename+".tmp", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// Get opens the file with the specified key for reading.
+func (d *DiskStore) Get(key string) (io.ReadCloser, error) {
+	filename, err := d.filename(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filename)
+}
+
+// Stat returns the size of the file with the specified key.
+func (d *DiskStore) Stat(key string) (int64, error) {
+	filename, err := d.filename(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	return i.Size(), nil
+}
+
+// Delete removes the file with the specified key.
+func (d *DiskStore) Delete(key string) error {
+	filename, err := d.filename(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
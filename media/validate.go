@@ -0,0 +1,164 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Info describes the contents of an uploaded file.
+type Info struct {
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	Duration    time.Duration
+}
+
+// limits describes the restrictions Twitter places on a type of media.
+type limits struct {
+	category    string
+	maxSize     int64
+	minWidth    int
+	minHeight   int
+	maxWidth    int
+	maxHeight   int
+	minDuration time.Duration
+	maxDuration time.Duration
+}
+
+// twitterLimits lists the media types accepted by Twitter along with their
+// restrictions.
+var twitterLimits = map[string]*limits{
+	"image/jpeg": {
+		category:  "tweet_image",
+		maxSize:   5 * 1024 * 1024,
+		minWidth:  4,
+		minHeight: 4,
+		maxWidth:  8192,
+		maxHeight: 8192,
+	},
+	"image/png": {
+		category:  "tweet_image",
+		maxSize:   5 * 1024 * 1024,
+		minWidth:  4,
+		minHeight: 4,
+		maxWidth:  8192,
+		maxHeight: 8192,
+	},
+	"image/webp": {
+		category:  "tweet_image",
+		maxSize:   5 * 1024 * 1024,
+		minWidth:  4,
+		minHeight: 4,
+		maxWidth:  8192,
+		maxHeight: 8192,
+	},
+	"image/gif": {
+		category:  "tweet_gif",
+		maxSize:   15 * 1024 * 1024,
+		minWidth:  4,
+		minHeight: 4,
+		maxWidth:  1280,
+		maxHeight: 1080,
+	},
+	"video/mp4": {
+		category:    "tweet_video",
+		maxSize:     512 * 1024 * 1024,
+		minWidth:    32,
+		minHeight:   32,
+		maxWidth:    1280,
+		maxHeight:   1024,
+		minDuration: 500 * time.Millisecond,
+		maxDuration: 140 * time.Second,
+	},
+}
+
+// MaxSize is the size of the largest file accepted for any type of media.
+const MaxSize = 512 * 1024 * 1024
+
+// MaxAltTextLength is the maximum number of characters in alt text.
+const MaxAltTextLength = 1000
+
+// Inspect determines the type, size and dimensions of a file.
+func Inspect(r io.ReadSeeker) (*Info, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 512)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	var (
+		contentType = http.DetectContentType(b[:n])
+		i           *Info
+	)
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch contentType {
+	case "video/mp4":
+		i, err = inspectMP4(r)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		c, _, err := image.DecodeConfig(r)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported media type %s", contentType)
+		}
+		i = &Info{
+			ContentType: contentType,
+			Width:       c.Width,
+			Height:      c.Height,
+		}
+	}
+	i.Size = size
+	return i, nil
+}
+
+// Category returns the media category used when uploading to Twitter.
+func (i *Info) Category() string {
+	if l, ok := twitterLimits[i.ContentType]; ok {
+		return l.category
+	}
+	return ""
+}
+
+// Validate ensures that the media falls within the limits imposed by Twitter.
+// The returned error is suitable for displaying to the user.
+func (i *Info) Validate() error {
+	l, ok := twitterLimits[i.ContentType]
+	if !ok {
+		return fmt.Errorf("unsupported media type %s", i.ContentType)
+	}
+	if i.Size > l.maxSize {
+		return fmt.Errorf("file exceeds maximum size of %d MB", l.maxSize/1024/1024)
+	}
+	if i.Width < l.minWidth || i.Height < l.minHeight {
+		return fmt.Errorf("dimensions must be at least %dx%d", l.minWidth, l.minHeight)
+	}
+	if i.Width > l.maxWidth || i.Height > l.maxHeight {
+		return fmt.Errorf("dimensions must not exceed %dx%d", l.maxWidth, l.maxHeight)
+	}
+	if l.maxDuration != 0 {
+		if i.Duration < l.minDuration || i.Duration > l.maxDuration {
+			return errors.New("video must be between 0.5 and 140 seconds long")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pollInterval determines how often new notifications are emailed
+	pollInterval = time.Minute
+
+	// digestInterval is how long notifications are collected for users who
+	// receive a digest
+	digestInterval = 24 * time.Hour
+
+	// maxAttempts is the number of attempts made to email a notification
+	// before giving up
+	maxAttempts = 8
+
+	// initialBackoff is the delay before the first retry, which doubles with
+	// each subsequent attempt up to maxBackoff
+	initialBackoff = 5 * time.Minute
+	maxBackoff     = 6 * time.Hour
+)
+
+// SMTPOptions provides the settings for sending email. Email is disabled if
+// Host is empty.
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	// SiteTitle and SiteURL are included in each message
+	SiteTitle string
+	SiteURL   string
+}
+
+// Mailer emails notifications in the background, either as they arrive or
+// as a daily digest depending on each user's preference.
+type Mailer struct {
+	options func() (*SMTPOptions, error)
+	log     *logrus.Entry
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewMailer creates a new mailer. The options are retrieved before each
+// check so that changes take effect without a restart.
+func NewMailer(options func() (*SMTPOptions, error)) *Mailer {
+	m := &Mailer{
+		options: options,
+		log:     logrus.WithField("context", "notify"),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go m.run()
+	return m
+}
+
+// headerReplacer removes line breaks from header values.
+var headerReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+// send delivers a plain text message.
+func (m *Mailer) send(o *SMTPOptions, to, subject, body string) error {
+	var (
+		addr = net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+		msg  = &bytes.Buffer{}
+		auth smtp.Auth
+	)
+	if len(o.Username) != 0 {
+		auth = smtp.PlainAuth("", o.Username, o.Password, o.Host)
+	}
+	fmt.Fprintf(msg, "From: %s\r\n", headerReplacer.Replace(o.From))
+	fmt.Fprintf(msg, "To: %s\r\n", headerReplacer.Replace(to))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerReplacer.Replace(subject)))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	return smtp.SendMail(addr, auth, o.From, []string{to}, msg.Bytes())
+}
+
+// link returns the absolute URL for a notification.
+func link(o *SMTPOptions, n *db.Notification) string {
+	return strings.TrimSuffix(o.SiteURL, "/") + n.URL
+}
+
+// sendInstant emails a single notification.
+func (m *Mailer) sendInstant(o *SMTPOptions, u *db.User, n *db.Notification) error {
+	return m.send(
+		o,
+		u.Email,
+		fmt.Sprintf("[%s] %s", o.SiteTitle, n.Message),
+		fmt.Sprintf("%s\n\n%s\n", n.Message, link(o, n)),
+	)
+}
+
+// sendDigest emails a summary of several notifications.
+func (m *Mailer) sendDigest(o *SMTPOptions, u *db.User, notifications []*db.Notification) error {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "You have %d new notifications:\n\n", len(notifications))
+	for _, n := range notifications {
+		fmt.Fprintf(b, "- %s\n  %s\n\n", n.Message, link(o, n))
+	}
+	return m.send(
+		o,
+		u.Email,
+		fmt.Sprintf("[%s] %d new notifications", o.SiteTitle, len(notifications)),
+		b.String(),
+	)
+}
+
+// message is an email to a user containing one or more notifications.
+type message struct {
+	user          *db.User
+	notifications []*db.Notification
+	digest        bool
+}
+
+// backoff returns the delay before the next attempt.
+func backoff(attempts int) time.Duration {
+	b := initialBackoff
+	for i := 1; i < attempts && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff {
+		b = maxBackoff
+	}
+	return b
+}
+
+// claim selects the notifications to email and marks them as emailed before
+// the transaction is committed, so that no other worker sends them while
+// the messages are being sent. Notifications for users who do not want
+// email, or when email is not configured, are marked as handled so that they
+// are not sent later. Digests are sent once the oldest notification in them
+// has waited for the digest interval.
+func claim(t *db.Token, o *SMTPOptions, now time.Time) ([]*message, error) {
+	notifications, err := db.UnemailedNotifications(t, now)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		users    = map[int]*db.User{}
+		digests  = map[int]*message{}
+		messages = []*message{}
+		handled  = []*db.Notification{}
+	)
+	for _, n := range notifications {
+		u, ok := users[n.UserID]
+		if !ok {
+			u, err = db.FindUser(t, "ID", n.UserID)
+			if err != nil {
+				return nil, err
+			}
+			users[n.UserID] = u
+		}
+		switch {
+		case len(o.Host) == 0 || len(u.Email) == 0 || u.IsDisabled || u.EmailMode == db.EmailOff:
+			handled = append(handled, n)
+		case u.EmailMode == db.EmailDigest:
+			m, ok := digests[u.ID]
+			if !ok {
+				m = &message{user: u, digest: true}
+				digests[u.ID] = m
+			}
+			m.notifications = append(m.notifications, n)
+		default:
+			messages = append(messages, &message{
+				user:          u,
+				notifications: []*db.Notification{n},
+			})
+			handled = append(handled, n)
+		}
+	}
+	for _, m := range digests {
+		if now.Sub(m.notifications[0].Created) < digestInterval {
+			continue
+		}
+		messages = append(messages, m)
+		handled = append(handled, m.notifications...)
+	}
+	for _, n := range handled {
+		n.IsEmailed = true
+		if err := n.SaveEmail(t); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// retry schedules another attempt at sending the notifications in a message
+// that could not be sent. After too many attempts they remain marked as
+// emailed and are not sent.
+func (m *Mailer) retry(msg *message, now time.Time) error {
+	for _, n := range msg.notifications {
+		n.EmailAttempts++
+		if n.EmailAttempts >= maxAttempts {
+			m.log.WithField("notification", n.ID).Error("giving up on emailing notification")
+		} else {
+			n.IsEmailed = false
+			n.NextEmail = now.Add(backoff(n.EmailAttempts))
+		}
+		if err := n.SaveEmail(&db.Token{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// process emails pending notifications. The notifications are claimed in a
+// transaction and sent after it is committed, so that no locks are held
+// while waiting for the mail server. Messages that cannot be sent are
+// retried with exponential backoff.
+func (m *Mailer) process() error {
+	o, err := m.options()
+	if err != nil {
+		return err
+	}
+	var messages []*message
+	if err := db.Transaction(func(t *db.Token) error {
+		var err error
+		messages, err = claim(t, o, time.Now().UTC())
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		var err error
+		if msg.digest {
+			err = m.sendDigest(o, msg.user, msg.notifications)
+		} else {
+			err = m.sendInstant(o, msg.user, msg.notifications[0])
+		}
+		if err == nil {
+			continue
+		}
+		m.log.WithError(err).WithField("user", msg.user.ID).Error("unable to send email")
+		if err := m.retry(msg, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run emails notifications until stopped.
+func (m *Mailer) run() {
+	defer close(m.stopped)
+	for {
+		if err := m.process(); err != nil {
+			m.log.WithError(err).Error("unable to process notifications")
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Close stops sending email.
+func (m *Mailer) Close() {
+	close(m.stop)
+	<-m.stopped
+}
@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionRegexp matches an @ followed by a username. The @ must not follow
+// a letter or digit so that email addresses are not treated as mentions.
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@([\w.\-]+)`)
+
+// Mentions returns the usernames mentioned in the text in the order in which
+// they first appear. Punctuation ending a sentence is not part of the name.
+func Mentions(text string) []string {
+	var (
+		names = []string{}
+		seen  = map[string]bool{}
+	)
+	for _, m := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimRight(m[1], ".-")
+		if len(name) == 0 || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
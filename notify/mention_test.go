@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		text        string
+		expected    []string
+	}{
+		{"no mentions", "looks good to me", []string{}},
+		{"single mention", "@alice can you check this?", []string{"alice"}},
+		{"several mentions", "cc @alice and @bob_smith", []string{"alice", "bob_smith"}},
+		{"trailing punctuation", "over to you, @alice.", []string{"alice"}},
+		{"dots in names", "thanks @first.last!", []string{"first.last"}},
+		{"repeated in another case", "@Alice and @alice", []string{"Alice"}},
+		{"email address", "write to press@example.com", []string{}},
+		{"lone at sign", "meet @ noon", []string{}},
+	} {
+		if v := Mentions(tc.text); !reflect.DeepEqual(v, tc.expected) {
+			t.Errorf("%s: got %v", tc.description, v)
+		}
+	}
+}
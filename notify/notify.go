@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+// Kinds of notification.
+const (
+	KindApprovalRequired = "approval_required"
+	KindTweetApproved    = "tweet_approved"
+	KindTweetRejected    = "tweet_rejected"
+	KindTweetFailed      = "tweet_failed"
+	KindComment          = "comment"
+	KindCommentMention   = "comment_mention"
+
+	KindConversationAssigned = "conversation_assigned"
+)
+
+// Notify creates a notification for each of the users. Notifications are
+// written using the provided token so that they only appear if the
+// transaction that caused them is committed.
+func Notify(t *db.Token, userIDs []int, kind, message, url string) error {
+	now := time.Now().UTC()
+	for _, id := range userIDs {
+		n := &db.Notification{
+			UserID:  id,
+			Kind:    kind,
+			Message: message,
+			URL:     url,
+			Created: now,
+		}
+		if err := n.Save(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nathan-osman/informas/bluesky"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/twittertext"
+)
+
+const (
+	// maxBlueskyLength is the maximum number of characters in a post.
+	maxBlueskyLength = 300
+
+	// maxBlueskyImages is the maximum number of images embedded in a post.
+	maxBlueskyImages = 4
+)
+
+// mentionRegexp matches mentions of handles such as @example.bsky.social.
+var mentionRegexp = regexp.MustCompile(`(?:^|[\s(])(@([a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)+))`)
+
+// blueskyPublisher creates posts in an AT Protocol repository.
+type blueskyPublisher struct {
+	account *db.Account
+}
+
+func newBlueskyPublisher(a *db.Account) *blueskyPublisher {
+	return &blueskyPublisher{
+		account: a,
+	}
+}
+
+// Validate checks the length of the post and that only images are attached.
+func (b *blueskyPublisher) Validate(p *Post) error {
+	if len(p.Media) > maxBlueskyImages {
+		return errors.New("a post may have at most four images")
+	}
+	for _, m := range p.Media {
+		if !strings.HasPrefix(m.ContentType, "image/") {
+			return errors.New("only images may be attached to a post")
+		}
+	}
+	if len(strings.TrimSpace(p.Text)) == 0 && len(p.Media) == 0 {
+		return errors.New("post is empty")
+	}
+	if n := utf8.RuneCountInString(p.Text); n > maxBlueskyLength {
+		return fmt.Errorf("post is too long (%d/%d)", n, maxBlueskyLength)
+	}
+	return nil
+}
+
+// blueskyFacets annotates links and mentions in the text. Mentions of handles
+// that cannot be resolved are left as plain text.
+func blueskyFacets(c *bluesky.Client, text string) []*bluesky.Facet {
+	facets := []*bluesky.Facet{}
+	for _, s := range twittertext.ExtractURLs(text) {
+		uri := text[s.Start:s.End]
+		if !strings.Contains(uri, "://") {
+			uri = "https://" + uri
+		}
+		facets = append(facets, bluesky.LinkFacet(s.Start, s.End, uri))
+	}
+	for _, m := range mentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+		did, err := c.ResolveHandle(text[m[4]:m[5]])
+		if err != nil {
+			continue
+		}
+		facets = append(facets, bluesky.MentionFacet(m[2], m[3], did))
+	}
+	return facets
+}
+
+// Publish uses the cached session for the account, uploads the images and creates
+// the post. The AT URI of the post is returned.
+func (b *blueskyPublisher) Publish(p *Post) (string, error) {
+	c, err := bluesky.Login(b.account.Instance, b.account.RemoteID, b.account.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	post := &bluesky.Post{
+		Text:   p.Text,
+		Facets: blueskyFacets(c, p.Text),
+	}
+	if len(p.Media) != 0 {
+		images := []*bluesky.Image{}
+		for _, m := range p.Media {
+			blob, err := c.UploadBlob(m.Reader, m.ContentType)
+			if err != nil {
+				return "", err
+			}
+			images = append(images, &bluesky.Image{
+				Image: blob,
+				Alt:   m.AltText,
+			})
+		}
+		post.Embed = bluesky.NewImagesEmbed(images)
+	}
+	if len(p.InReplyTo) != 0 {
+		r, err := c.ReplyTo(p.InReplyTo)
+		if err != nil {
+			return "", err
+		}
+		post.Reply = r
+	}
+	ref, err := c.CreatePost(post)
+	if err != nil {
+		return "", err
+	}
+	return ref.URI, nil
+}
@@ -0,0 +1,79 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/mastodon"
+)
+
+const (
+	// maxMastodonLength is the default character limit for a status,
+	// including the content warning. Some instances allow more.
+	maxMastodonLength = 500
+
+	// maxMastodonMedia is the maximum number of files attached to a status.
+	maxMastodonMedia = 4
+)
+
+// mastodonPublisher posts statuses to a Mastodon instance.
+type mastodonPublisher struct {
+	client *mastodon.Client
+}
+
+func newMastodonPublisher(a *db.Account) *mastodonPublisher {
+	return &mastodonPublisher{
+		client: mastodon.NewClient(a.Instance, a.AccessToken),
+	}
+}
+
+// Validate checks the length of the status, the visibility and the number of
+// files.
+func (m *mastodonPublisher) Validate(p *Post) error {
+	switch p.Visibility {
+	case "",
+		mastodon.VisibilityPublic,
+		mastodon.VisibilityUnlisted,
+		mastodon.VisibilityPrivate,
+		mastodon.VisibilityDirect:
+	default:
+		return errors.New("invalid visibility")
+	}
+	if len(p.Media) > maxMastodonMedia {
+		return errors.New("a status may have at most four attachments")
+	}
+	if len(strings.TrimSpace(p.Text)) == 0 && len(p.Media) == 0 {
+		return errors.New("status is empty")
+	}
+	if n := utf8.RuneCountInString(p.Text) + utf8.RuneCountInString(p.ContentWarning); n > maxMastodonLength {
+		return fmt.Errorf("status is too long (%d/%d)", n, maxMastodonLength)
+	}
+	return nil
+}
+
+// Publish uploads the media and posts the status.
+func (m *mastodonPublisher) Publish(p *Post) (string, error) {
+	mediaIDs := []string{}
+	for _, f := range p.Media {
+		a, err := m.client.UploadMedia(f.Reader, f.Filename, f.AltText)
+		if err != nil {
+			return "", err
+		}
+		mediaIDs = append(mediaIDs, a.ID)
+	}
+	s, err := m.client.PostStatus(&mastodon.NewStatus{
+		Text:        p.Text,
+		MediaIDs:    mediaIDs,
+		InReplyToID: p.InReplyTo,
+		Visibility:  p.Visibility,
+		SpoilerText: p.ContentWarning,
+		Sensitive:   len(p.ContentWarning) != 0,
+	}, p.IdempotencyKey)
+	if err != nil {
+		return "", err
+	}
+	return s.ID, nil
+}
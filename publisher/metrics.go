@@ -0,0 +1,62 @@
+package publisher
+
+import (
+	"strconv"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "informas_tweet_queue_depth",
+			Help: "Number of tweets waiting, by status (pending approval or scheduled).",
+		},
+		[]string{"status"},
+	)
+	tweetsSentTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "informas_tweets_sent_total",
+			Help: "Number of tweets published, by account ID.",
+		},
+		[]string{"account"},
+	)
+	tweetsFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "informas_tweets_failed_total",
+			Help: "Number of tweets that failed permanently or ran out of attempts, by account ID.",
+		},
+		[]string{"account"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		queueDepth,
+		tweetsSentTotal,
+		tweetsFailedTotal,
+	)
+}
+
+// recordOutcome counts a tweet that was sent or failed.
+func recordOutcome(tw *db.Tweet) {
+	account := strconv.Itoa(tw.AccountID)
+	switch tw.Status {
+	case db.TweetSent:
+		tweetsSentTotal.WithLabelValues(account).Inc()
+	case db.TweetFailed:
+		tweetsFailedTotal.WithLabelValues(account).Inc()
+	}
+}
+
+// updateQueueDepth sets the queue depth gauge from the database.
+func updateQueueDepth() error {
+	pending, scheduled, err := db.QueueDepth(&db.Token{})
+	if err != nil {
+		return err
+	}
+	queueDepth.WithLabelValues(db.TweetPending).Set(float64(pending))
+	queueDepth.WithLabelValues(db.TweetScheduled).Set(float64(scheduled))
+	return nil
+}
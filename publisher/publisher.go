@@ -0,0 +1,60 @@
+package publisher
+
+import (
+	"errors"
+	"io"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+// Media is a file to attach to a post.
+type Media struct {
+	Reader      io.Reader
+	Filename    string
+	Size        int64
+	ContentType string
+	AltText     string
+}
+
+// Post contains the content to publish. Options that a platform does not
+// support are ignored.
+type Post struct {
+	Text           string
+	Media          []*Media
+	InReplyTo      string
+	Visibility     string
+	ContentWarning string
+	IdempotencyKey string
+}
+
+// Publisher posts content to an account on a single platform.
+type Publisher interface {
+
+	// Validate checks that the post is acceptable to the platform before any
+	// attempt is made to publish it.
+	Validate(p *Post) error
+
+	// Publish posts the content and returns its ID on the platform so that
+	// later posts can reply to it.
+	Publish(p *Post) (string, error)
+}
+
+// Options contains the application-wide credentials required by some
+// platforms.
+type Options struct {
+	TwitterConsumerKey    string
+	TwitterConsumerSecret string
+}
+
+// New creates a publisher for the specified account.
+func New(a *db.Account, o *Options) (Publisher, error) {
+	switch a.Platform {
+	case db.PlatformTwitter:
+		return newTwitterPublisher(a, o), nil
+	case db.PlatformMastodon:
+		return newMastodonPublisher(a), nil
+	case db.PlatformBluesky:
+		return newBlueskyPublisher(a), nil
+	}
+	return nil, errors.New("unsupported platform")
+}
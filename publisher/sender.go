@@ -0,0 +1,319 @@
+package publisher
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/media"
+	"github.com/nathan-osman/informas/notify"
+	"github.com/nathan-osman/informas/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pollInterval determines how often the queue is checked for tweets
+	pollInterval = 30 * time.Second
+
+	// batchSize limits the number of tweets published on each check; they are
+	// claimed one at a time
+	batchSize = 20
+
+	// maxAttempts is the number of attempts made before a tweet fails
+	maxAttempts = 5
+
+	// initialBackoff is the delay before the first retry, which doubles with
+	// each subsequent attempt up to maxBackoff
+	initialBackoff = time.Minute
+	maxBackoff     = time.Hour
+
+	// leaseDuration is how long a claimed tweet is reserved for publishing its
+	// next part before another worker may claim it. The lease is renewed after
+	// each part and leaves plenty of time to upload and process its media.
+	leaseDuration = time.Hour
+)
+
+// errLeaseLost indicates that another worker claimed the tweet while it was
+// being published, so the outcome must not be recorded.
+var errLeaseLost = errors.New("lease on tweet was lost")
+
+// permanentError indicates that retrying will not help, such as when the
+// platform would reject the content.
+type permanentError struct {
+	error
+}
+
+// Sender publishes scheduled tweets in the background. Threads are published
+// one part at a time; if a part fails, the thread stops there and later
+// attempts resume from that part.
+type Sender struct {
+	options func() (*Options, error)
+	store   media.Store
+	log     *logrus.Entry
+	wake    chan bool
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewSender creates a new sender and begins publishing tweets. The options
+// are retrieved before each check so that changes take effect without a
+// restart.
+func NewSender(options func() (*Options, error), store media.Store) *Sender {
+	s := &Sender{
+		options: options,
+		store:   store,
+		log:     logrus.WithField("context", "publisher"),
+		wake:    make(chan bool, 1),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go s.run()
+	return s
+}
+
+// backoff returns the delay before the next attempt.
+func backoff(attempts int) time.Duration {
+	b := initialBackoff
+	for i := 1; i < attempts && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff {
+		b = maxBackoff
+	}
+	return b
+}
+
+// post prepares a part for publishing with the options of its tweet. The
+// returned files must be closed once the part has been published.
+func (s *Sender) post(tw *db.Tweet, part *db.TweetPart) (*Post, []io.Closer, error) {
+	var (
+		p = &Post{
+			Text:           part.Text,
+			Visibility:     tw.Visibility,
+			ContentWarning: tw.ContentWarning,
+		}
+		files = []io.Closer{}
+	)
+	for _, id := range part.MediaIDs {
+		m, err := db.FindMedia(&db.Token{}, "ID", id)
+		if err != nil {
+			return nil, files, permanentError{err}
+		}
+		f, err := s.store.Get(m.Key)
+		if err != nil {
+			return nil, files, err
+		}
+		files = append(files, f)
+		p.Media = append(p.Media, &Media{
+			Reader:      f,
+			Filename:    m.Key,
+			Size:        m.Size,
+			ContentType: m.ContentType,
+			AltText:     m.AltText,
+		})
+	}
+	return p, files, nil
+}
+
+// publish posts a single part as a reply to the previous part.
+func (s *Sender) publish(pub Publisher, tw *db.Tweet, part *db.TweetPart, inReplyTo string) (string, error) {
+	p, files, err := s.post(tw, part)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if err != nil {
+		return "", err
+	}
+	p.InReplyTo = inReplyTo
+	p.IdempotencyKey = fmt.Sprintf("informas-%d-%d", tw.ID, part.ID)
+	if err := pub.Validate(p); err != nil {
+		return "", permanentError{err}
+	}
+	return pub.Publish(p)
+}
+
+// partError identifies the part that failed, keeping errors permanent.
+func partError(part *db.TweetPart, err error) error {
+	e := fmt.Errorf("part %d: %s", part.Position, err)
+	if _, ok := err.(permanentError); ok {
+		return permanentError{e}
+	}
+	return e
+}
+
+// publishParts publishes the parts that have not yet been published, each as
+// a reply to the one before it. The first part replies to the post the tweet
+// answers, if any. Each part is saved as soon as it is published so that a
+// later attempt does not post it again. Publishing stops at the first part
+// that fails.
+func (s *Sender) publishParts(pub Publisher, tw *db.Tweet, parts []*db.TweetPart, save func(*db.TweetPart) error) error {
+	inReplyTo := tw.InReplyTo
+	for _, part := range parts {
+		if len(part.RemoteID) == 0 {
+			id, err := s.publish(pub, tw, part, inReplyTo)
+			if err != nil {
+				return partError(part, err)
+			}
+			part.RemoteID = id
+			if err := save(part); err != nil {
+				return err
+			}
+		}
+		inReplyTo = part.RemoteID
+	}
+	return nil
+}
+
+// send publishes the remaining parts of the tweet, renewing its lease after
+// each part.
+func (s *Sender) send(o *Options, tw *db.Tweet) error {
+	a, err := db.FindAccount(&db.Token{}, "ID", tw.AccountID)
+	if err != nil {
+		return permanentError{err}
+	}
+	pub, err := New(a, o)
+	if err != nil {
+		return permanentError{err}
+	}
+	parts, err := db.TweetParts(&db.Token{}, tw.ID)
+	if err != nil {
+		return err
+	}
+	return s.publishParts(pub, tw, parts, func(part *db.TweetPart) error {
+		if err := part.Save(&db.Token{}); err != nil {
+			return err
+		}
+		err := tw.RenewLease(&db.Token{}, time.Now().UTC().Add(leaseDuration))
+		if err == sql.ErrNoRows {
+			return errLeaseLost
+		}
+		return err
+	})
+}
+
+// finish records the outcome of an attempt. Webhooks are told when the
+// tweet is sent or fails.
+func (s *Sender) finish(tw *db.Tweet, sendErr error, now time.Time) error {
+	tw.Attempts++
+	tw.Error = ""
+	if sendErr == nil {
+		tw.Status = db.TweetSent
+	} else {
+		tw.Error = sendErr.Error()
+		_, permanent := sendErr.(permanentError)
+		if permanent || tw.Attempts >= maxAttempts {
+			tw.Status = db.TweetFailed
+		} else {
+			tw.NextAttempt = now.Add(backoff(tw.Attempts))
+		}
+	}
+	if err := db.Transaction(func(t *db.Token) error {
+		if err := tw.Save(t); err != nil {
+			return err
+		}
+		switch tw.Status {
+		case db.TweetSent:
+			return webhook.EnqueueTweets(t, webhook.EventTweetSent, tw)
+		case db.TweetFailed:
+			if err := webhook.EnqueueTweets(t, webhook.EventTweetFailed, tw); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		return notify.Notify(
+			t,
+			[]int{tw.UserID},
+			notify.KindTweetFailed,
+			fmt.Sprintf("Tweet #%d could not be published: %s", tw.ID, tw.Error),
+			fmt.Sprintf("/tweets/%d", tw.ID),
+		)
+	}); err != nil {
+		return err
+	}
+	recordOutcome(tw)
+	return nil
+}
+
+// process claims due tweets one at a time, so that each lease starts just
+// before the tweet is published, and returns the number claimed. No
+// transaction is held open while waiting for the platforms.
+func (s *Sender) process() (int, error) {
+	o, err := s.options()
+	if err != nil {
+		return 0, err
+	}
+	claimed := 0
+	for claimed < batchSize {
+		tweets, err := db.ClaimTweets(&db.Token{}, time.Now().UTC(), leaseDuration, 1)
+		if err != nil {
+			return claimed, err
+		}
+		if len(tweets) == 0 {
+			break
+		}
+		claimed++
+		tw := tweets[0]
+		sendErr := s.send(o, tw)
+		if sendErr == errLeaseLost {
+			s.log.WithField("tweet", tw.ID).Warning("tweet was claimed by another worker")
+			continue
+		}
+		if sendErr != nil {
+			s.log.WithError(sendErr).WithField("tweet", tw.ID).Warning("unable to publish tweet")
+		}
+		if err := s.finish(tw, sendErr, time.Now().UTC()); err != nil {
+			return claimed, err
+		}
+	}
+	return claimed, nil
+}
+
+// run publishes tweets until stopped. Full batches are followed immediately
+// by another check.
+func (s *Sender) run() {
+	defer close(s.stopped)
+	for {
+		n, err := s.process()
+		if err != nil {
+			s.log.WithError(err).Error("unable to publish tweets")
+		}
+		if err := updateQueueDepth(); err != nil {
+			s.log.WithError(err).Error("unable to measure queue depth")
+		}
+		if n == batchSize {
+			select {
+			case <-s.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Wake checks for tweets immediately rather than waiting for the next poll.
+// It should be called after committing a transaction that scheduled a tweet.
+func (s *Sender) Wake() {
+	select {
+	case s.wake <- true:
+	default:
+	}
+}
+
+// Close stops publishing tweets.
+func (s *Sender) Close() {
+	close(s.stop)
+	<-s.stopped
+}
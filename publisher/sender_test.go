@@ -0,0 +1,169 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testPublisher records the posts it publishes and fails the post with the
+// specified text once.
+type testPublisher struct {
+	posts   []*Post
+	failOn  string
+	invalid string
+}
+
+func (p *testPublisher) Validate(post *Post) error {
+	if post.Text == p.invalid {
+		return errors.New("invalid")
+	}
+	return nil
+}
+
+func (p *testPublisher) Publish(post *Post) (string, error) {
+	if post.Text == p.failOn {
+		p.failOn = ""
+		return "", errors.New("unavailable")
+	}
+	p.posts = append(p.posts, post)
+	return fmt.Sprintf("remote-%s", post.Text), nil
+}
+
+// newTestThread creates a tweet with a part for each of the texts.
+func newTestThread(texts ...string) (*db.Tweet, []*db.TweetPart) {
+	var (
+		tw    = &db.Tweet{ID: 1}
+		parts = []*db.TweetPart{}
+	)
+	for i, text := range texts {
+		parts = append(parts, &db.TweetPart{
+			ID:       i + 10,
+			TweetID:  tw.ID,
+			Position: i + 1,
+			Text:     text,
+		})
+	}
+	return tw, parts
+}
+
+func TestPublishPartsResumes(t *testing.T) {
+	var (
+		s         = &Sender{}
+		pub       = &testPublisher{failOn: "b"}
+		tw, parts = newTestThread("a", "b", "c")
+		saved     = []string{}
+		save      = func(p *db.TweetPart) error {
+			saved = append(saved, p.Text)
+			return nil
+		}
+	)
+	err := s.publishParts(pub, tw, parts, save)
+	if err == nil || !strings.HasPrefix(err.Error(), "part 2:") {
+		t.Fatalf("got %v", err)
+	}
+	if _, ok := err.(permanentError); ok {
+		t.Fatal("temporary error is permanent")
+	}
+	if parts[0].RemoteID != "remote-a" || parts[1].RemoteID != "" || parts[2].RemoteID != "" {
+		t.Fatal("thread did not stop at the failed part")
+	}
+	if err := s.publishParts(pub, tw, parts, save); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(saved, "") != "abc" || len(pub.posts) != 3 {
+		t.Fatalf("saved %v after %d posts", saved, len(pub.posts))
+	}
+	for i, expected := range []string{"", "remote-a", "remote-b"} {
+		if p := pub.posts[i]; p.InReplyTo != expected {
+			t.Errorf("%s: replied to %q, expected %q", p.Text, p.InReplyTo, expected)
+		}
+	}
+	if pub.posts[0].IdempotencyKey == pub.posts[1].IdempotencyKey {
+		t.Error("parts share an idempotency key")
+	}
+}
+
+func TestPublishPartsReply(t *testing.T) {
+	var (
+		s         = &Sender{}
+		pub       = &testPublisher{}
+		tw, parts = newTestThread("a", "b")
+	)
+	tw.InReplyTo = "mention"
+	if err := s.publishParts(pub, tw, parts, func(*db.TweetPart) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"mention", "remote-a"} {
+		if p := pub.posts[i]; p.InReplyTo != expected {
+			t.Errorf("%s: replied to %q, expected %q", p.Text, p.InReplyTo, expected)
+		}
+	}
+}
+
+func TestPublishPartsInvalid(t *testing.T) {
+	var (
+		s         = &Sender{}
+		pub       = &testPublisher{invalid: "b"}
+		tw, parts = newTestThread("a", "b", "c")
+	)
+	err := s.publishParts(pub, tw, parts, func(*db.TweetPart) error { return nil })
+	if _, ok := err.(permanentError); !ok {
+		t.Fatalf("got %v, expected a permanent error", err)
+	}
+	if len(pub.posts) != 1 {
+		t.Fatalf("published %d parts", len(pub.posts))
+	}
+}
+
+func TestPublishPartsLeaseLost(t *testing.T) {
+	var (
+		s         = &Sender{}
+		pub       = &testPublisher{}
+		tw, parts = newTestThread("a", "b")
+	)
+	err := s.publishParts(pub, tw, parts, func(*db.TweetPart) error { return errLeaseLost })
+	if err != errLeaseLost {
+		t.Fatalf("got %v, expected the lease to be lost", err)
+	}
+	if len(pub.posts) != 1 {
+		t.Fatalf("published %d parts after losing the lease", len(pub.posts))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{10, maxBackoff},
+	} {
+		if b := backoff(tc.attempts); b != tc.expected {
+			t.Errorf("%d attempts: got %s, expected %s", tc.attempts, b, tc.expected)
+		}
+	}
+}
+
+func TestRecordOutcome(t *testing.T) {
+	var (
+		sent   = &db.Tweet{AccountID: 41, Status: db.TweetSent}
+		failed = &db.Tweet{AccountID: 41, Status: db.TweetFailed}
+		retry  = &db.Tweet{AccountID: 41, Status: db.TweetScheduled}
+	)
+	for _, tw := range []*db.Tweet{sent, sent, failed, retry} {
+		recordOutcome(tw)
+	}
+	if v := testutil.ToFloat64(tweetsSentTotal.WithLabelValues("41")); v != 2 {
+		t.Errorf("got %v sent", v)
+	}
+	if v := testutil.ToFloat64(tweetsFailedTotal.WithLabelValues("41")); v != 1 {
+		t.Errorf("got %v failed", v)
+	}
+}
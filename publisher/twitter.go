@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/twitter"
+	"github.com/nathan-osman/informas/twittertext"
+)
+
+// maxTwitterMedia is the maximum number of files attached to a tweet.
+const maxTwitterMedia = 4
+
+// twitterPublisher posts tweets.
+type twitterPublisher struct {
+	client *twitter.Client
+}
+
+func newTwitterPublisher(a *db.Account, o *Options) *twitterPublisher {
+	return &twitterPublisher{
+		client: twitter.NewClient(
+			o.TwitterConsumerKey,
+			o.TwitterConsumerSecret,
+			a.AccessToken,
+			a.AccessSecret,
+		),
+	}
+}
+
+// twitterCategory determines the media category for the type of file.
+func twitterCategory(contentType string) string {
+	switch {
+	case contentType == "image/gif":
+		return "tweet_gif"
+	case strings.HasPrefix(contentType, "video/"):
+		return "tweet_video"
+	}
+	return "tweet_image"
+}
+
+// Validate checks the weighted length of the tweet and the number of files.
+func (t *twitterPublisher) Validate(p *Post) error {
+	if len(p.Media) > maxTwitterMedia {
+		return errors.New("a tweet may have at most four attachments")
+	}
+	if len(p.Media) > 1 {
+		for _, m := range p.Media {
+			if twitterCategory(m.ContentType) != "tweet_image" {
+				return errors.New("videos and GIFs must be the only attachment")
+			}
+		}
+	}
+	if len(p.Media) != 0 && len(strings.TrimSpace(p.Text)) == 0 {
+		return nil
+	}
+	return twittertext.Validate(p.Text)
+}
+
+// Publish uploads the media and posts the tweet.
+func (t *twitterPublisher) Publish(p *Post) (string, error) {
+	mediaIDs := []string{}
+	for _, m := range p.Media {
+		id, err := t.client.UploadMedia(
+			m.Reader,
+			m.Size,
+			m.ContentType,
+			twitterCategory(m.ContentType),
+		)
+		if err != nil {
+			return "", err
+		}
+		if len(m.AltText) != 0 {
+			if err := t.client.SetAltText(id, m.AltText); err != nil {
+				return "", err
+			}
+		}
+		mediaIDs = append(mediaIDs, id)
+	}
+	tweet, err := t.client.UpdateStatus(p.Text, mediaIDs, p.InReplyTo)
+	if err != nil {
+		return "", err
+	}
+	return tweet.IDString, nil
+}
@@ -0,0 +1,218 @@
+package schedule
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule determines when a recurring post is published. Times are always
+// calculated in the schedule's time zone so that a post set for 9:00 stays at
+// 9:00 local time when daylight saving time begins or ends.
+type Schedule struct {
+	spec     string
+	location *time.Location
+	schedule cron.Schedule
+}
+
+// Parse interprets either a standard five-field cron expression or a phrase
+// such as "every Monday 9:00 in Europe/London". If no time zone is given,
+// the default location is used.
+func Parse(spec string, defaultLocation *time.Location) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(strings.ToLower(spec), "every ") {
+		w, err := parsePhrase(spec, defaultLocation)
+		if err != nil {
+			return nil, err
+		}
+		return &Schedule{
+			spec:     spec,
+			location: w.location,
+			schedule: w,
+		}, nil
+	}
+	expr := spec
+	if !strings.HasPrefix(expr, "CRON_TZ=") && !strings.HasPrefix(expr, "TZ=") {
+		expr = "CRON_TZ=" + defaultLocation.String() + " " + expr
+	}
+	c, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, err
+	}
+	loc := defaultLocation
+	if s, ok := c.(*cron.SpecSchedule); ok {
+		loc = s.Location
+	}
+	return &Schedule{
+		spec:     spec,
+		location: loc,
+		schedule: c,
+	}, nil
+}
+
+// Next returns the first time after the specified time that the schedule
+// fires, in UTC.
+func (s *Schedule) Next(after time.Time) time.Time {
+	return s.schedule.Next(after).UTC()
+}
+
+// Location returns the time zone of the schedule.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// String returns the schedule as it was originally specified.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// weekly fires at the same local time on each of the selected days.
+type weekly struct {
+	days     [7]bool
+	hour     int
+	minute   int
+	location *time.Location
+}
+
+// localTime returns the time at which clocks in the location show the
+// specified time of day on the date. time.Date leaves the choice unspecified
+// when clocks change, so both offsets in effect around the date are tried. A
+// time that occurs twice when clocks go back uses the first occurrence and a
+// time that is skipped when clocks go forward is moved forward by the length
+// of the gap, so the post is neither skipped nor sent twice.
+func localTime(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	var (
+		wall   = time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+		guess  = time.Date(year, month, day, hour, minute, 0, 0, loc)
+		_, off = guess.Add(-12 * time.Hour).Zone()
+		result time.Time
+	)
+	before := wall.Add(-time.Duration(off) * time.Second)
+	for _, probe := range []time.Time{guess.Add(-12 * time.Hour), guess, guess.Add(12 * time.Hour)} {
+		_, off := probe.Zone()
+		t := wall.Add(-time.Duration(off) * time.Second)
+		l := t.In(loc)
+		if l.Day() != day || l.Hour() != hour || l.Minute() != minute {
+			continue
+		}
+		if result.IsZero() || t.Before(result) {
+			result = t
+		}
+	}
+	if result.IsZero() {
+		return before.In(loc)
+	}
+	return result.In(loc)
+}
+
+// Next implements cron.Schedule.
+func (w *weekly) Next(after time.Time) time.Time {
+	local := after.In(w.location)
+	for i := 0; i <= 7; i++ {
+		d := local.AddDate(0, 0, i)
+		if !w.days[d.Weekday()] {
+			continue
+		}
+		t := localTime(d.Year(), d.Month(), d.Day(), w.hour, w.minute, w.location)
+		if t.After(after) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	phraseRegexp = regexp.MustCompile(
+		`^every\s+(.+?)\s+(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?(?:\s+in\s+(\S+))?$`,
+	)
+	dayNames = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+	errInvalidPhrase = errors.New(`invalid schedule, expected e.g. "every Monday at 9:00 in Europe/London"`)
+)
+
+// parseDays interprets "day", "weekday", "weekend" or a list of day names
+// separated by commas or "and".
+func parseDays(s string) ([7]bool, error) {
+	var days [7]bool
+	switch s {
+	case "day":
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	case "weekday":
+		for i := time.Monday; i <= time.Friday; i++ {
+			days[i] = true
+		}
+		return days, nil
+	case "weekend":
+		days[time.Saturday] = true
+		days[time.Sunday] = true
+		return days, nil
+	}
+	s = strings.Replace(s, " and ", ",", -1)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSuffix(strings.TrimSpace(name), "s")
+		d, ok := dayNames[name]
+		if !ok {
+			return days, errInvalidPhrase
+		}
+		days[d] = true
+	}
+	return days, nil
+}
+
+// parsePhrase interprets a schedule written in English.
+func parsePhrase(spec string, defaultLocation *time.Location) (*weekly, error) {
+	m := phraseRegexp.FindStringSubmatch(strings.ToLower(spec))
+	if m == nil {
+		return nil, errInvalidPhrase
+	}
+	days, err := parseDays(m[1])
+	if err != nil {
+		return nil, err
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	switch m[4] {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour != 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return nil, errInvalidPhrase
+	}
+	loc := defaultLocation
+	if len(m[5]) != 0 {
+		// Zone names are case-sensitive, so take the name from the original
+		// text rather than the lowercase copy
+		name := spec[strings.LastIndex(strings.ToLower(spec), m[5]):]
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, errors.New("unknown time zone " + name)
+		}
+		loc = l
+	}
+	return &weekly{
+		days:     days,
+		hour:     hour,
+		minute:   minute,
+		location: loc,
+	}, nil
+}
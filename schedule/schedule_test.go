@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// utc parses a time in UTC, failing the test if it is invalid.
+func utc(t *testing.T, v string) time.Time {
+	r, err := time.Parse("2006-01-02 15:04", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// dstTests cover the days on which clocks change in each hemisphere. The
+// expected times are in UTC.
+var dstTests = []struct {
+	description string
+	spec        string
+	after       string
+	expected    string
+}{
+	// New York moves from EST (-5) to EDT (-4) at 2:00 on 10 March 2024
+	// and back at 2:00 on 3 November 2024
+	{"morning before spring forward", "every Saturday at 9:00 in America/New_York", "2024-03-09 00:00", "2024-03-09 14:00"},
+	{"morning of spring forward", "every Sunday at 9:00 in America/New_York", "2024-03-09 00:00", "2024-03-10 13:00"},
+	{"time in the gap moves to the next hour", "every Sunday at 2:30 in America/New_York", "2024-03-09 00:00", "2024-03-10 07:30"},
+	{"daily across spring forward", "every day at 9:00 in America/New_York", "2024-03-10 00:00", "2024-03-10 13:00"},
+	{"morning of fall back", "every Sunday at 9:00 in America/New_York", "2024-11-02 00:00", "2024-11-03 14:00"},
+	{"repeated time uses the first occurrence", "every Sunday at 1:30 in America/New_York", "2024-11-02 00:00", "2024-11-03 05:30"},
+	{"repeated time is not sent twice", "every Sunday at 1:30 in America/New_York", "2024-11-03 05:30", "2024-11-10 06:30"},
+	{"cron expression across fall back", "CRON_TZ=America/New_York 0 9 * * 0", "2024-11-02 00:00", "2024-11-03 14:00"},
+
+	// London moves from GMT (0) to BST (+1) at 1:00 on 31 March 2024 and
+	// back at 2:00 on 27 October 2024
+	{"London spring forward", "every Sunday at 9:00 in Europe/London", "2024-03-30 00:00", "2024-03-31 08:00"},
+	{"London time in the gap", "every Sunday at 1:15 in Europe/London", "2024-03-30 00:00", "2024-03-31 01:15"},
+	{"London fall back", "every Sunday at 9:00 in Europe/London", "2024-10-26 00:00", "2024-10-27 09:00"},
+	{"London repeated time", "every Sunday at 1:30 in Europe/London", "2024-10-26 00:00", "2024-10-27 00:30"},
+
+	// Sydney moves from AEDT (+11) to AEST (+10) at 3:00 on 7 April 2024
+	// and forward at 2:00 on 6 October 2024
+	{"Sydney fall back", "every Sunday at 9:00 in Australia/Sydney", "2024-04-06 00:00", "2024-04-06 23:00"},
+	{"Sydney repeated time", "every Sunday at 2:30 in Australia/Sydney", "2024-04-05 00:00", "2024-04-06 15:30"},
+	{"Sydney spring forward", "every Sunday at 9:00 in Australia/Sydney", "2024-10-05 00:00", "2024-10-05 22:00"},
+	{"Sydney time in the gap", "every Sunday at 2:30 in Australia/Sydney", "2024-10-05 00:00", "2024-10-05 16:30"},
+}
+
+func TestScheduleDST(t *testing.T) {
+	for _, tc := range dstTests {
+		s, err := Parse(tc.spec, time.UTC)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.description, err)
+		}
+		if n := s.Next(utc(t, tc.after)); !n.Equal(utc(t, tc.expected)) {
+			t.Errorf("%s: got %s, expected %s UTC", tc.description, n, tc.expected)
+		}
+	}
+}
+
+func TestNextFreeSlotDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slots := []*Slot{
+		{Weekday: time.Sunday, Hour: 9, Location: loc},
+		{Weekday: time.Sunday, Hour: 1, Minute: 30, Location: loc},
+	}
+	for _, tc := range []struct {
+		description string
+		after       string
+		taken       []string
+		expected    string
+	}{
+		{"earliest slot", "2024-11-02 00:00", nil, "2024-11-03 05:30"},
+		{"repeated time is taken", "2024-11-02 00:00", []string{"2024-11-03 05:30"}, "2024-11-03 14:00"},
+		{"both slots are taken", "2024-11-02 00:00", []string{"2024-11-03 05:30", "2024-11-03 14:00"}, "2024-11-10 06:30"},
+		{"spring forward", "2024-03-09 00:00", []string{"2024-03-10 06:30"}, "2024-03-10 13:00"},
+	} {
+		taken := []time.Time{}
+		for _, v := range tc.taken {
+			taken = append(taken, utc(t, v))
+		}
+		if n := NextFreeSlot(slots, utc(t, tc.after), taken); !n.Equal(utc(t, tc.expected)) {
+			t.Errorf("%s: got %s, expected %s UTC", tc.description, n, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"time"
+)
+
+// slotHorizon limits how far ahead NextFreeSlot searches.
+const slotHorizon = 52 * 7 * 24 * time.Hour
+
+// Slot is a weekly posting time in a particular time zone.
+type Slot struct {
+	Weekday  time.Weekday
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// next returns the first occurrence of the slot after the specified time.
+func (s *Slot) next(after time.Time) time.Time {
+	var days [7]bool
+	days[s.Weekday] = true
+	return (&weekly{
+		days:     days,
+		hour:     s.Hour,
+		minute:   s.Minute,
+		location: s.Location,
+	}).Next(after)
+}
+
+// NextFreeSlot returns the earliest occurrence of any of the slots after the
+// specified time that is not already taken, in UTC. The zero time is returned
+// if there are no slots or all of them are taken for the next year.
+func NextFreeSlot(slots []*Slot, after time.Time, taken []time.Time) time.Time {
+	isTaken := func(t time.Time) bool {
+		for _, x := range taken {
+			if x.Equal(t) {
+				return true
+			}
+		}
+		return false
+	}
+	for cur := after; cur.Sub(after) < slotHorizon; {
+		var earliest time.Time
+		for _, s := range slots {
+			if n := s.next(cur); earliest.IsZero() || n.Before(earliest) {
+				earliest = n
+			}
+		}
+		if earliest.IsZero() {
+			break
+		}
+		if !isTaken(earliest) {
+			return earliest.UTC()
+		}
+		cur = earliest
+	}
+	return time.Time{}
+}
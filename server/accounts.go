@@ -1,13 +1,380 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dghubble/oauth1"
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/bluesky"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/mastodon"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/twitter"
 )
 
+// absoluteURL builds a URL on this site for use in OAuth callbacks. The site
+// URL from the settings is used if it was set, since the request may have
+// been forwarded by a proxy that terminates TLS.
+func (s *Server) absoluteURL(r *http.Request, path string) string {
+	if siteURL := s.config.GetString(configSiteURL); len(siteURL) != 0 {
+		return strings.TrimRight(siteURL, "/") + path
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
+
+// normalizeInstance converts user input such as "mastodon.social" into the
+// base URL of the instance.
+func normalizeInstance(instance string) (string, error) {
+	instance = strings.TrimSpace(instance)
+	if !strings.Contains(instance, "://") {
+		instance = "https://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil || len(u.Host) == 0 {
+		return "", newPublicError("invalid instance", err)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// accountsIndex displays a list of all accounts.
 func (s *Server) accountsIndex(w http.ResponseWriter, r *http.Request) {
-	//...
+	a, err := db.AllAccounts(&db.Token{})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "accountsIndex.html", pongo2.Context{
+		"title":    "Accounts",
+		"accounts": a,
+	})
 }
 
+// accountsNew begins the process of adding an account. The user is sent to
+// the platform to authorize access and returns to the matching callback.
 func (s *Server) accountsNew(w http.ResponseWriter, r *http.Request) {
-	//...
+	var (
+		twitterEnabled = len(s.config.GetString(configTwitterConsumerKey)) != 0
+		instance       string
+		handle         string
+		pds            string
+	)
+	if r.Method == http.MethodPost {
+		var (
+			redirectURL string
+			err         error
+		)
+		switch r.Form.Get("platform") {
+		case db.PlatformTwitter:
+			redirectURL, err = s.beginTwitterAuth(w, r)
+		case db.PlatformMastodon:
+			instance = r.Form.Get("instance")
+			redirectURL, err = s.beginMastodonAuth(w, r, instance)
+		case db.PlatformBluesky:
+			handle = r.Form.Get("handle")
+			pds = r.Form.Get("pds")
+			redirectURL, err = s.addBlueskyAccount(w, r, handle, r.Form.Get("app_password"), pds)
+		default:
+			err = newPublicError("invalid platform", nil)
+		}
+		if err != nil {
+			s.addError(w, r, err)
+		} else {
+			http.Redirect(w, r, redirectURL, http.StatusFound)
+			return
+		}
+	}
+	s.render(w, r, "accountsNew.html", pongo2.Context{
+		"title":           "Add Account",
+		"twitter_enabled": twitterEnabled,
+		"instance":        instance,
+		"handle":          handle,
+		"pds":             pds,
+	})
+}
+
+// twitterAuthConfig creates the OAuth configuration for adding accounts.
+func (s *Server) twitterAuthConfig(r *http.Request) (*oauth1.Config, error) {
+	secret, err := s.config.GetSecret(configTwitterConsumerSecret)
+	if err != nil {
+		return nil, err
+	}
+	return twitter.AuthConfig(
+		s.config.GetString(configTwitterConsumerKey),
+		secret,
+		s.absoluteURL(r, "/accounts/twitter/callback"),
+	), nil
+}
+
+// beginTwitterAuth obtains a request token and returns the URL for the user to
+// authorize it. The secret is kept in the session for the callback.
+func (s *Server) beginTwitterAuth(w http.ResponseWriter, r *http.Request) (string, error) {
+	c, err := s.twitterAuthConfig(r)
+	if err != nil {
+		return "", err
+	}
+	if len(c.ConsumerKey) == 0 {
+		return "", newPublicError("Twitter API credentials are not configured", nil)
+	}
+	token, secret, err := c.RequestToken()
+	if err != nil {
+		return "", newPublicError("unable to contact Twitter", err)
+	}
+	u, err := c.AuthorizationURL(token)
+	if err != nil {
+		return "", err
+	}
+	session, _ := s.sessionStore().Get(r, sessionName)
+	session.Values[sessionOAuthSecret] = secret
+	session.Save(r, w)
+	return u.String(), nil
+}
+
+// accountsTwitterCallback completes the addition of a Twitter account.
+func (s *Server) accountsTwitterCallback(w http.ResponseWriter, r *http.Request) {
+	err := func() error {
+		session, _ := s.sessionStore().Get(r, sessionName)
+		secret, _ := session.Values[sessionOAuthSecret].(string)
+		delete(session.Values, sessionOAuthSecret)
+		session.Save(r, w)
+		token, verifier, err := oauth1.ParseAuthorizationCallback(r)
+		if err != nil || len(secret) == 0 {
+			return newPublicError("authorization was not completed", err)
+		}
+		c, err := s.twitterAuthConfig(r)
+		if err != nil {
+			return err
+		}
+		accessToken, accessSecret, err := c.AccessToken(token, secret, verifier)
+		if err != nil {
+			return newPublicError("unable to obtain access token", err)
+		}
+		u, err := twitter.NewClient(
+			c.ConsumerKey,
+			c.ConsumerSecret,
+			accessToken,
+			accessSecret,
+		).VerifyCredentials()
+		if err != nil {
+			return newPublicError("unable to retrieve account details", err)
+		}
+		a := &db.Account{
+			Platform:     db.PlatformTwitter,
+			RemoteID:     u.IDString,
+			Username:     u.ScreenName,
+			AccessToken:  accessToken,
+			AccessSecret: accessSecret,
+		}
+		return a.Save(&db.Token{})
+	}()
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "account added")
+	}
+	http.Redirect(w, r, "/accounts", http.StatusFound)
+}
+
+// mastodonApp retrieves the credentials for the instance, registering the
+// application if this is the first account added from it. Applications are
+// registered for a single redirect URI, so a new one is registered if the
+// site URL changes.
+func (s *Server) mastodonApp(r *http.Request, instance string) (*mastodon.App, error) {
+	redirectURI := s.absoluteURL(r, "/accounts/mastodon/callback")
+	a, err := db.FindApplication(&db.Token{}, db.PlatformMastodon, instance, redirectURI)
+	if err == nil {
+		return &mastodon.App{
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+		}, nil
+	}
+	app, err := mastodon.NewClient(instance, "").RegisterApp(
+		s.config.GetString(configSiteTitle),
+		redirectURI,
+		s.absoluteURL(r, "/"),
+	)
+	if err != nil {
+		return nil, newPublicError("unable to register with instance", err)
+	}
+	a = &db.Application{
+		Platform:     db.PlatformMastodon,
+		Instance:     instance,
+		RedirectURI:  redirectURI,
+		ClientID:     app.ClientID,
+		ClientSecret: app.ClientSecret,
+	}
+	if err := a.Save(&db.Token{}); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// beginMastodonAuth returns the URL for the user to authorize access to an
+// account on the instance. A random state value guards against forged
+// callbacks.
+func (s *Server) beginMastodonAuth(w http.ResponseWriter, r *http.Request, instance string) (string, error) {
+	instance, err := normalizeInstance(instance)
+	if err != nil {
+		return "", err
+	}
+	app, err := s.mastodonApp(r, instance)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	state := hex.EncodeToString(b)
+	session, _ := s.sessionStore().Get(r, sessionName)
+	session.Values[sessionOAuthState] = state
+	session.Values[sessionOAuthInstance] = instance
+	session.Save(r, w)
+	return mastodon.NewClient(instance, "").AuthorizeURL(
+		app,
+		s.absoluteURL(r, "/accounts/mastodon/callback"),
+		state,
+	), nil
+}
+
+// accountsMastodonCallback completes the addition of a Mastodon account.
+func (s *Server) accountsMastodonCallback(w http.ResponseWriter, r *http.Request) {
+	err := func() error {
+		session, _ := s.sessionStore().Get(r, sessionName)
+		state, _ := session.Values[sessionOAuthState].(string)
+		instance, _ := session.Values[sessionOAuthInstance].(string)
+		delete(session.Values, sessionOAuthState)
+		delete(session.Values, sessionOAuthInstance)
+		session.Save(r, w)
+		code := r.URL.Query().Get("code")
+		if len(state) == 0 || r.URL.Query().Get("state") != state || len(code) == 0 {
+			return newPublicError("authorization was not completed", nil)
+		}
+		app, err := s.mastodonApp(r, instance)
+		if err != nil {
+			return err
+		}
+		accessToken, err := mastodon.NewClient(instance, "").ExchangeCode(
+			app,
+			s.absoluteURL(r, "/accounts/mastodon/callback"),
+			code,
+		)
+		if err != nil {
+			return newPublicError("unable to obtain access token", err)
+		}
+		u, err := mastodon.NewClient(instance, accessToken).VerifyCredentials()
+		if err != nil {
+			return newPublicError("unable to retrieve account details", err)
+		}
+		a := &db.Account{
+			Platform:    db.PlatformMastodon,
+			Instance:    instance,
+			RemoteID:    u.ID,
+			Username:    u.Username,
+			AccessToken: accessToken,
+		}
+		return a.Save(&db.Token{})
+	}()
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "account added")
+	}
+	http.Redirect(w, r, "/accounts", http.StatusFound)
+}
+
+// addBlueskyAccount verifies the app password by creating a session and adds
+// the account. Bluesky has no authorization page, so the user is returned
+// directly to the list of accounts.
+func (s *Server) addBlueskyAccount(w http.ResponseWriter, r *http.Request, handle, appPassword, pds string) (string, error) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if len(pds) == 0 {
+		pds = bluesky.DefaultPDS
+	}
+	pds, err := normalizeInstance(pds)
+	if err != nil {
+		return "", err
+	}
+	c := bluesky.NewClient(pds)
+	if err := c.CreateSession(handle, appPassword); err != nil {
+		return "", newPublicError("invalid handle or app password", err)
+	}
+	a := &db.Account{
+		Platform:    db.PlatformBluesky,
+		Instance:    pds,
+		RemoteID:    c.DID(),
+		Username:    c.Handle(),
+		AccessToken: appPassword,
+	}
+	if err := a.Save(&db.Token{}); err != nil {
+		return "", err
+	}
+	s.addAlert(w, r, alertInfo, "account added")
+	return "/accounts", nil
+}
+
+// findAccount retrieves the account in the URL, ensuring that the current
+// user has access to it.
+func findAccount(t *db.Token, r *http.Request) (*db.Account, error) {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+	if err != nil {
+		return nil, newPublicError("invalid account", err)
+	}
+	ok, err := db.HasAccess(t, currentUser, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newPublicError("invalid account", nil)
+	}
+	return a, nil
+}
+
+// accountsIdDelete allows accounts to be removed.
+func (s *Server) accountsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var account *db.Account
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		if r.Method == http.MethodPost {
+			if err := a.Delete(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "account deleted")
+		http.Redirect(w, r, "/accounts", http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsDelete.html", pongo2.Context{
+		"title":   "Delete Account",
+		"account": account,
+	})
+}
+
+// publisherOptions returns the application credentials needed to access
+// accounts.
+func (s *Server) publisherOptions() (*publisher.Options, error) {
+	secret, err := s.config.GetSecret(configTwitterConsumerSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &publisher.Options{
+		TwitterConsumerKey:    s.config.GetString(configTwitterConsumerKey),
+		TwitterConsumerSecret: secret,
+	}, nil
 }
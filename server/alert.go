@@ -24,19 +24,20 @@ func init() {
 }
 
 // addAlert stores the provided alert in the current session for retrieval when
-// the next page is rendered.
-func (s *Server) addAlert(w http.ResponseWriter, r *http.Request, type_ alertType, body string) {
-	session, _ := s.sessions.Get(r, sessionName)
+// the next page is rendered. The body is translated into the language of the
+// request and formatted with the arguments, if any.
+func (s *Server) addAlert(w http.ResponseWriter, r *http.Request, type_ alertType, body string, args ...interface{}) {
+	session, _ := s.sessionStore().Get(r, sessionName)
 	defer session.Save(r, w)
 	session.AddFlash(&alert{
 		Type: type_,
-		Body: body,
+		Body: s.catalog(r).T(body, args...),
 	})
 }
 
 // getAlerts retrieves all of the alerts for the current session.
 func (s *Server) getAlerts(w http.ResponseWriter, r *http.Request) interface{} {
-	session, _ := s.sessions.Get(r, sessionName)
+	session, _ := s.sessionStore().Get(r, sessionName)
 	defer session.Save(r, w)
 	return session.Flashes()
 }
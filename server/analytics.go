@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/analytics"
+	"github.com/nathan-osman/informas/db"
+)
+
+// chartBar is a single bar in a chart. Percent is its height relative to the
+// tallest bar.
+type chartBar struct {
+	Label   string
+	Value   int
+	Percent int
+}
+
+// newChart creates a chart from the values, scaling the bars so that the
+// tallest fills the chart.
+func newChart(labels []string, values []int) []*chartBar {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	bars := make([]*chartBar, len(values))
+	for i, v := range values {
+		bars[i] = &chartBar{
+			Label: labels[i],
+			Value: v,
+		}
+		if max != 0 {
+			bars[i].Percent = v * 100 / max
+		}
+	}
+	return bars
+}
+
+// analyticsTotals sums the most recent snapshots of all posts. Impressions is
+// -1 if no post has an impression count.
+type analyticsTotals struct {
+	Posts       int
+	Likes       int
+	Reposts     int
+	Replies     int
+	Impressions int
+}
+
+// dailyEngagement totals the engagement with posts by the day they were
+// published in the user's time zone, oldest day first.
+func dailyEngagement(stats []*db.PostStats, loc *time.Location, now time.Time, days int) []*chartBar {
+	var (
+		today  = now.In(loc)
+		start  = time.Date(today.Year(), today.Month(), today.Day()-days+1, 0, 0, 0, 0, loc)
+		labels = make([]string, days)
+		values = make([]int, days)
+	)
+	for i := range labels {
+		labels[i] = start.AddDate(0, 0, i).Format("2 Jan")
+	}
+	for _, s := range stats {
+		p := s.Post.Published.In(loc)
+		d := time.Date(p.Year(), p.Month(), p.Day(), 0, 0, 0, 0, loc)
+		if d.Before(start) {
+			continue
+		}
+		// Days are not always 24 hours long when daylight saving time
+		// changes, so round to the nearest day
+		i := int(d.Sub(start).Hours()+12) / 24
+		if i < days {
+			values[i] += s.Metric.Engagement()
+		}
+	}
+	return newChart(labels, values)
+}
+
+// accountsIdAnalytics displays the engagement with posts published by an
+// account during the tracking period.
+func (s *Server) accountsIdAnalytics(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		now         = time.Now().UTC()
+		account     *db.Account
+		stats       []*db.PostStats
+		top         []*db.PostStats
+		totals      = &analyticsTotals{Impressions: -1}
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := findAccount(t, r)
+		if err != nil {
+			return err
+		}
+		account = a
+		since := now.Add(-analytics.TrackingPeriod)
+		stats, err = db.AccountPostStats(t, a.ID, since, db.OrderPublished, 0)
+		if err != nil {
+			return err
+		}
+		top, err = db.AccountPostStats(t, a.ID, since, db.OrderEngagement, 10)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	for _, st := range stats {
+		totals.Posts++
+		totals.Likes += st.Metric.Likes
+		totals.Reposts += st.Metric.Reposts
+		if st.Metric.Replies > 0 {
+			totals.Replies += st.Metric.Replies
+		}
+		if st.Metric.Impressions >= 0 {
+			if totals.Impressions < 0 {
+				totals.Impressions = 0
+			}
+			totals.Impressions += st.Metric.Impressions
+		}
+	}
+	s.render(w, r, "accountsAnalytics.html", pongo2.Context{
+		"title":   "Analytics",
+		"account": account,
+		"totals":  totals,
+		"chart":   dailyEngagement(stats, s.timePrefs(currentUser).Location, now, 30),
+		"top":     top,
+	})
+}
+
+// accountsIdAnalyticsCsv exports the most recent snapshot of every tracked
+// post published by an account.
+func (s *Server) accountsIdAnalyticsCsv(w http.ResponseWriter, r *http.Request) {
+	var (
+		account *db.Account
+		stats   []*db.PostStats
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := findAccount(t, r)
+		if err != nil {
+			return err
+		}
+		account = a
+		stats, err = db.AccountPostStats(t, a.ID, time.Time{}, db.OrderPublished, 0)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	optional := func(v int) string {
+		if v < 0 {
+			return ""
+		}
+		return strconv.Itoa(v)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"analytics-%s-%d.csv\"", account.Platform, account.ID),
+	)
+	c := csv.NewWriter(w)
+	c.Write([]string{
+		"published", "url", "text", "likes", "reposts", "replies", "impressions", "recorded",
+	})
+	for _, st := range stats {
+		c.Write([]string{
+			st.Post.Published.Format(time.RFC3339),
+			st.Post.URL,
+			st.Post.Text,
+			strconv.Itoa(st.Metric.Likes),
+			strconv.Itoa(st.Metric.Reposts),
+			optional(st.Metric.Replies),
+			optional(st.Metric.Impressions),
+			st.Metric.Recorded.Format(time.RFC3339),
+		})
+	}
+	c.Flush()
+}
+
+// accountsIdAnalyticsId displays how the engagement with a single post
+// changed over time.
+func (s *Server) accountsIdAnalyticsId(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		account     *db.Account
+		post        *db.Post
+		metrics     []*db.Metric
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := findAccount(t, r)
+		if err != nil {
+			return err
+		}
+		account = a
+		p, err := db.FindPost(t, "ID", atoi(mux.Vars(r)["post"]))
+		if err != nil || p.AccountID != a.ID {
+			return newPublicError("invalid post", err)
+		}
+		post = p
+		metrics, err = db.PostMetrics(t, p.ID)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		if account == nil {
+			http.Redirect(w, r, "/", http.StatusFound)
+		} else {
+			http.Redirect(w, r, fmt.Sprintf("/accounts/%d/analytics", account.ID), http.StatusFound)
+		}
+		return
+	}
+	var (
+		loc    = s.timePrefs(currentUser).Location
+		labels = make([]string, len(metrics))
+		values = make([]int, len(metrics))
+	)
+	for i, m := range metrics {
+		labels[i] = m.Recorded.In(loc).Format("2 Jan 15:04")
+		values[i] = m.Engagement()
+	}
+	s.render(w, r, "accountsAnalyticsPost.html", pongo2.Context{
+		"title":   "Post Analytics",
+		"account": account,
+		"post":    post,
+		"metrics": metrics,
+		"chart":   newChart(labels, values),
+	})
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/approval"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/notify"
+	"github.com/nathan-osman/informas/webhook"
+)
+
+// stageView is an approval stage with the names of its users for display.
+type stageView struct {
+	*db.Stage
+	ApproverNames string
+	EscalateNames string
+}
+
+// usernames returns the names of the users with the specified IDs.
+func usernames(users []*db.User, ids []int) string {
+	names := []string{}
+	for _, u := range users {
+		for _, id := range ids {
+			if u.ID == id {
+				names = append(names, u.Username)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// formUserIDs reads the users selected in a form field, ensuring that each
+// of them is one of the provided users.
+func formUserIDs(r *http.Request, field string, users []*db.User) ([]int, error) {
+	ids := []int{}
+	for _, v := range r.Form[field] {
+		id := atoi(v)
+		if len(usernames(users, []int{id})) == 0 {
+			return nil, newPublicError("invalid user", nil)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// addStage adds an approval stage from the form to the account.
+func addStage(t *db.Token, r *http.Request, a *db.Account, users []*db.User) error {
+	stage := &db.Stage{
+		AccountID:     a.ID,
+		Name:          strings.TrimSpace(r.Form.Get("name")),
+		Required:      atoi(r.Form.Get("required")),
+		EscalateHours: atoi(r.Form.Get("escalate_hours")),
+	}
+	if len(stage.Name) == 0 {
+		return newPublicError("name is required", nil)
+	}
+	if stage.EscalateHours < 0 {
+		return newPublicError("invalid escalation time", nil)
+	}
+	approvers, err := formUserIDs(r, "approvers", users)
+	if err != nil {
+		return err
+	}
+	escalateTo, err := formUserIDs(r, "escalate_to", users)
+	if err != nil {
+		return err
+	}
+	stage.Approvers = approvers
+	stage.EscalateTo = escalateTo
+	p := &approval.Policy{
+		Stages: []*approval.Stage{
+			{
+				Name:          stage.Name,
+				Required:      stage.Required,
+				Approvers:     stage.Approvers,
+				EscalateAfter: time.Duration(stage.EscalateHours) * time.Hour,
+				EscalateTo:    stage.EscalateTo,
+			},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		return newPublicError("invalid number of approvals", err)
+	}
+	if err := stage.Save(t); err != nil {
+		return newPublicError("unable to add stage", err)
+	}
+	return nil
+}
+
+// accountsIdApproval displays the approval policy for an account and allows
+// stages to be added and trusted users to be chosen.
+func (s *Server) accountsIdApproval(w http.ResponseWriter, r *http.Request) {
+	var (
+		account *db.Account
+		stages  []*stageView
+		users   []*db.User
+		trusted []int
+		action  = r.Form.Get("action")
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		users, err = db.AccountUsers(t, a.ID)
+		if err != nil {
+			return err
+		}
+		if r.Method == http.MethodPost {
+			switch action {
+			case "stage":
+				if err := addStage(t, r, a, users); err != nil {
+					return err
+				}
+			case "trusted":
+				ids, err := formUserIDs(r, "trusted", users)
+				if err != nil {
+					return err
+				}
+				if err := db.SetTrustedUsers(t, a.ID, ids); err != nil {
+					return err
+				}
+			default:
+				return newPublicError("invalid action", nil)
+			}
+		}
+		dbStages, err := db.AccountStages(t, a.ID)
+		if err != nil {
+			return err
+		}
+		for _, stage := range dbStages {
+			stages = append(stages, &stageView{
+				Stage:         stage,
+				ApproverNames: usernames(users, stage.Approvers),
+				EscalateNames: usernames(users, stage.EscalateTo),
+			})
+		}
+		trusted, err = db.TrustedUsers(t, a.ID)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		if action == "stage" {
+			s.addAlert(w, r, alertInfo, "stage added")
+		} else {
+			s.addAlert(w, r, alertInfo, "trusted users updated")
+		}
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsApproval.html", pongo2.Context{
+		"title":   "Approval Policy",
+		"account": account,
+		"stages":  stages,
+		"users":   users,
+		"trusted": trusted,
+	})
+}
+
+// accountsIdApprovalIdDelete removes an approval stage.
+func (s *Server) accountsIdApprovalIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		accountID = atoi(mux.Vars(r)["id"])
+		redirect  = fmt.Sprintf("/accounts/%d/approval", accountID)
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	if err := db.DeleteStage(&db.Token{}, accountID, atoi(mux.Vars(r)["stage"])); err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "stage deleted")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// approvalView is the progress of a tweet's group through its approval
+// policy for display. CanReview is set if the current user may approve or
+// reject the current stage, CanEdit if they may change its text and
+// Escalates if the stage will be escalated at the time in Escalation.
+type approvalView struct {
+	Status     string
+	Stage      string
+	Number     int
+	Stages     int
+	Approved   string
+	Waiting    string
+	CanReview  bool
+	CanEdit    bool
+	Escalates  bool
+	Escalation time.Time
+}
+
+// newApprovalView describes the approval state of the group for the user.
+// Nil is returned for groups that were approved without any stages.
+func newApprovalView(t *db.Token, g *db.TweetGroup, tweets []*db.Tweet, u *db.User) (*approvalView, error) {
+	p, err := approval.LoadGroup(t, g, tweets)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Stages) == 0 && g.Status == string(approval.StatusApproved) {
+		return nil, nil
+	}
+	users, err := db.AllUsers(t, "Username")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		state     = approval.GroupState(g)
+		approvers = p.Approvers(state)
+		v         = &approvalView{
+			Status:   g.Status,
+			Number:   g.Stage + 1,
+			Stages:   len(p.Stages),
+			Approved: usernames(users, g.Approvals),
+			Waiting:  usernames(users, approvers),
+			CanEdit:  canEdit(p, g, u),
+		}
+	)
+	if g.Stage < len(p.Stages) {
+		v.Stage = p.Stages[g.Stage].Name
+	}
+	for _, id := range approvers {
+		if id == u.ID {
+			v.CanReview = true
+		}
+	}
+	v.Escalation, v.Escalates = p.EscalationTime(state)
+	return v, nil
+}
+
+// reviewError converts an error from the approval engine into one that can
+// be shown to the user.
+func reviewError(err error) error {
+	switch err {
+	case approval.ErrNotPending:
+		return newPublicError("this tweet is no longer waiting for approval", err)
+	case approval.ErrNotApprover:
+		return newPublicError("you are not an approver for this stage", err)
+	case approval.ErrAlreadyApproved:
+		return newPublicError("you have already approved this stage", err)
+	case approval.ErrAuthor:
+		return newPublicError("you cannot approve your own tweet", err)
+	}
+	return err
+}
+
+// reviewTweet approves or rejects the current stage of the tweet's group and
+// saves the new state along with the time of the user's review. Once the
+// group is approved its tweets are scheduled and once it is rejected they are
+// never published; in either case the author and webhooks are notified,
+// along with the reason for a rejection if one was given. When the next stage
+// begins, its approvers are notified instead. The new status of the group is
+// returned.
+func reviewTweet(t *db.Token, r *http.Request, reject bool, reason string) (approval.Status, error) {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	tw, err := findTweet(t, r)
+	if err != nil {
+		return "", err
+	}
+	g, err := db.FindTweetGroup(t, tw.GroupID)
+	if err != nil {
+		return "", err
+	}
+	tweets, err := db.GroupTweets(t, g.ID)
+	if err != nil {
+		return "", err
+	}
+	p, err := approval.LoadGroup(t, g, tweets)
+	if err != nil {
+		return "", err
+	}
+	var (
+		now   = time.Now().UTC()
+		state = approval.GroupState(g)
+		n     *approval.State
+	)
+	if reject {
+		n, err = p.Reject(state, currentUser.ID, now)
+	} else {
+		n, err = p.Approve(state, currentUser.ID, now)
+	}
+	if err != nil {
+		return "", reviewError(err)
+	}
+	approval.SetGroupState(g, n)
+	if err := g.Save(t); err != nil {
+		return "", err
+	}
+	rv := &db.Review{
+		GroupID:  g.ID,
+		UserID:   currentUser.ID,
+		Reviewed: now,
+	}
+	if err := rv.Save(t); err != nil {
+		return "", err
+	}
+	var (
+		url   = fmt.Sprintf("/tweets/%d", tw.ID)
+		kind  string
+		event string
+		msg   string
+	)
+	switch n.Status {
+	case approval.StatusApproved:
+		kind = notify.KindTweetApproved
+		event = webhook.EventTweetApproved
+		msg = fmt.Sprintf("Tweet #%d was approved by %s", tw.ID, currentUser.Username)
+	case approval.StatusRejected:
+		kind = notify.KindTweetRejected
+		event = webhook.EventTweetRejected
+		msg = fmt.Sprintf("Tweet #%d was rejected by %s", tw.ID, currentUser.Username)
+		if len(reason) != 0 {
+			msg = fmt.Sprintf("Tweet #%d was rejected by %s: %s", tw.ID, currentUser.Username, reason)
+		}
+	default:
+		if n.Stage == state.Stage {
+			return n.Status, nil
+		}
+		return n.Status, notify.Notify(
+			t,
+			p.Approvers(n),
+			notify.KindApprovalRequired,
+			fmt.Sprintf("Tweet #%d has reached the %s stage and needs your approval", tw.ID, p.Stages[n.Stage].Name),
+			url,
+		)
+	}
+	for _, o := range tweets {
+		if o.Status != db.TweetPending {
+			continue
+		}
+		o.Status = db.TweetRejected
+		if n.Status == approval.StatusApproved {
+			o.Status = db.TweetScheduled
+			if o.NextAttempt.Before(now) {
+				o.NextAttempt = now
+			}
+		}
+		if err := o.Save(t); err != nil {
+			return "", err
+		}
+		if err := webhook.EnqueueTweets(t, event, o); err != nil {
+			return "", err
+		}
+	}
+	return n.Status, notify.Notify(t, []int{g.UserID}, kind, msg, url)
+}
+
+// tweetsIdApprove records the current user's approval of a tweet.
+func (s *Server) tweetsIdApprove(w http.ResponseWriter, r *http.Request) {
+	redirect := fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	var status approval.Status
+	err := db.Transaction(func(t *db.Token) error {
+		var err error
+		status, err = reviewTweet(t, r, false, "")
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if status == approval.StatusApproved {
+		s.sender.Wake()
+		s.webhooks.Wake()
+		s.addAlert(w, r, alertInfo, "tweet approved")
+	} else {
+		s.addAlert(w, r, alertInfo, "approval recorded")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// tweetsIdReject rejects a tweet on behalf of the current stage. The reason,
+// if one is given, is added to the tweet's comments.
+func (s *Server) tweetsIdReject(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+		reason      = strings.TrimSpace(r.Form.Get("text"))
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		if len(reason) != 0 {
+			tw, err := findTweet(t, r)
+			if err != nil {
+				return err
+			}
+			g, p, err := groupPolicy(t, tw)
+			if err != nil {
+				return err
+			}
+			if err := addComment(t, tw, g, p, currentUser, reason); err != nil {
+				return err
+			}
+		}
+		_, err := reviewTweet(t, r, true, reason)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.webhooks.Wake()
+		s.addAlert(w, r, alertInfo, "tweet rejected")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/nathan-osman/informas/db"
+)
+
+// dateLayout is the format of the dates used to navigate the calendar.
+const dateLayout = "2006-01-02"
+
+// calendarTweet is a tweet shown on the calendar. Movable is set if the tweet
+// has not been published and may be dragged to another day.
+type calendarTweet struct {
+	ID      int
+	Status  string
+	Time    string
+	Text    string
+	Movable bool
+}
+
+// calendarDay is a single day of the calendar. Outside is set for the days
+// shown before and after the month being displayed.
+type calendarDay struct {
+	Date    string
+	Day     int
+	Outside bool
+	Today   bool
+	Tweets  []*calendarTweet
+}
+
+// calendarRange returns the first day shown by the view containing the date
+// and the number of days it shows. Weeks begin on Sunday and the month view
+// shows whole weeks.
+func calendarRange(view string, date time.Time) (time.Time, int) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	if view != "month" {
+		return day.AddDate(0, 0, -int(day.Weekday())), 7
+	}
+	var (
+		first = day.AddDate(0, 0, 1-day.Day())
+		last  = first.AddDate(0, 1, -1)
+		days  = last.Day() + int(first.Weekday()) + 6 - int(last.Weekday())
+	)
+	return first.AddDate(0, 0, -int(first.Weekday())), days
+}
+
+// accountsIdCalendar displays the tweets for an account by week or month.
+// Tweets that have not been published can be dragged to another day.
+func (s *Server) accountsIdCalendar(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		loc         = s.timePrefs(currentUser).Location
+		now         = time.Now().In(loc)
+		date        = now
+		view        = r.Form.Get("view")
+		account     *db.Account
+	)
+	if view != "month" {
+		view = "week"
+	}
+	if d, err := time.ParseInLocation(dateLayout, r.Form.Get("date"), loc); err == nil {
+		date = d
+	}
+	var (
+		start, n = calendarRange(view, date)
+		days     = []*calendarDay{}
+		byDate   = map[string]*calendarDay{}
+		weeks    = [][]*calendarDay{}
+		prev     = date.AddDate(0, 0, -7)
+		next     = date.AddDate(0, 0, 7)
+	)
+	if view == "month" {
+		prev = date.AddDate(0, 0, 1-date.Day()).AddDate(0, -1, 0)
+		next = date.AddDate(0, 0, 1-date.Day()).AddDate(0, 1, 0)
+	}
+	for i := 0; i < n; i++ {
+		d := start.AddDate(0, 0, i)
+		day := &calendarDay{
+			Date:    d.Format(dateLayout),
+			Day:     d.Day(),
+			Outside: view == "month" && d.Month() != date.Month(),
+			Today:   d.Format(dateLayout) == now.Format(dateLayout),
+		}
+		days = append(days, day)
+		byDate[day.Date] = day
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := findAccount(t, r)
+		if err != nil {
+			return err
+		}
+		account = a
+		tweets, err := db.AccountTweets(t, a.ID, start.UTC(), start.AddDate(0, 0, n).UTC())
+		if err != nil {
+			return err
+		}
+		for _, tw := range tweets {
+			parts, err := db.TweetParts(t, tw.ID)
+			if err != nil {
+				return err
+			}
+			var (
+				scheduled = tw.Scheduled.In(loc)
+				ct        = &calendarTweet{
+					ID:      tw.ID,
+					Status:  tw.Status,
+					Time:    scheduled.Format("15:04"),
+					Movable: tw.Status == db.TweetPending || tw.Status == db.TweetScheduled,
+				}
+			)
+			if len(parts) != 0 {
+				ct.Text = parts[0].Text
+			}
+			if day, ok := byDate[scheduled.Format(dateLayout)]; ok {
+				day.Tweets = append(day.Tweets, ct)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	for i := 0; i < len(days); i += 7 {
+		weeks = append(weeks, days[i:i+7])
+	}
+	s.render(w, r, "accountsCalendar.html", pongo2.Context{
+		"title":    "Calendar",
+		"account":  account,
+		"view":     view,
+		"date":     date.Format(dateLayout),
+		"prev":     prev.Format(dateLayout),
+		"next":     next.Format(dateLayout),
+		"weeks":    weeks,
+		"weekdays": weekdays,
+	})
+}
+
+// tweetsIdReschedule moves a tweet that has not been published to the time
+// in the JSON object sent with the request, which is in the user's time
+// zone. Tweets that are due may already be in the hands of the sender and
+// cannot be moved. The new time is returned.
+func (s *Server) tweetsIdReschedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		loc         = s.timePrefs(currentUser).Location
+		v           struct {
+			Scheduled string `json:"scheduled"`
+		}
+		tweet *db.Tweet
+	)
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil || len(v.Scheduled) == 0 {
+		s.writeJSONError(w, r, newPublicError("invalid time", err))
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if tw.Status != db.TweetPending && tw.Status != db.TweetScheduled {
+			return newPublicError("only tweets that have not been published can be rescheduled", nil)
+		}
+		if tw.Status == db.TweetScheduled && !tw.Scheduled.After(now) {
+			return newPublicError("the tweet is already being published", nil)
+		}
+		scheduled, err := parseScheduled(v.Scheduled, loc, now)
+		if err != nil {
+			return err
+		}
+		tw.Scheduled = scheduled
+		tw.NextAttempt = scheduled
+		tweet = tw
+		return tw.Save(t)
+	})
+	if err != nil {
+		s.writeJSONError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":        tweet.ID,
+		"scheduled": tweet.Scheduled,
+	})
+}
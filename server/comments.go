@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/approval"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/notify"
+)
+
+// threadAccess determines whether the user may read the comments on a group
+// and whether they may add to them. The author, the approvers of every stage
+// and the users who were mentioned or took part in the discussion may read
+// it. Comments can only be added while the group is waiting for approval.
+func threadAccess(p *approval.Policy, g *db.TweetGroup, comments []*db.Comment, u *db.User) (bool, bool) {
+	ids := append([]int{g.UserID}, p.Reviewers()...)
+	for _, c := range comments {
+		ids = append(append(ids, c.UserID), c.Mentions...)
+	}
+	for _, id := range ids {
+		if id == u.ID {
+			return true, g.Status == string(approval.StatusPending)
+		}
+	}
+	return false, false
+}
+
+// addComment adds a comment by the user to the discussion of the tweet's
+// group. Mentioned users are notified, as is the author if someone else
+// wrote the comment. Names that do not belong to a user with access to the
+// tweet's account are left as plain text since nobody else can open it.
+func addComment(t *db.Token, tw *db.Tweet, g *db.TweetGroup, p *approval.Policy, u *db.User, text string) error {
+	comments, err := db.GroupComments(t, g.ID)
+	if err != nil {
+		return err
+	}
+	if _, ok := threadAccess(p, g, comments, u); !ok {
+		return newPublicError("only the author and approvers can comment on tweets waiting for approval", nil)
+	}
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return newPublicError("comment is empty", nil)
+	}
+	users, err := db.AccountUsers(t, tw.AccountID)
+	if err != nil {
+		return err
+	}
+	c := &db.Comment{
+		GroupID: g.ID,
+		UserID:  u.ID,
+		Author:  u.Username,
+		Text:    text,
+	}
+	for _, name := range notify.Mentions(text) {
+		var found *db.User
+		for _, v := range users {
+			if strings.EqualFold(v.Username, name) {
+				found = v
+			}
+		}
+		if found != nil && found.ID != u.ID {
+			c.Mentions = append(c.Mentions, found.ID)
+		}
+	}
+	if err := c.Save(t); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("/tweets/%d#comments", tw.ID)
+	if err := notify.Notify(
+		t,
+		c.Mentions,
+		notify.KindCommentMention,
+		fmt.Sprintf("%s mentioned you in a comment on tweet #%d", u.Username, tw.ID),
+		url,
+	); err != nil {
+		return err
+	}
+	if g.UserID == u.ID {
+		return nil
+	}
+	for _, id := range c.Mentions {
+		if id == g.UserID {
+			return nil
+		}
+	}
+	return notify.Notify(
+		t,
+		[]int{g.UserID},
+		notify.KindComment,
+		fmt.Sprintf("%s commented on tweet #%d", u.Username, tw.ID),
+		url,
+	)
+}
+
+// tweetsIdComments adds a comment to the discussion of a tweet that is
+// waiting for approval.
+func (s *Server) tweetsIdComments(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		g, p, err := groupPolicy(t, tw)
+		if err != nil {
+			return err
+		}
+		return addComment(t, tw, g, p, currentUser, r.Form.Get("text"))
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "comment added")
+	}
+	http.Redirect(w, r, redirect+"#comments", http.StatusFound)
+}
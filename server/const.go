@@ -4,16 +4,40 @@ const (
 	// Indicate whether installation was completed
 	configInstalled = "installed"
 
-	// Secret key used for encrypting sessions
+	// Secret key used for signing sessions before they were encrypted
 	configSecretKey = "secret_key"
 
+	// Keys for signing and encrypting sessions, newest first
+	configSessionKeys = "session_keys"
+
 	// Title shown in the <title> for each page
 	configSiteTitle = "site_title"
+
+	// Address of the site, used for links that leave the site
+	configSiteURL = "site_url"
+
+	// Twitter application credentials used for all accounts
+	configTwitterConsumerKey    = "twitter_key"
+	configTwitterConsumerSecret = "twitter_secret"
+
+	// Default time zone and date format for users without their own
+	configTimeZone   = "time_zone"
+	configDateFormat = "date_format"
+
+	// SMTP server used for sending notifications
+	configSMTPHost     = "smtp_host"
+	configSMTPPort     = "smtp_port"
+	configSMTPUsername = "smtp_username"
+	configSMTPPassword = "smtp_password"
+	configSMTPFrom     = "smtp_from"
 )
 
 const (
 	// Currently logged in user
 	contextCurrentUser = "current_user"
+
+	// Message catalog for the language of the current request
+	contextCatalog = "catalog"
 )
 
 const (
@@ -22,4 +46,11 @@ const (
 
 	// ID of currently logged in user
 	sessionUserID = "user_id"
+
+	// Request token secret while authorizing a Twitter account
+	sessionOAuthSecret = "oauth_secret"
+
+	// State and instance while authorizing a Mastodon account
+	sessionOAuthState    = "oauth_state"
+	sessionOAuthInstance = "oauth_instance"
 )
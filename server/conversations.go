@@ -0,0 +1,276 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/inbox"
+	"github.com/nathan-osman/informas/notify"
+)
+
+// conversationItem is a conversation along with its account and assignee for
+// display.
+type conversationItem struct {
+	Conversation *db.Conversation
+	Account      *db.Account
+	Assignee     *db.User
+}
+
+// messageItem is a message along with the user who wrote it in Informas, if
+// any.
+type messageItem struct {
+	Message *db.Message
+	User    *db.User
+}
+
+// isConversationStatus determines whether the value is a valid conversation
+// state.
+func isConversationStatus(v string) bool {
+	switch v {
+	case db.ConversationOpen, db.ConversationPending, db.ConversationClosed:
+		return true
+	}
+	return false
+}
+
+// conversations lists the direct message conversations of the accounts the
+// current user has access to. Open conversations are shown unless another
+// state is selected.
+func (s *Server) conversations(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		q           = r.URL.Query()
+		filter      = &db.ConversationFilter{
+			AccountID: atoi(q.Get("account")),
+			Status:    q.Get("status"),
+			Limit:     100,
+		}
+		accounts []*db.Account
+		items    []*conversationItem
+	)
+	if _, ok := q["status"]; !ok {
+		filter.Status = db.ConversationOpen
+	} else if !isConversationStatus(filter.Status) {
+		filter.Status = ""
+	}
+	if q.Get("mine") != "" {
+		filter.AssigneeID = currentUser.ID
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.UserAccounts(t, currentUser)
+		if err != nil {
+			return err
+		}
+		accounts = a
+		for _, a := range accounts {
+			filter.AccountIDs = append(filter.AccountIDs, a.ID)
+		}
+		conversations, err := db.FilterConversations(t, filter)
+		if err != nil {
+			return err
+		}
+		users, err := db.AllUsers(t, "Username")
+		if err != nil {
+			return err
+		}
+		for _, c := range conversations {
+			item := &conversationItem{Conversation: c}
+			for _, a := range accounts {
+				if a.ID == c.AccountID {
+					item.Account = a
+				}
+			}
+			for _, u := range users {
+				if u.ID == c.AssigneeID {
+					item.Assignee = u
+				}
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "conversations.html", pongo2.Context{
+		"title":    "Messages",
+		"accounts": accounts,
+		"items":    items,
+		"filter":   filter,
+		"mine":     q.Get("mine") != "",
+	})
+}
+
+// updateConversation applies an action other than replying to the
+// conversation.
+func (s *Server) updateConversation(t *db.Token, r *http.Request, c *db.Conversation, users []*db.User) error {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	switch r.Form.Get("action") {
+	case "note":
+		text := strings.TrimSpace(r.Form.Get("text"))
+		if len(text) == 0 {
+			return newPublicError("note is empty", nil)
+		}
+		m := &db.Message{
+			ConversationID: c.ID,
+			UserID:         currentUser.ID,
+			Sender:         currentUser.Username,
+			Text:           text,
+			IsNote:         true,
+			Created:        time.Now().UTC(),
+		}
+		return m.Save(t)
+	case "assign":
+		assigneeID := atoi(r.Form.Get("user_id"))
+		if assigneeID != 0 && !containsUser(users, assigneeID) {
+			return newPublicError("user does not have access to the account", nil)
+		}
+		if assigneeID != 0 && assigneeID != currentUser.ID && assigneeID != c.AssigneeID {
+			if err := notify.Notify(
+				t,
+				[]int{assigneeID},
+				notify.KindConversationAssigned,
+				fmt.Sprintf("%s assigned you a conversation with %s", currentUser.Username, c.Participants),
+				fmt.Sprintf("/conversations/%d", c.ID),
+			); err != nil {
+				return err
+			}
+		}
+		c.AssigneeID = assigneeID
+	case "status":
+		status := r.Form.Get("status")
+		if !isConversationStatus(status) {
+			return newPublicError("invalid status", nil)
+		}
+		c.Status = status
+	default:
+		return newPublicError("invalid action", nil)
+	}
+	return c.Save(t)
+}
+
+// replyConversation sends a direct message to the participants of the
+// conversation. The conversation is then pending until they respond.
+func (s *Server) replyConversation(r *http.Request, c *db.Conversation, a *db.Account, messages []*db.Message) error {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	text := strings.TrimSpace(r.Form.Get("text"))
+	if len(text) == 0 {
+		return newPublicError("message is empty", nil)
+	}
+	var inReplyTo string
+	for _, m := range messages {
+		if !m.IsNote && len(m.RemoteID) != 0 {
+			inReplyTo = m.RemoteID
+		}
+	}
+	o, err := s.publisherOptions()
+	if err != nil {
+		return err
+	}
+	src, err := inbox.New(a, o)
+	if err != nil {
+		return err
+	}
+	m, err := src.Send(c, inReplyTo, text)
+	if err != nil {
+		return newPublicError("unable to send message", err)
+	}
+	return db.Transaction(func(t *db.Token) error {
+		m.ConversationID = c.ID
+		m.UserID = currentUser.ID
+		if err := m.Save(t); err != nil {
+			return err
+		}
+		if m.Created.After(c.Updated) {
+			c.Updated = m.Created
+		}
+		c.Status = db.ConversationPending
+		return c.Save(t)
+	})
+}
+
+// conversationsId displays a conversation along with its internal notes and
+// allows replies and notes to be added and the conversation to be assigned or
+// have its state changed.
+func (s *Server) conversationsId(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser  = context.Get(r, contextCurrentUser).(*db.User)
+		conversation *db.Conversation
+		account      *db.Account
+		messages     []*db.Message
+		items        []*messageItem
+		users        []*db.User
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		c, err := db.FindConversation(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid conversation", err)
+		}
+		ok, err := db.HasAccess(t, currentUser, c.AccountID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return newPublicError("invalid conversation", nil)
+		}
+		conversation = c
+		account, err = db.FindAccount(t, "ID", c.AccountID)
+		if err != nil {
+			return err
+		}
+		users, err = db.AccountUsers(t, c.AccountID)
+		if err != nil {
+			return err
+		}
+		if r.Method == http.MethodPost && r.Form.Get("action") != "reply" {
+			return s.updateConversation(t, r, c, users)
+		}
+		messages, err = db.ConversationMessages(t, c.ID)
+		if err != nil {
+			return err
+		}
+		allUsers, err := db.AllUsers(t, "Username")
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			item := &messageItem{Message: m}
+			for _, u := range allUsers {
+				if u.ID == m.UserID {
+					item.User = u
+				}
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err == nil && r.Method == http.MethodPost && r.Form.Get("action") == "reply" {
+		err = s.replyConversation(r, conversation, account, messages)
+	}
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "conversation updated")
+	}
+	if conversation == nil {
+		http.Redirect(w, r, "/conversations", http.StatusFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		http.Redirect(w, r, fmt.Sprintf("/conversations/%d", conversation.ID), http.StatusFound)
+		return
+	}
+	s.render(w, r, "conversationsId.html", pongo2.Context{
+		"title":        "Conversation",
+		"conversation": conversation,
+		"account":      account,
+		"items":        items,
+		"users":        users,
+	})
+}
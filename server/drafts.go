@@ -0,0 +1,128 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+)
+
+// draftView is a draft of a new tweet for display. Summary is the text of
+// the first part that is not empty.
+type draftView struct {
+	*db.Draft
+	Summary string
+}
+
+// draftValues decodes the form values stored in a draft. The ID of the draft
+// is included so that the form keeps saving to it.
+func draftValues(d *db.Draft) (url.Values, error) {
+	v, err := url.ParseQuery(d.Form)
+	if err != nil {
+		return nil, err
+	}
+	v.Set("draft", strconv.Itoa(d.ID))
+	return v, nil
+}
+
+// userDrafts retrieves the drafts of new tweets written by the user.
+func userDrafts(t *db.Token, u *db.User) ([]*draftView, error) {
+	drafts, err := db.UserDrafts(t, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	views := []*draftView{}
+	for _, d := range drafts {
+		v, err := url.ParseQuery(d.Form)
+		if err != nil {
+			return nil, err
+		}
+		view := &draftView{Draft: d}
+		for _, text := range v["text"] {
+			if len(strings.TrimSpace(text)) != 0 {
+				view.Summary = text
+				break
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// draftsSave stores the values of the compose form or of the form used to
+// edit a tweet so that they are not lost before the form is submitted. The
+// ID of the draft is returned so that later saves replace it. Only one draft
+// is kept for each tweet that the user edits.
+func (s *Server) draftsSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		draftID     = atoi(r.PostForm.Get("draft"))
+		tweetID     = atoi(r.PostForm.Get("tweet"))
+		values      = url.Values{}
+		draft       *db.Draft
+	)
+	for k, v := range r.PostForm {
+		if k != "draft" && k != "tweet" {
+			values[k] = v
+		}
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		var err error
+		switch {
+		case tweetID != 0:
+			if _, err := findUserTweet(t, currentUser, tweetID); err != nil {
+				return err
+			}
+			draft, err = db.FindTweetDraft(t, currentUser.ID, tweetID)
+			if err == sql.ErrNoRows {
+				draft = &db.Draft{UserID: currentUser.ID, TweetID: tweetID}
+			} else if err != nil {
+				return err
+			}
+		case draftID != 0:
+			draft, err = db.FindDraft(t, currentUser.ID, draftID)
+			if err != nil {
+				return newPublicError("invalid draft", err)
+			}
+		default:
+			draft = &db.Draft{UserID: currentUser.ID}
+		}
+		draft.Form = values.Encode()
+		return draft.Save(t)
+	})
+	if err != nil {
+		s.writeJSONError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      draft.ID,
+		"updated": draft.Updated,
+	})
+}
+
+// draftsIdDelete removes a draft of a new tweet.
+func (s *Server) draftsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = "/tweets/new"
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	if err := db.DeleteDraft(&db.Token{}, currentUser.ID, atoi(mux.Vars(r)["id"])); err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "draft deleted")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+)
+
+// publicError is an error with a message that is safe to display to users.
+// The underlying cause, if any, is only ever written to the log.
+type publicError struct {
+	message string
+	cause   error
+}
+
+// newPublicError creates a new error with the specified message and cause. The
+// cause may be nil.
+func newPublicError(message string, cause error) error {
+	return &publicError{
+		message: message,
+		cause:   cause,
+	}
+}
+
+// Error returns the message intended for the user.
+func (p *publicError) Error() string {
+	return p.message
+}
+
+// addError displays an error to the user. Only messages from publicError are
+// shown as-is - anything else is logged against the request ID and replaced
+// with a generic message so that internal details do not leak.
+func (s *Server) addError(w http.ResponseWriter, r *http.Request, err error) {
+	if p, ok := err.(*publicError); ok {
+		if p.cause != nil {
+			s.requestLog(r).WithError(p.cause).Warning(p.message)
+		}
+		s.addAlert(w, r, alertDanger, p.message)
+		return
+	}
+	s.requestLog(r).WithError(err).Error("unexpected error")
+	s.addAlert(w, r, alertDanger, "an internal error occurred (request %s)", requestID(r))
+}
+
+// writeJSONError responds to a JSON request with an error, following the
+// same rules as addError for which messages are shown.
+func (s *Server) writeJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	if p, ok := err.(*publicError); ok {
+		if p.cause != nil {
+			s.requestLog(r).WithError(p.cause).Warning(p.message)
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": s.catalog(r).T(p.message),
+		})
+		return
+	}
+	s.requestLog(r).WithError(err).Error("unexpected error")
+	writeJSON(w, http.StatusInternalServerError, map[string]string{
+		"error": s.catalog(r).T("an internal error occurred (request %s)", requestID(r)),
+	})
+}
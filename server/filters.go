@@ -0,0 +1,96 @@
+package server
+
+import (
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/nathan-osman/informas/db"
+)
+
+// defaultDateFormat is used when neither the user nor the site has chosen a
+// date format.
+const defaultDateFormat = "Monday 2 January 2006 at 15:04 MST"
+
+// dateFormats lists the layouts that may be chosen for displaying times.
+var dateFormats = []string{
+	defaultDateFormat,
+	"Mon Jan 2, 2006 3:04 PM MST",
+	"2006-01-02 15:04 MST",
+	"02/01/2006 15:04 MST",
+	"01/02/2006 3:04 PM MST",
+}
+
+// isDateFormat determines whether the layout is one of the available formats.
+func isDateFormat(layout string) bool {
+	for _, f := range dateFormats {
+		if f == layout {
+			return true
+		}
+	}
+	return false
+}
+
+// timePrefs determines how times are displayed to a user.
+type timePrefs struct {
+	Location *time.Location
+	Format   string
+}
+
+// timePrefs returns the time zone and date format for the user, falling back
+// to the site defaults and finally to UTC. The user may be nil.
+func (s *Server) timePrefs(u *db.User) *timePrefs {
+	var (
+		zone   = s.config.GetString(configTimeZone)
+		format = s.config.GetString(configDateFormat)
+	)
+	if u != nil {
+		if len(u.TimeZone) != 0 {
+			zone = u.TimeZone
+		}
+		if len(u.DateFormat) != 0 {
+			format = u.DateFormat
+		}
+	}
+	p := &timePrefs{
+		Location: time.UTC,
+		Format:   defaultDateFormat,
+	}
+	if l, err := time.LoadLocation(zone); err == nil {
+		p.Location = l
+	}
+	if len(format) != 0 {
+		p.Format = format
+	}
+	return p
+}
+
+func init() {
+	pongo2.RegisterFilter("weekday", filterWeekday)
+	pongo2.RegisterFilter("localtime", filterLocaltime)
+}
+
+// filterWeekday converts a day number (0 for Sunday) into its name.
+func filterWeekday(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	d := in.Integer()
+	if d < 0 || d > 6 {
+		return pongo2.AsValue(""), nil
+	}
+	return pongo2.AsValue(time.Weekday(d).String()), nil
+}
+
+// filterLocaltime formats a time in the viewer's time zone using their date
+// format. The parameter is the "tz" value injected by render.
+func filterLocaltime(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	t, ok := in.Interface().(time.Time)
+	if !ok || t.IsZero() {
+		return pongo2.AsValue(""), nil
+	}
+	p, ok := param.Interface().(*timePrefs)
+	if !ok || p == nil {
+		p = &timePrefs{
+			Location: time.UTC,
+			Format:   defaultDateFormat,
+		}
+	}
+	return pongo2.AsValue(t.In(p.Location).Format(p.Format)), nil
+}
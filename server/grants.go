@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+)
+
+// accountsIdGrants displays the users with access to an account and allows
+// access to be granted to others.
+func (s *Server) accountsIdGrants(w http.ResponseWriter, r *http.Request) {
+	var (
+		account *db.Account
+		granted []*db.User
+		users   []*db.User
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		if r.Method == http.MethodPost {
+			u, err := db.FindUser(t, "ID", atoi(r.Form.Get("user_id")))
+			if err != nil {
+				return newPublicError("invalid user", err)
+			}
+			g := &db.Grant{
+				AccountID: a.ID,
+				UserID:    u.ID,
+			}
+			if err := g.Save(t); err != nil {
+				return err
+			}
+		}
+		granted, err = db.GrantedUsers(t, a.ID)
+		if err != nil {
+			return err
+		}
+		users, err = db.AllUsers(t, "Username")
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "access granted")
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsGrants.html", pongo2.Context{
+		"title":   "Access",
+		"account": account,
+		"granted": granted,
+		"users":   users,
+	})
+}
+
+// accountsIdGrantsIdDelete revokes a user's access to an account.
+func (s *Server) accountsIdGrantsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		accountID = atoi(mux.Vars(r)["id"])
+		redirect  = fmt.Sprintf("/accounts/%d/grants", accountID)
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	g := &db.Grant{
+		AccountID: accountID,
+		UserID:    atoi(mux.Vars(r)["user"]),
+	}
+	if err := g.Delete(&db.Token{}); err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "access revoked")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+// healthz indicates that the process is alive and able to serve requests.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, "ok\n")
+}
+
+// readyz indicates whether the application is able to do useful work - the
+// database must be reachable, all migrations applied, and installation
+// completed.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	var reason string
+	switch {
+	case db.Ping() != nil:
+		reason = "database unreachable"
+	case !db.IsMigrated():
+		reason = "migrations pending"
+	case s.config.GetInt(configInstalled) == 0:
+		reason = "installation incomplete"
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	if len(reason) != 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, reason+"\n")
+		return
+	}
+	io.WriteString(w, "ok\n")
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/nathan-osman/informas/i18n"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// locale describes a language that users may choose.
+type locale struct {
+	Tag  string
+	Name string
+}
+
+// catalog returns the message catalog for the request. Requests that did not
+// pass through a view, such as those for error pages, use the browser's
+// preference.
+func (s *Server) catalog(r *http.Request) *i18n.Catalog {
+	if c, ok := context.Get(r, contextCatalog).(*i18n.Catalog); ok {
+		return c
+	}
+	return s.locales.Match(r.Header.Get("Accept-Language"))
+}
+
+// availableLocales lists the languages with a catalog, each named in its own
+// language.
+func (s *Server) availableLocales() []*locale {
+	l := []*locale{}
+	for _, t := range s.locales.Languages() {
+		l = append(l, &locale{
+			Tag:  t,
+			Name: display.Self.Name(language.Make(t)),
+		})
+	}
+	return l
+}
+
+// isLocale determines whether a catalog exists for the language.
+func (s *Server) isLocale(tag string) bool {
+	for _, t := range s.locales.Languages() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/nathan-osman/informas/db"
+)
+
+const (
+	// maxImportSize limits the size of an uploaded CSV file
+	maxImportSize = 1024 * 1024
+
+	// maxImportRows limits the number of tweets in a single import
+	maxImportRows = 500
+)
+
+var (
+	errImportFailed = newPublicError("nothing was imported because some rows are invalid", nil)
+	errImportEmpty  = newPublicError("the file does not contain any tweets", nil)
+
+	// errDryRun rolls back the transaction once every row has been checked
+	errDryRun = errors.New("dry run")
+)
+
+// importRow is a row of an imported CSV file and the outcome of creating its
+// tweet. Error is a message to be translated and Detail explains it further
+// where the platform rejected the text.
+type importRow struct {
+	Line      int    `json:"line"`
+	Account   string `json:"account"`
+	Text      string `json:"text"`
+	Scheduled string `json:"scheduled"`
+	Media     string `json:"media"`
+	TweetID   int    `json:"tweet_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// readImport reads the rows of a CSV file with the columns account, text,
+// scheduled time and media. A header row is skipped if present.
+func readImport(r io.Reader) ([]*importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, newPublicError("invalid CSV file", err)
+	}
+	rows := []*importRow{}
+	for i, rec := range records {
+		if i == 0 && len(rec) != 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "account") {
+			continue
+		}
+		row := &importRow{Line: i + 1}
+		for j, v := range []*string{&row.Account, &row.Text, &row.Scheduled, &row.Media} {
+			if j < len(rec) {
+				*v = rec[j]
+			}
+		}
+		if len(rec) < 3 || len(rec) > 4 {
+			row.Error = "expected account, text, scheduled time and media"
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errImportEmpty
+	}
+	if len(rows) > maxImportRows {
+		return nil, newPublicError("the file contains too many tweets", nil)
+	}
+	return rows, nil
+}
+
+// importAccount finds the account named in a row, which may be given by
+// username, with or without the @, or by ID.
+func importAccount(accounts []*db.Account, v string) *db.Account {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "@")
+	for _, a := range accounts {
+		if strings.EqualFold(a.Username, v) || strconv.Itoa(a.ID) == v {
+			return a
+		}
+	}
+	return nil
+}
+
+// importMedia converts the media column into the IDs of uploaded files.
+// Files are never downloaded from URLs since the server would then fetch
+// arbitrary addresses on behalf of users; they must be uploaded first.
+func importMedia(v string) (string, error) {
+	ids := []string{}
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	}) {
+		if strings.Contains(f, "://") {
+			return "", newPublicError("media must be uploaded and referenced by ID", nil)
+		}
+		if _, err := strconv.Atoi(f); err != nil {
+			return "", newPublicError("invalid attachment", err)
+		}
+		ids = append(ids, f)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// importTweet creates the tweet for a row in the same way as the compose
+// form, so that the same validation and approval rules apply. Problems with
+// the row are recorded in it; only unexpected errors are returned.
+func (s *Server) importTweet(t *db.Token, u *db.User, accounts []*db.Account, row *importRow) error {
+	if len(row.Error) != 0 {
+		return nil
+	}
+	a := importAccount(accounts, row.Account)
+	if a == nil {
+		row.Error = "invalid account"
+		return nil
+	}
+	media, err := importMedia(row.Media)
+	if err != nil {
+		row.Error = err.Error()
+		return nil
+	}
+	scheduled := strings.Replace(strings.TrimSpace(row.Scheduled), " ", "T", 1)
+	if len(scheduled) == 0 {
+		row.Error = "a scheduled time is required"
+		return nil
+	}
+	f := &composeForm{
+		AccountIDs: []int{a.ID},
+		Parts:      []*composePart{newComposePart(row.Text, media)},
+		Scheduled:  scheduled,
+		Targets: []*composeTarget{
+			{Account: a, Selected: true},
+		},
+	}
+	tweets, err := s.createTweets(t, u, f, s.timePrefs(u).Location)
+	if err != nil {
+		p, ok := err.(*publicError)
+		if !ok {
+			return err
+		}
+		row.Error = p.message
+		row.Detail = f.Parts[0].Error
+		return nil
+	}
+	row.TweetID = tweets[0].ID
+	row.Status = tweets[0].Status
+	return nil
+}
+
+// runImport creates a tweet for each row in a single transaction. Nothing is
+// created if any row is invalid or if this is a dry run, in which case the
+// rows still show whether each tweet would be scheduled or held for approval.
+func (s *Server) runImport(u *db.User, rows []*importRow, dryRun bool) error {
+	err := db.Transaction(func(t *db.Token) error {
+		accounts, err := db.UserAccounts(t, u)
+		if err != nil {
+			return err
+		}
+		failed := false
+		for _, row := range rows {
+			if err := s.importTweet(t, u, accounts, row); err != nil {
+				return err
+			}
+			if len(row.Error) != 0 {
+				failed = true
+			}
+		}
+		if failed {
+			return errImportFailed
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == errDryRun {
+		return nil
+	}
+	if err == nil {
+		s.sender.Wake()
+		s.webhooks.Wake()
+	}
+	return err
+}
+
+// tweetsImport displays the import form and imports an uploaded CSV file,
+// showing the outcome of each row.
+func (s *Server) tweetsImport(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		rows        []*importRow
+		dryRun      bool
+		imported    bool
+	)
+	if r.Method == http.MethodPost {
+		err := func() error {
+			r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+			if err := r.ParseMultipartForm(maxImportSize); err != nil {
+				return newPublicError("invalid upload", err)
+			}
+			defer r.MultipartForm.RemoveAll()
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				return newPublicError("no file provided", err)
+			}
+			defer f.Close()
+			rows, err = readImport(f)
+			if err != nil {
+				return err
+			}
+			dryRun = len(r.Form.Get("dry_run")) != 0
+			return s.runImport(currentUser, rows, dryRun)
+		}()
+		if err != nil {
+			s.addError(w, r, err)
+		} else {
+			imported = !dryRun
+		}
+	}
+	s.render(w, r, "tweetsImport.html", pongo2.Context{
+		"title":    "Import Tweets",
+		"rows":     rows,
+		"dry_run":  dryRun,
+		"imported": imported,
+	})
+}
+
+// tweetsImportJson imports the CSV file in the body of the request and
+// returns the outcome of each row. Nothing is created if dry_run is set in
+// the query string.
+func (s *Server) tweetsImportJson(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		dryRun      = len(r.URL.Query().Get("dry_run")) != 0
+		c           = s.catalog(r)
+	)
+	rows, err := readImport(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		s.writeJSONError(w, r, err)
+		return
+	}
+	err = s.runImport(currentUser, rows, dryRun)
+	if err != nil && err != errImportFailed {
+		s.writeJSONError(w, r, err)
+		return
+	}
+	for _, row := range rows {
+		if len(row.Error) != 0 {
+			row.Error = c.T(row.Error)
+		}
+	}
+	status := http.StatusOK
+	if err == errImportFailed {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"dry_run":  dryRun,
+		"imported": err == nil && !dryRun,
+		"rows":     rows,
+	})
+}
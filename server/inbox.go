@@ -0,0 +1,220 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+)
+
+// inboxItem is a mention along with its account and assignee for display.
+type inboxItem struct {
+	Mention  *db.Mention
+	Account  *db.Account
+	Assignee *db.User
+}
+
+// inbox lists mentions of the accounts the current user has access to. The
+// list may be filtered by account, assignee and whether it is done.
+func (s *Server) inbox(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		q           = r.URL.Query()
+		filter      = &db.MentionFilter{
+			AccountID: atoi(q.Get("account")),
+			IsDone:    q.Get("done") != "",
+			Limit:     100,
+		}
+		accounts []*db.Account
+		items    []*inboxItem
+	)
+	if q.Get("mine") != "" {
+		filter.AssigneeID = currentUser.ID
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.UserAccounts(t, currentUser)
+		if err != nil {
+			return err
+		}
+		accounts = a
+		for _, a := range accounts {
+			filter.AccountIDs = append(filter.AccountIDs, a.ID)
+		}
+		mentions, err := db.FilterMentions(t, filter)
+		if err != nil {
+			return err
+		}
+		users, err := db.AllUsers(t, "Username")
+		if err != nil {
+			return err
+		}
+		for _, m := range mentions {
+			item := &inboxItem{Mention: m}
+			for _, a := range accounts {
+				if a.ID == m.AccountID {
+					item.Account = a
+				}
+			}
+			for _, u := range users {
+				if u.ID == m.AssigneeID {
+					item.Assignee = u
+				}
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "inbox.html", pongo2.Context{
+		"title":    "Inbox",
+		"accounts": accounts,
+		"items":    items,
+		"filter":   filter,
+		"mine":     q.Get("mine") != "",
+	})
+}
+
+// newReplyForm creates a compose form for a reply to the mention from the
+// posted text. The reply is written for the account that was mentioned.
+func newReplyForm(r *http.Request, m *db.Mention, a *db.Account) *composeForm {
+	f := &composeForm{
+		AccountIDs: []int{a.ID},
+		Queue:      len(r.Form.Get("queue")) != 0,
+		InReplyTo:  m.RemoteID,
+		Targets:    []*composeTarget{{Account: a, Selected: true}},
+	}
+	if text := r.Form.Get("text"); len(strings.TrimSpace(text)) != 0 {
+		f.Parts = []*composePart{newComposePart(text, "")}
+	}
+	return f
+}
+
+// inboxId displays a mention alongside earlier mentions by the same author and
+// the replies written to it. The mention can be assigned, marked as done or
+// replied to. Replies go through approval and the queue like any other tweet.
+func (s *Server) inboxId(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		mention     *db.Mention
+		account     *db.Account
+		related     []*db.Mention
+		replies     []*tweetView
+		users       []*db.User
+		reply       *composeForm
+		tweets      []*db.Tweet
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		m, err := db.FindMention(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid mention", err)
+		}
+		ok, err := db.HasAccess(t, currentUser, m.AccountID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return newPublicError("invalid mention", nil)
+		}
+		mention = m
+		account, err = db.FindAccount(t, "ID", m.AccountID)
+		if err != nil {
+			return err
+		}
+		users, err = db.AccountUsers(t, m.AccountID)
+		if err != nil {
+			return err
+		}
+		related, err = db.AuthorMentions(t, m, 10)
+		if err != nil {
+			return err
+		}
+		sent, err := db.ReplyTweets(t, m.AccountID, m.RemoteID)
+		if err != nil {
+			return err
+		}
+		for _, tw := range sent {
+			v, err := newTweetView(t, tw)
+			if err != nil {
+				return err
+			}
+			replies = append(replies, v)
+		}
+		if r.Method == http.MethodPost {
+			switch r.Form.Get("action") {
+			case "assign":
+				assigneeID := atoi(r.Form.Get("user_id"))
+				if assigneeID != 0 && !containsUser(users, assigneeID) {
+					return newPublicError("user does not have access to the account", nil)
+				}
+				m.AssigneeID = assigneeID
+			case "done":
+				m.IsDone = true
+			case "reopen":
+				m.IsDone = false
+			case "reply":
+				reply = newReplyForm(r, m, account)
+				tweets, err = s.createTweets(t, currentUser, reply, s.timePrefs(currentUser).Location)
+				return err
+			default:
+				return newPublicError("invalid action", nil)
+			}
+			return m.Save(t)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		if mention == nil {
+			http.Redirect(w, r, "/inbox", http.StatusFound)
+			return
+		}
+	} else if r.Method == http.MethodPost {
+		switch {
+		case tweets == nil:
+			s.addAlert(w, r, alertInfo, "mention updated")
+		case tweets[0].Status == db.TweetScheduled:
+			s.webhooks.Wake()
+			s.sender.Wake()
+			s.addAlert(w, r, alertInfo, "reply scheduled")
+		default:
+			s.webhooks.Wake()
+			s.addAlert(w, r, alertInfo, "reply submitted for approval")
+		}
+		http.Redirect(w, r, fmt.Sprintf("/inbox/%d", mention.ID), http.StatusFound)
+		return
+	}
+	if reply == nil {
+		text := ""
+		if account.Platform == db.PlatformMastodon {
+			text = mention.Author + " "
+		}
+		reply = &composeForm{Parts: []*composePart{newComposePart(text, "")}}
+	} else if len(reply.Parts) == 0 {
+		reply.Parts = []*composePart{newComposePart("", "")}
+	}
+	s.render(w, r, "inboxMention.html", pongo2.Context{
+		"title":   "Mention",
+		"mention": mention,
+		"account": account,
+		"related": related,
+		"replies": replies,
+		"users":   users,
+		"reply":   reply,
+	})
+}
+
+// containsUser determines whether the user is in the list.
+func containsUser(users []*db.User, id int) bool {
+	for _, u := range users {
+		if u.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -4,11 +4,50 @@ import (
 	"net/http"
 
 	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/nathan-osman/informas/db"
 )
 
-// index displays the home page.
+// recentTweets is the number of tweets shown on the dashboard.
+const recentTweets = 20
+
+// index displays the home page, which lists the accounts the current user has
+// access to and the progress of their most recent tweets.
 func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		accounts    []*db.Account
+		tweets      []*tweetView
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.UserAccounts(t, currentUser)
+		if err != nil {
+			return err
+		}
+		accounts = a
+		ids := []int{}
+		for _, a := range accounts {
+			ids = append(ids, a.ID)
+		}
+		recent, err := db.RecentTweets(t, ids, recentTweets)
+		if err != nil {
+			return err
+		}
+		for _, tw := range recent {
+			v, err := newTweetView(t, tw)
+			if err != nil {
+				return err
+			}
+			tweets = append(tweets, v)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	}
 	s.render(w, r, "index.html", pongo2.Context{
-		"title": "Dashboard",
+		"title":    "Dashboard",
+		"accounts": accounts,
+		"tweets":   tweets,
 	})
 }
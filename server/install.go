@@ -40,8 +40,10 @@ func (s *Server) install(w http.ResponseWriter, r *http.Request) {
 
 			// Create the initial configuration
 			initialConfig := map[string]string{
-				configInstalled: "1",
-				configSiteTitle: "Informas",
+				configInstalled:  "1",
+				configSiteTitle:  "Informas",
+				configTimeZone:   "UTC",
+				configDateFormat: defaultDateFormat,
 			}
 			for k, v := range initialConfig {
 				if err := s.config.SetString(t, k, v); err != nil {
@@ -52,7 +54,7 @@ func (s *Server) install(w http.ResponseWriter, r *http.Request) {
 			return nil
 		})
 		if err != nil {
-			s.addAlert(w, r, alertDanger, err.Error())
+			s.addError(w, r, err)
 		} else {
 			s.addAlert(w, r, alertInfo, "installation complete")
 			http.Redirect(w, r, "/users/login", http.StatusFound)
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDKey is used to store the request ID in the request context.
+type requestIDKey struct{}
+
+// requestID retrieves the ID assigned to the request by logRequests.
+func requestID(r *http.Request) string {
+	v, _ := r.Context().Value(requestIDKey{}).(string)
+	return v
+}
+
+// newRequestID generates a random identifier for a request.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestLog returns a log entry tagged with the ID of the request.
+func (s *Server) requestLog(r *http.Request) *logrus.Entry {
+	return s.log.WithField("request_id", requestID(r))
+}
+
+// logRequests assigns an ID to each request and writes an entry to the access
+// log once the request has been processed. The ID is also returned to the
+// client in the X-Request-ID header so that it may be quoted in bug reports.
+func (s *Server) logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start = time.Now()
+			id    = newRequestID()
+			sw    = &statusWriter{ResponseWriter: w}
+		)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+		w.Header().Set("X-Request-ID", id)
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		s.requestLog(r).WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"remote":   r.RemoteAddr,
+			"status":   sw.status,
+			"size":     sw.size,
+			"duration": time.Since(start).Seconds(),
+		}).Info("request")
+	})
+}
+
+// recoverPanics ensures that a panic in a view is logged and that the user is
+// shown a friendly error page instead of an empty response. The page is only
+// rendered if the view had not started writing its response, since anything
+// written afterwards would be appended to it. http.ErrAbortHandler is passed
+// on so that the server aborts the response as the view intended.
+func (s *Server) recoverPanics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			s.requestLog(r).WithFields(logrus.Fields{
+				"panic": v,
+				"stack": string(debug.Stack()),
+			}).Error("panic while processing request")
+			if sw.status == 0 {
+				s.renderError(w, r, http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
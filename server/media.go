@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/media"
+)
+
+// newMediaKey generates a random key for storing an uploaded file.
+func newMediaKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// mediaUpload accepts a file for attaching to a tweet. The file is validated
+// against Twitter's limits before being stored. A JSON description of the new
+// media is returned.
+func (s *Server) mediaUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxSize+1024*1024)
+	if err := r.ParseMultipartForm(32 * 1024 * 1024); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid upload"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no file provided"})
+		return
+	}
+	defer f.Close()
+	altText := r.FormValue("alt_text")
+	if utf8.RuneCountInString(altText) > media.MaxAltTextLength {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "alt text is too long"})
+		return
+	}
+	i, err := media.Inspect(f)
+	if err == nil {
+		err = i.Validate()
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		s.requestLog(r).WithError(err).Error("unable to rewind upload")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "unable to store file"})
+		return
+	}
+	m := &db.Media{
+		UserID:      context.Get(r, contextCurrentUser).(*db.User).ID,
+		Key:         newMediaKey(),
+		ContentType: i.ContentType,
+		Size:        i.Size,
+		Width:       i.Width,
+		Height:      i.Height,
+		AltText:     altText,
+		Created:     time.Now().UTC(),
+	}
+	if err := s.media.Put(m.Key, f); err != nil {
+		s.requestLog(r).WithError(err).Error("unable to store file")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "unable to store file"})
+		return
+	}
+	if err := m.Save(&db.Token{}); err != nil {
+		s.media.Delete(m.Key)
+		s.requestLog(r).WithError(err).Error("unable to save media")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "unable to store file"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":           m.ID,
+		"content_type": m.ContentType,
+		"width":        m.Width,
+		"height":       m.Height,
+		"alt_text":     m.AltText,
+	})
+}
+
+// mediaId serves the contents of an uploaded file. Only the uploader and
+// administrators may view it.
+func (s *Server) mediaId(w http.ResponseWriter, r *http.Request) {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	m, err := db.FindMedia(&db.Token{}, "ID", atoi(mux.Vars(r)["id"]))
+	if err != nil || !currentUser.IsAdmin && m.UserID != currentUser.ID {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	f, err := s.media.Get(m.Key)
+	if err != nil {
+		s.requestLog(r).WithError(err).Error("unable to open media")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", m.ContentType)
+	io.Copy(w, f)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "informas_http_requests_total",
+			Help: "Number of HTTP requests processed, by route, method and status code.",
+		},
+		[]string{"route", "method", "code"},
+	)
+	httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "informas_http_request_duration_seconds",
+			Help:    "Time taken to process HTTP requests, by route and method.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method"},
+	)
+	loginFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "informas_login_failures_total",
+			Help: "Number of failed login attempts.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpRequestsTotal,
+		httpRequestDuration,
+		loginFailuresTotal,
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "informas_db_open_connections",
+				Help: "Number of established connections to the database.",
+			},
+			func() float64 { return float64(db.Stats().OpenConnections) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "informas_db_in_use_connections",
+				Help: "Number of database connections currently in use.",
+			},
+			func() float64 { return float64(db.Stats().InUse) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "informas_db_idle_connections",
+				Help: "Number of idle database connections.",
+			},
+			func() float64 { return float64(db.Stats().Idle) },
+		),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Name: "informas_db_wait_count_total",
+				Help: "Number of times a query waited for a free database connection.",
+			},
+			func() float64 { return float64(db.Stats().WaitCount) },
+		),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Name: "informas_db_wait_duration_seconds_total",
+				Help: "Time spent waiting for a free database connection.",
+			},
+			func() float64 { return db.Stats().WaitDuration.Seconds() },
+		),
+	)
+}
+
+// instrument records the number of requests and the time taken to process
+// them. Requests are labelled with the path template of the matching route to
+// keep the number of series small.
+func (s *Server) instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start = time.Now()
+			sw    = &statusWriter{ResponseWriter: w}
+			route = "unknown"
+		)
+		if m := mux.CurrentRoute(r); m != nil {
+			if t, err := m.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		httpRequestsTotal.WithLabelValues(
+			route,
+			r.Method,
+			strconv.Itoa(sw.status),
+		).Inc()
+		httpRequestDuration.WithLabelValues(
+			route,
+			r.Method,
+		).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/notify"
+)
+
+// smtpOptions returns the current email settings for the mailer.
+func (s *Server) smtpOptions() (*notify.SMTPOptions, error) {
+	password, err := s.config.GetSecret(configSMTPPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &notify.SMTPOptions{
+		Host:      s.config.GetString(configSMTPHost),
+		Port:      s.config.GetInt(configSMTPPort),
+		Username:  s.config.GetString(configSMTPUsername),
+		Password:  password,
+		From:      s.config.GetString(configSMTPFrom),
+		SiteTitle: s.config.GetString(configSiteTitle),
+		SiteURL:   s.config.GetString(configSiteURL),
+	}, nil
+}
+
+// notifications displays the recent notifications for the current user and
+// allows them to be marked as read.
+func (s *Server) notifications(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser   = context.Get(r, contextCurrentUser).(*db.User)
+		unreadOnly    = r.URL.Query().Get("all") == ""
+		notifications []*db.Notification
+	)
+	if r.Method == http.MethodPost {
+		if err := db.MarkNotificationsRead(&db.Token{}, currentUser.ID); err != nil {
+			s.addError(w, r, err)
+		} else {
+			s.addAlert(w, r, alertInfo, "notifications marked as read")
+		}
+		http.Redirect(w, r, "/notifications", http.StatusFound)
+		return
+	}
+	notifications, err := db.UserNotifications(&db.Token{}, currentUser.ID, unreadOnly, 100)
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "notifications.html", pongo2.Context{
+		"title":         "Notifications",
+		"notifications": notifications,
+		"unread_only":   unreadOnly,
+	})
+}
+
+// notificationsId marks a notification as read and opens the page it refers
+// to.
+func (s *Server) notificationsId(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = "/notifications"
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		n, err := db.FindNotification(t, currentUser.ID, atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid notification", err)
+		}
+		n.IsRead = true
+		if err := n.Save(t); err != nil {
+			return err
+		}
+		// Only follow links within the site
+		if strings.HasPrefix(n.URL, "/") && !strings.HasPrefix(n.URL, "//") {
+			redirect = n.URL
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/schedule"
+)
+
+// addPool adds an evergreen pool from the form to the account.
+func addPool(t *db.Token, r *http.Request, a *db.Account, u *db.User, zone string) error {
+	p := &db.Pool{
+		AccountID:   a.ID,
+		UserID:      u.ID,
+		Name:        strings.TrimSpace(r.Form.Get("name")),
+		Schedule:    strings.TrimSpace(r.Form.Get("schedule")),
+		TimeZone:    zone,
+		MinInterval: atoi(r.Form.Get("min_interval")),
+	}
+	if len(p.Name) == 0 {
+		return newPublicError("name is required", nil)
+	}
+	if p.MinInterval < 0 {
+		return newPublicError("invalid minimum interval", nil)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return newPublicError("unknown time zone", err)
+	}
+	s, err := schedule.Parse(p.Schedule, loc)
+	if err != nil {
+		return newPublicError("invalid schedule", err)
+	}
+	p.NextRun = s.Next(time.Now())
+	if err := p.Save(t); err != nil {
+		return newPublicError("unable to add pool", err)
+	}
+	return nil
+}
+
+// accountsIdPools displays the evergreen pools for an account and allows new
+// ones to be added.
+func (s *Server) accountsIdPools(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		account     *db.Account
+		pools       []*db.Pool
+		zone        = r.Form.Get("time_zone")
+	)
+	if len(zone) == 0 {
+		zone = s.timePrefs(currentUser).Location.String()
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		if r.Method == http.MethodPost {
+			if err := addPool(t, r, a, currentUser, zone); err != nil {
+				return err
+			}
+		}
+		pools, err = db.AccountPools(t, a.ID)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "pool added")
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsPools.html", pongo2.Context{
+		"title":        "Evergreen Pools",
+		"account":      account,
+		"pools":        pools,
+		"name":         r.Form.Get("name"),
+		"schedule":     r.Form.Get("schedule"),
+		"min_interval": r.Form.Get("min_interval"),
+		"time_zone":    zone,
+	})
+}
+
+// accountsIdPoolsId displays the posts in an evergreen pool and allows new
+// ones to be added.
+func (s *Server) accountsIdPoolsId(w http.ResponseWriter, r *http.Request) {
+	var (
+		account   *db.Account
+		pool      *db.Pool
+		posts     []*db.PoolPost
+		text      = r.Form.Get("text")
+		textError string
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		pool, err = db.FindPool(t, a.ID, atoi(mux.Vars(r)["pool"]))
+		if err != nil {
+			return newPublicError("invalid pool", err)
+		}
+		if r.Method == http.MethodPost {
+			o, err := s.publisherOptions()
+			if err != nil {
+				return newPublicError("unable to decrypt credentials", err)
+			}
+			pub, err := publisher.New(a, o)
+			if err != nil {
+				return newPublicError("invalid account", err)
+			}
+			if err := validatePart(pub, &publisher.Post{Text: text}, nil); err != nil {
+				textError = err.Error()
+				return newPublicError("the post cannot be published", nil)
+			}
+			if err := (&db.PoolPost{
+				PoolID: pool.ID,
+				Text:   text,
+			}).Save(t); err != nil {
+				return err
+			}
+		}
+		posts, err = db.PoolPosts(t, pool.ID)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		if pool == nil {
+			http.Redirect(w, r, "/accounts", http.StatusFound)
+			return
+		}
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "post added")
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsPoolsId.html", pongo2.Context{
+		"title":      "Evergreen Pool",
+		"account":    account,
+		"pool":       pool,
+		"posts":      posts,
+		"text":       text,
+		"text_error": textError,
+	})
+}
+
+// accountsIdPoolsIdDelete removes an evergreen pool and its posts.
+func (s *Server) accountsIdPoolsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		accountID = atoi(mux.Vars(r)["id"])
+		redirect  = fmt.Sprintf("/accounts/%d/pools", accountID)
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	if err := db.DeletePool(&db.Token{}, accountID, atoi(mux.Vars(r)["pool"])); err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "pool deleted")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// accountsIdPoolsIdPostsIdDelete removes a post from an evergreen pool.
+func (s *Server) accountsIdPoolsIdPostsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars     = mux.Vars(r)
+		redirect = fmt.Sprintf("/accounts/%s/pools/%s", vars["id"], vars["pool"])
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		pool, err := db.FindPool(t, atoi(vars["id"]), atoi(vars["pool"]))
+		if err != nil {
+			return newPublicError("invalid pool", err)
+		}
+		return db.DeletePoolPost(t, pool.ID, atoi(vars["post"]))
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "post deleted")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
@@ -12,21 +12,45 @@ import (
 // render loads the specified template, injects the provided context, and
 // renders it directly to the response.
 func (s *Server) render(w http.ResponseWriter, r *http.Request, templateName string, ctx pongo2.Context) {
+	s.renderStatus(w, r, http.StatusOK, templateName, ctx)
+}
+
+// renderStatus is identical to render but allows the status code to be set.
+func (s *Server) renderStatus(w http.ResponseWriter, r *http.Request, status int, templateName string, ctx pongo2.Context) {
 	t, err := pongo2.FromFile(path.Join(s.templateDir, templateName))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.requestLog(r).WithError(err).Error("unable to load template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	currentUser, _ := context.Get(r, contextCurrentUser).(*db.User)
+	catalog := s.catalog(r)
 	ctx["request"] = r
+	ctx["request_id"] = requestID(r)
 	ctx["alerts"] = s.getAlerts(w, r)
-	ctx["current_user"] = context.Get(r, contextCurrentUser).(*db.User)
+	ctx["current_user"] = currentUser
+	if currentUser != nil {
+		ctx["unread_notifications"], _ = db.UnreadNotificationCount(&db.Token{}, currentUser.ID)
+	}
+	ctx["tz"] = s.timePrefs(currentUser)
+	ctx["T"] = catalog.T
+	ctx["language"] = catalog.Language()
 	ctx["site_title"] = s.config.GetString(configSiteTitle)
 	b, err := t.ExecuteBytes(ctx)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.requestLog(r).WithError(err).Error("unable to render template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	w.Write(b)
 }
+
+// renderError displays a friendly error page with the specified status code.
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, status int) {
+	s.renderStatus(w, r, status, "error.html", pongo2.Context{
+		"title":  http.StatusText(status),
+		"status": status,
+	})
+}
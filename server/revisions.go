@@ -0,0 +1,287 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/diff"
+)
+
+// changeView is a run of text in a diff for display.
+type changeView struct {
+	Text     string
+	Deleted  bool
+	Inserted bool
+}
+
+// partDiff is the changes to one part of a thread between two revisions.
+type partDiff struct {
+	Number  int
+	Changed bool
+	Changes []*changeView
+}
+
+// diffTexts compares the text of each part in two revisions. Parts missing
+// from either revision are treated as empty.
+func diffTexts(a, b []string) []*partDiff {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	diffs := []*partDiff{}
+	for i := 0; i < n; i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		var (
+			changes = diff.Words(x, y)
+			d       = &partDiff{
+				Number:  i + 1,
+				Changed: diff.Changed(changes),
+			}
+		)
+		for _, c := range changes {
+			d.Changes = append(d.Changes, &changeView{
+				Text:     c.Text,
+				Deleted:  c.Op == diff.Delete,
+				Inserted: c.Op == diff.Insert,
+			})
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// partTexts returns the text of each part in order.
+func partTexts(parts []*db.TweetPart) []string {
+	texts := []string{}
+	for _, p := range parts {
+		texts = append(texts, p.Text)
+	}
+	return texts
+}
+
+// addRevision records the current text of the tweet's parts as edited by
+// the user.
+func addRevision(t *db.Token, tweetID, userID int, parts []*db.TweetPart) error {
+	rev := &db.Revision{
+		TweetID: tweetID,
+		UserID:  userID,
+		Texts:   partTexts(parts),
+	}
+	return rev.Save(t)
+}
+
+// revisionView is a revision with its number and the name of its editor for
+// display.
+type revisionView struct {
+	*db.Revision
+	Number int
+	Editor string
+}
+
+// tweetRevisions retrieves the revisions of a tweet for display.
+func tweetRevisions(t *db.Token, tweetID int) ([]*revisionView, error) {
+	revisions, err := db.TweetRevisions(t, tweetID)
+	if err != nil {
+		return nil, err
+	}
+	users, err := db.AllUsers(t, "Username")
+	if err != nil {
+		return nil, err
+	}
+	views := []*revisionView{}
+	for i, rev := range revisions {
+		views = append(views, &revisionView{
+			Revision: rev,
+			Number:   i + 1,
+			Editor:   usernames(users, []int{rev.UserID}),
+		})
+	}
+	return views, nil
+}
+
+// findRevision returns the revision with the specified ID or nil.
+func findRevision(revisions []*revisionView, id int) *revisionView {
+	for _, rev := range revisions {
+		if rev.ID == id {
+			return rev
+		}
+	}
+	return nil
+}
+
+// reviewChanges compares the revision that the user last reviewed with the
+// latest revision of the tweet. Nil is returned if the user has not reviewed
+// the tweet's group or if the text has not changed since.
+func reviewChanges(t *db.Token, g *db.TweetGroup, tweetID int, u *db.User) (*db.Review, []*partDiff, error) {
+	review, err := db.FindReview(t, g.ID, u.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	revisions, err := db.TweetRevisions(t, tweetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reviewed *db.Revision
+	for _, rev := range revisions {
+		if !rev.Created.After(review.Reviewed) {
+			reviewed = rev
+		}
+	}
+	if reviewed == nil || reviewed == revisions[len(revisions)-1] {
+		return nil, nil, nil
+	}
+	diffs := diffTexts(reviewed.Texts, revisions[len(revisions)-1].Texts)
+	for _, d := range diffs {
+		if d.Changed {
+			return review, diffs, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// tweetsIdRevisions lists the revisions of a tweet and shows the changes
+// between two of them, which default to the latest revision and the one
+// before it.
+func (s *Server) tweetsIdRevisions(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		tweet       *tweetView
+		revisions   []*revisionView
+		from        *revisionView
+		to          *revisionView
+		diffs       []*partDiff
+		canRestore  bool
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		tweet, err = newTweetView(t, tw)
+		if err != nil {
+			return err
+		}
+		revisions, err = tweetRevisions(t, tw.ID)
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 {
+			return nil
+		}
+		var (
+			q    = r.URL.Query()
+			last = len(revisions) - 1
+		)
+		to = revisions[last]
+		if last > 0 {
+			from = revisions[last-1]
+		}
+		if v := q.Get("to"); len(v) != 0 {
+			if to = findRevision(revisions, atoi(v)); to == nil {
+				return newPublicError("invalid revision", nil)
+			}
+		}
+		if v := q.Get("from"); len(v) != 0 {
+			if from = findRevision(revisions, atoi(v)); from == nil {
+				return newPublicError("invalid revision", nil)
+			}
+		}
+		var texts []string
+		if from != nil {
+			texts = from.Texts
+		}
+		diffs = diffTexts(texts, to.Texts)
+		g, p, err := groupPolicy(t, tw)
+		if err != nil {
+			return err
+		}
+		canRestore = canEdit(p, g, currentUser)
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		http.Redirect(w, r, fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"]), http.StatusFound)
+		return
+	}
+	s.render(w, r, "tweetsRevisions.html", pongo2.Context{
+		"title":       "Revisions",
+		"tweet":       tweet,
+		"revisions":   revisions,
+		"from":        from,
+		"to":          to,
+		"diffs":       diffs,
+		"can_restore": canRestore,
+	})
+}
+
+// tweetsIdRevisionsIdRestore replaces the text of a tweet with that of an
+// earlier revision. The restored text is recorded as a new revision and is
+// reviewed again like any other edit.
+func (s *Server) tweetsIdRevisionsIdRestore(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect+"/revisions", http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		g, p, err := groupPolicy(t, tw)
+		if err != nil {
+			return err
+		}
+		if !canEdit(p, g, currentUser) {
+			return errNotEditable
+		}
+		tweet, err := newTweetView(t, tw)
+		if err != nil {
+			return err
+		}
+		revisions, err := db.TweetRevisions(t, tw.ID)
+		if err != nil {
+			return err
+		}
+		var rev *db.Revision
+		for _, v := range revisions {
+			if v.ID == atoi(mux.Vars(r)["revision"]) {
+				rev = v
+			}
+		}
+		if rev == nil {
+			return newPublicError("invalid revision", nil)
+		}
+		if len(rev.Texts) != len(tweet.Parts) {
+			return newPublicError("the revision has a different number of tweets", nil)
+		}
+		parts := []*composePart{}
+		for _, text := range rev.Texts {
+			parts = append(parts, newComposePart(text, ""))
+		}
+		return s.editTweet(t, currentUser, g, p, tweet, parts)
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "revision restored")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
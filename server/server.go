@@ -1,70 +1,253 @@
 package server
 
 import (
+	"crypto/tls"
+	"net"
 	"net/http"
 	"path"
+	"sync"
 
 	"github.com/gorilla/mux"
-	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/hectane/go-asyncserver"
+	"github.com/nathan-osman/informas/analytics"
+	"github.com/nathan-osman/informas/approval"
 	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/evergreen"
+	"github.com/nathan-osman/informas/i18n"
+	"github.com/nathan-osman/informas/inbox"
+	"github.com/nathan-osman/informas/media"
+	"github.com/nathan-osman/informas/notify"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/webhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
 // Server provides the web interface for the application.
 type Server struct {
-	server      *server.AsyncServer
-	sessions    *sessions.CookieStore
-	config      *db.Config
-	templateDir string
+	server         *server.AsyncServer
+	tlsServer      *http.Server
+	certs          *certReloader
+	sessions       *sessions.CookieStore
+	sessionKeys    []*sessionKey
+	sessionOptions *sessions.Options
+	sessionsMutex  sync.RWMutex
+	config         *db.Config
+	media          media.Store
+	sender         *publisher.Sender
+	escalator      *approval.Escalator
+	evergreen      *evergreen.Runner
+	locales        *i18n.Bundle
+	webhooks       *webhook.Dispatcher
+	mailer         *notify.Mailer
+	poller         *inbox.Poller
+	refresher      *analytics.Refresher
+	templateDir    string
+	log            *logrus.Entry
 }
 
-// New creates a new server instance.
-func New(addr, dataDir string) (*Server, error) {
+// New creates a new server instance. If tlsOptions is not nil, the application
+// is also served over HTTPS.
+func New(addr, dataDir string, tlsOptions *TLSOptions) (*Server, error) {
 	c, err := db.NewConfig(&db.Token{})
 	if err != nil {
 		return nil, err
 	}
-	secretKey := c.GetBytes(configSecretKey)
-	if len(secretKey) == 0 {
-		secretKey = securecookie.GenerateRandomKey(32)
-		if err := c.SetBytes(&db.Token{}, configSecretKey, secretKey); err != nil {
+	for _, k := range []string{configTwitterConsumerSecret, configSMTPPassword, configSessionKeys} {
+		if err := c.ProtectSecret(&db.Token{}, k); err != nil {
 			return nil, err
 		}
 	}
+	store, err := media.NewDiskStore(path.Join(dataDir, "media"))
+	if err != nil {
+		return nil, err
+	}
+	locales, err := i18n.Load(path.Join(dataDir, "locales"))
+	if err != nil {
+		return nil, err
+	}
 	var (
 		m = mux.NewRouter()
 		s = &Server{
-			server:      server.New(addr),
-			sessions:    sessions.NewCookieStore(secretKey),
+			server: server.New(addr),
+			sessionOptions: &sessions.Options{
+				Path:     "/",
+				MaxAge:   86400 * 30,
+				HttpOnly: true,
+				Secure:   tlsOptions != nil,
+				SameSite: http.SameSiteLaxMode,
+			},
 			config:      c,
+			media:       store,
+			locales:     locales,
 			templateDir: path.Join(dataDir, "templates"),
+			log:         logrus.WithField("context", "server"),
 		}
 	)
-	s.server.Handler = m
+	if err := s.loadSessionKeys(); err != nil {
+		return nil, err
+	}
+	h := s.logRequests(s.recoverPanics(m))
+	if tlsOptions != nil {
+		if err := s.initTLS(h, tlsOptions, dataDir); err != nil {
+			return nil, err
+		}
+	} else {
+		s.server.Handler = h
+	}
+	m.Use(s.instrument)
 	m.HandleFunc("/", s.view(accessRegistered, s.index))
 	m.HandleFunc("/accounts", s.view(accessAdmin, s.accountsIndex))
 	m.HandleFunc("/accounts/new", s.view(accessAdmin, s.accountsNew))
+	m.HandleFunc("/accounts/mastodon/callback", s.view(accessAdmin, s.accountsMastodonCallback))
+	m.HandleFunc("/accounts/twitter/callback", s.view(accessAdmin, s.accountsTwitterCallback))
+	m.HandleFunc("/accounts/{id:[0-9]+}/analytics", s.view(accessRegistered, s.accountsIdAnalytics))
+	m.HandleFunc("/accounts/{id:[0-9]+}/analytics.csv", s.view(accessRegistered, s.accountsIdAnalyticsCsv))
+	m.HandleFunc("/accounts/{id:[0-9]+}/analytics/{post:[0-9]+}", s.view(accessRegistered, s.accountsIdAnalyticsId))
+	m.HandleFunc("/accounts/{id:[0-9]+}/approval", s.view(accessAdmin, s.accountsIdApproval))
+	m.HandleFunc("/accounts/{id:[0-9]+}/approval/{stage:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdApprovalIdDelete))
+	m.HandleFunc("/accounts/{id:[0-9]+}/calendar", s.view(accessRegistered, s.accountsIdCalendar))
+	m.HandleFunc("/accounts/{id:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdDelete))
+	m.HandleFunc("/accounts/{id:[0-9]+}/grants", s.view(accessAdmin, s.accountsIdGrants))
+	m.HandleFunc("/accounts/{id:[0-9]+}/grants/{user:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdGrantsIdDelete))
+	m.HandleFunc("/accounts/{id:[0-9]+}/pools", s.view(accessAdmin, s.accountsIdPools))
+	m.HandleFunc("/accounts/{id:[0-9]+}/pools/{pool:[0-9]+}", s.view(accessAdmin, s.accountsIdPoolsId))
+	m.HandleFunc("/accounts/{id:[0-9]+}/pools/{pool:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdPoolsIdDelete))
+	m.HandleFunc("/accounts/{id:[0-9]+}/pools/{pool:[0-9]+}/posts/{post:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdPoolsIdPostsIdDelete))
+	m.HandleFunc("/accounts/{id:[0-9]+}/slots", s.view(accessAdmin, s.accountsIdSlots))
+	m.HandleFunc("/accounts/{id:[0-9]+}/slots/{slot:[0-9]+}/delete", s.view(accessAdmin, s.accountsIdSlotsIdDelete))
+	m.HandleFunc("/conversations", s.view(accessRegistered, s.conversations))
+	m.HandleFunc("/conversations/{id:[0-9]+}", s.view(accessRegistered, s.conversationsId))
+	m.HandleFunc("/drafts/save", s.view(accessRegistered, s.draftsSave))
+	m.HandleFunc("/drafts/{id:[0-9]+}/delete", s.view(accessRegistered, s.draftsIdDelete))
+	m.HandleFunc("/healthz", s.healthz)
+	m.HandleFunc("/inbox", s.view(accessRegistered, s.inbox))
+	m.HandleFunc("/inbox/{id:[0-9]+}", s.view(accessRegistered, s.inboxId))
 	m.HandleFunc("/install", s.view(accessPublic, s.install))
+	m.HandleFunc("/media/upload", s.view(accessRegistered, s.mediaUpload))
+	m.HandleFunc("/media/{id:[0-9]+}", s.view(accessRegistered, s.mediaId))
+	m.Handle("/metrics", promhttp.Handler())
+	m.HandleFunc("/notifications", s.view(accessRegistered, s.notifications))
+	m.HandleFunc("/notifications/{id:[0-9]+}", s.view(accessRegistered, s.notificationsId))
+	m.HandleFunc("/readyz", s.readyz)
 	m.HandleFunc("/settings", s.view(accessAdmin, s.settings))
+	m.HandleFunc("/settings/rotate-keys", s.view(accessAdmin, s.settingsRotateKeys))
+	m.HandleFunc("/tweets/import", s.view(accessRegistered, s.tweetsImport))
+	m.HandleFunc("/tweets/import.json", s.view(accessRegistered, s.tweetsImportJson))
+	m.HandleFunc("/tweets/new", s.view(accessRegistered, s.tweetsNew))
+	m.HandleFunc("/tweets/validate", s.view(accessRegistered, s.tweetsValidate))
+	m.HandleFunc("/tweets/{id:[0-9]+}", s.view(accessRegistered, s.tweetsId))
+	m.HandleFunc("/tweets/{id:[0-9]+}/approve", s.view(accessRegistered, s.tweetsIdApprove))
+	m.HandleFunc("/tweets/{id:[0-9]+}/comments", s.view(accessRegistered, s.tweetsIdComments))
+	m.HandleFunc("/tweets/{id:[0-9]+}/edit", s.view(accessRegistered, s.tweetsIdEdit))
+	m.HandleFunc("/tweets/{id:[0-9]+}/reject", s.view(accessRegistered, s.tweetsIdReject))
+	m.HandleFunc("/tweets/{id:[0-9]+}/reschedule", s.view(accessRegistered, s.tweetsIdReschedule))
+	m.HandleFunc("/tweets/{id:[0-9]+}/retry", s.view(accessRegistered, s.tweetsIdRetry))
+	m.HandleFunc("/tweets/{id:[0-9]+}/revisions", s.view(accessRegistered, s.tweetsIdRevisions))
+	m.HandleFunc("/tweets/{id:[0-9]+}/revisions/{revision:[0-9]+}/restore", s.view(accessRegistered, s.tweetsIdRevisionsIdRestore))
 	m.HandleFunc("/users", s.view(accessAdmin, s.usersIndex))
 	m.HandleFunc("/users/create", s.view(accessAdmin, s.usersCreate))
 	m.HandleFunc("/users/login", s.view(accessPublic, s.usersLogin))
 	m.HandleFunc("/users/logout", s.view(accessRegistered, s.usersLogout))
 	m.HandleFunc("/users/{id:[0-9]+}/edit", s.view(accessRegistered, s.usersIdEdit))
 	m.HandleFunc("/users/{id:[0-9]+}/delete", s.view(accessAdmin, s.usersIdDelete))
+	m.HandleFunc("/webhooks", s.view(accessAdmin, s.webhooksIndex))
+	m.HandleFunc("/webhooks/new", s.view(accessAdmin, s.webhooksNew))
+	m.HandleFunc("/webhooks/{id:[0-9]+}/edit", s.view(accessAdmin, s.webhooksIdEdit))
+	m.HandleFunc("/webhooks/{id:[0-9]+}/delete", s.view(accessAdmin, s.webhooksIdDelete))
+	m.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", s.view(accessAdmin, s.webhooksIdDeliveries))
+	m.HandleFunc("/webhooks/{id:[0-9]+}/ping", s.view(accessAdmin, s.webhooksIdPing))
 	m.PathPrefix("/static").Handler(
 		http.FileServer(http.Dir(dataDir)),
 	)
+	s.webhooks = webhook.NewDispatcher()
+	s.mailer = notify.NewMailer(s.smtpOptions)
+	s.sender = publisher.NewSender(s.publisherOptions, s.media)
+	s.escalator = approval.NewEscalator()
+	s.evergreen = evergreen.NewRunner(s.sender.Wake)
+	s.poller = inbox.NewPoller(s.publisherOptions)
+	s.refresher = analytics.NewRefresher(s.publisherOptions)
 	return s, nil
 }
 
+// initTLS prepares the HTTPS listener. The plain HTTP listener either serves
+// the application as well or redirects to HTTPS. When using ACME, it also
+// responds to HTTP challenges.
+func (s *Server) initTLS(h http.Handler, o *TLSOptions, dataDir string) error {
+	var (
+		tlsConfig   *tls.Config
+		httpHandler = h
+	)
+	if o.HSTS {
+		h = s.hsts(h)
+	}
+	if o.Redirect {
+		httpHandler = http.HandlerFunc(s.redirectToHTTPS)
+	}
+	if len(o.ACMEDomains) != 0 {
+		m, err := newACMEManager(o, dataDir)
+		if err != nil {
+			return err
+		}
+		tlsConfig = m.TLSConfig()
+		httpHandler = m.HTTPHandler(httpHandler)
+	} else {
+		c, err := newCertReloader(o.CertFile, o.KeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig = &tls.Config{GetCertificate: c.GetCertificate}
+		s.certs = c
+	}
+	s.server.Handler = httpHandler
+	s.tlsServer = &http.Server{
+		Addr:      o.Addr,
+		Handler:   h,
+		TLSConfig: tlsConfig,
+	}
+	return nil
+}
+
 // Start begins listening on the specified address.
 func (s *Server) Start() error {
+	if s.tlsServer != nil {
+		l, err := net.Listen("tcp", s.tlsServer.Addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := s.tlsServer.ServeTLS(l, "", ""); err != http.ErrServerClosed {
+				s.log.WithError(err).Error("HTTPS server stopped")
+			}
+		}()
+	}
 	return s.server.Start()
 }
 
+// Reload loads the TLS certificate from disk again. Nothing is done if the
+// certificate is not being read from a file.
+func (s *Server) Reload() error {
+	if s.certs != nil {
+		return s.certs.Reload()
+	}
+	return nil
+}
+
 // Stop shuts down the server.
 func (s *Server) Stop() {
 	s.server.Stop()
+	if s.tlsServer != nil {
+		s.tlsServer.Close()
+	}
+	if s.certs != nil {
+		s.certs.Close()
+	}
+	s.webhooks.Close()
+	s.mailer.Close()
+	s.sender.Close()
+	s.escalator.Close()
+	s.evergreen.Close()
+	s.poller.Close()
+	s.refresher.Close()
 }
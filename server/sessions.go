@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/nathan-osman/informas/db"
+)
+
+// maxSessionKeys is the number of keys that are accepted for decoding cookies.
+// Keeping the previous key after rotation means that users are not logged out
+// as long as they visit the site before the next rotation.
+const maxSessionKeys = 2
+
+// sessionKey is a pair of keys for signing and encrypting cookies. BlockKey is
+// empty for the key that was used before cookies were encrypted.
+type sessionKey struct {
+	HashKey  []byte
+	BlockKey []byte
+}
+
+// newSessionKey generates a new pair of random keys.
+func newSessionKey() *sessionKey {
+	return &sessionKey{
+		HashKey:  securecookie.GenerateRandomKey(64),
+		BlockKey: securecookie.GenerateRandomKey(32),
+	}
+}
+
+// errInvalidSessionKeys is returned when the stored session keys cannot be
+// read.
+var errInvalidSessionKeys = errors.New("stored session keys are invalid")
+
+// parseSessionKeys reads keys stored by formatSessionKeys.
+func parseSessionKeys(v string) ([]*sessionKey, error) {
+	keys := []*sessionKey{}
+	for _, pair := range strings.Split(v, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, errInvalidSessionKeys
+		}
+		hashKey, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil || len(hashKey) == 0 {
+			return nil, errInvalidSessionKeys
+		}
+		blockKey, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, errInvalidSessionKeys
+		}
+		keys = append(keys, &sessionKey{
+			HashKey:  hashKey,
+			BlockKey: blockKey,
+		})
+	}
+	return keys, nil
+}
+
+// formatSessionKeys encodes the keys, newest first, for storing in the
+// configuration.
+func formatSessionKeys(keys []*sessionKey) string {
+	pairs := []string{}
+	for _, k := range keys {
+		pairs = append(pairs, base64.StdEncoding.EncodeToString(k.HashKey)+":"+
+			base64.StdEncoding.EncodeToString(k.BlockKey))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// setSessionKeys replaces the cookie store with one that uses the keys. The
+// first key is used for new cookies and all of them are tried when decoding.
+// The caller must hold the lock.
+func (s *Server) setSessionKeys(keys []*sessionKey) {
+	pairs := [][]byte{}
+	for _, k := range keys {
+		var blockKey []byte
+		if len(k.BlockKey) != 0 {
+			blockKey = k.BlockKey
+		}
+		pairs = append(pairs, k.HashKey, blockKey)
+	}
+	store := sessions.NewCookieStore(pairs...)
+	store.Options = s.sessionOptions
+	s.sessions = store
+	s.sessionKeys = keys
+}
+
+// sessionStore returns the cookie store for the current keys.
+func (s *Server) sessionStore() *sessions.CookieStore {
+	s.sessionsMutex.RLock()
+	defer s.sessionsMutex.RUnlock()
+	return s.sessions
+}
+
+// loadSessionKeys reads the keys for the cookie store from the
+// configuration. They are only generated if none were stored, since
+// replacing keys that cannot be read would discard them. Cookies signed with
+// the single key used by earlier versions remain valid until the keys are
+// rotated.
+func (s *Server) loadSessionKeys() error {
+	var keys []*sessionKey
+	if s.config.Has(configSessionKeys) {
+		v, err := s.config.GetSecret(configSessionKeys)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt session keys: %s", err)
+		}
+		keys, err = parseSessionKeys(v)
+		if err != nil {
+			return err
+		}
+	} else {
+		keys = []*sessionKey{newSessionKey()}
+		if legacyKey := s.config.GetBytes(configSecretKey); len(legacyKey) != 0 {
+			keys = append(keys, &sessionKey{HashKey: legacyKey})
+		}
+		err := db.Transaction(func(t *db.Token) error {
+			if err := s.config.SetSecret(t, configSessionKeys, formatSessionKeys(keys)); err != nil {
+				return err
+			}
+			return s.config.SetString(t, configSecretKey, "")
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	s.setSessionKeys(keys)
+	return nil
+}
+
+// rotateSessionKeys generates a new key for cookies and keeps the previous
+// one for decoding existing cookies. Older keys are discarded.
+func (s *Server) rotateSessionKeys() error {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	keys := append([]*sessionKey{newSessionKey()}, s.sessionKeys...)
+	if len(keys) > maxSessionKeys {
+		keys = keys[:maxSessionKeys]
+	}
+	if err := s.config.SetSecret(&db.Token{}, configSessionKeys, formatSessionKeys(keys)); err != nil {
+		return err
+	}
+	s.setSessionKeys(keys)
+	return nil
+}
+
+// settingsRotateKeys generates a new session key. Users remain logged in
+// since their cookies are re-encoded with the new key on their next visit.
+func (s *Server) settingsRotateKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if err := s.rotateSessionKeys(); err != nil {
+			s.addError(w, r, err)
+		} else {
+			s.addAlert(w, r, alertInfo, "session keys rotated")
+		}
+	}
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/flosch/pongo2"
 	"github.com/nathan-osman/informas/db"
@@ -10,18 +12,80 @@ import (
 // settings allow site-wide configuration to be edited.
 func (s *Server) settings(w http.ResponseWriter, r *http.Request) {
 	var (
-		siteTitle = s.config.GetString(configSiteTitle)
+		siteTitle          = s.config.GetString(configSiteTitle)
+		siteURL            = s.config.GetString(configSiteURL)
+		twitterConsumerKey = s.config.GetString(configTwitterConsumerKey)
+		timeZone           = s.config.GetString(configTimeZone)
+		dateFormat         = s.config.GetString(configDateFormat)
+		smtpHost           = s.config.GetString(configSMTPHost)
+		smtpPort           = s.config.GetString(configSMTPPort)
+		smtpUsername       = s.config.GetString(configSMTPUsername)
+		smtpFrom           = s.config.GetString(configSMTPFrom)
 	)
+	twitterConsumerSecret, err := s.config.GetSecret(configTwitterConsumerSecret)
+	if err != nil {
+		s.addError(w, r, newPublicError("unable to decrypt credentials", err))
+	}
+	smtpPassword, err := s.config.GetSecret(configSMTPPassword)
+	if err != nil {
+		s.addError(w, r, newPublicError("unable to decrypt credentials", err))
+	}
 	if r.Method == http.MethodPost {
 		siteTitle = r.Form.Get("site_title")
+		siteURL = r.Form.Get("site_url")
+		twitterConsumerKey = r.Form.Get("twitter_consumer_key")
+		twitterConsumerSecret = r.Form.Get("twitter_consumer_secret")
+		timeZone = r.Form.Get("time_zone")
+		dateFormat = r.Form.Get("date_format")
+		smtpHost = r.Form.Get("smtp_host")
+		smtpPort = r.Form.Get("smtp_port")
+		smtpUsername = r.Form.Get("smtp_username")
+		smtpPassword = r.Form.Get("smtp_password")
+		smtpFrom = r.Form.Get("smtp_from")
 		err := db.Transaction(func(t *db.Token) error {
-			if err := s.config.SetString(t, configSiteTitle, siteTitle); err != nil {
-				return err
+			if _, err := time.LoadLocation(timeZone); err != nil || len(timeZone) == 0 {
+				return newPublicError("unknown time zone", err)
+			}
+			if !isDateFormat(dateFormat) {
+				return newPublicError("invalid date format", nil)
+			}
+			if len(smtpHost) != 0 {
+				if p, err := strconv.Atoi(smtpPort); err != nil || p < 1 || p > 65535 {
+					return newPublicError("invalid SMTP port", err)
+				}
+				if len(smtpFrom) == 0 {
+					return newPublicError("a sender address is required for email", nil)
+				}
+			}
+			values := map[string]string{
+				configSiteTitle:          siteTitle,
+				configSiteURL:            siteURL,
+				configTwitterConsumerKey: twitterConsumerKey,
+				configTimeZone:           timeZone,
+				configDateFormat:         dateFormat,
+				configSMTPHost:           smtpHost,
+				configSMTPPort:           smtpPort,
+				configSMTPUsername:       smtpUsername,
+				configSMTPFrom:           smtpFrom,
+			}
+			for k, v := range values {
+				if err := s.config.SetString(t, k, v); err != nil {
+					return err
+				}
+			}
+			secrets := map[string]string{
+				configTwitterConsumerSecret: twitterConsumerSecret,
+				configSMTPPassword:          smtpPassword,
+			}
+			for k, v := range secrets {
+				if err := s.config.SetSecret(t, k, v); err != nil {
+					return err
+				}
 			}
 			return nil
 		})
 		if err != nil {
-			s.addAlert(w, r, alertDanger, err.Error())
+			s.addError(w, r, err)
 		} else {
 			s.addAlert(w, r, alertInfo, "settings saved")
 			http.Redirect(w, r, "/settings", http.StatusFound)
@@ -29,7 +93,19 @@ func (s *Server) settings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	s.render(w, r, "settings.html", pongo2.Context{
-		"title":       "Settings",
-		"site_title_": siteTitle,
+		"title":                   "Settings",
+		"site_title_":             siteTitle,
+		"site_url":                siteURL,
+		"twitter_consumer_key":    twitterConsumerKey,
+		"twitter_consumer_secret": twitterConsumerSecret,
+		"time_zone":               timeZone,
+		"date_format":             dateFormat,
+		"date_formats":            dateFormats,
+		"now":                     time.Now(),
+		"smtp_host":               smtpHost,
+		"smtp_port":               smtpPort,
+		"smtp_username":           smtpUsername,
+		"smtp_password":           smtpPassword,
+		"smtp_from":               smtpFrom,
 	})
 }
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/schedule"
+)
+
+// weekdays lists the names of the days for the slot form.
+var weekdays = []string{
+	"Sunday",
+	"Monday",
+	"Tuesday",
+	"Wednesday",
+	"Thursday",
+	"Friday",
+	"Saturday",
+}
+
+// nextSlot calculates the next posting time for the account after the
+// specified time that is not already taken.
+func nextSlot(slots []*db.Slot, after time.Time, taken []time.Time) time.Time {
+	s := []*schedule.Slot{}
+	for _, slot := range slots {
+		loc, err := time.LoadLocation(slot.TimeZone)
+		if err != nil {
+			continue
+		}
+		s = append(s, &schedule.Slot{
+			Weekday:  time.Weekday(slot.Weekday),
+			Hour:     slot.Hour,
+			Minute:   slot.Minute,
+			Location: loc,
+		})
+	}
+	return schedule.NextFreeSlot(s, after, taken)
+}
+
+// accountsIdSlots displays the weekly posting slots for an account and allows
+// new ones to be added.
+func (s *Server) accountsIdSlots(w http.ResponseWriter, r *http.Request) {
+	var (
+		account *db.Account
+		slots   []*db.Slot
+		taken   []time.Time
+		now     = time.Now()
+		day     = r.Form.Get("weekday")
+		hhmm    = r.Form.Get("time")
+		zone    = r.Form.Get("time_zone")
+	)
+	if len(zone) == 0 {
+		u, _ := context.Get(r, contextCurrentUser).(*db.User)
+		zone = s.timePrefs(u).Location.String()
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		a, err := db.FindAccount(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid account", err)
+		}
+		account = a
+		if r.Method == http.MethodPost {
+			var hour, minute int
+			if _, err := fmt.Sscanf(hhmm, "%d:%d", &hour, &minute); err != nil ||
+				hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+				return newPublicError("invalid time", err)
+			}
+			weekday, err := strconv.Atoi(day)
+			if err != nil || weekday < 0 || weekday > 6 {
+				return newPublicError("invalid day", err)
+			}
+			if _, err := time.LoadLocation(zone); err != nil {
+				return newPublicError("unknown time zone", err)
+			}
+			slot := &db.Slot{
+				AccountID: a.ID,
+				Weekday:   weekday,
+				Hour:      hour,
+				Minute:    minute,
+				TimeZone:  zone,
+			}
+			if err := slot.Save(t); err != nil {
+				return newPublicError("unable to add slot", err)
+			}
+		}
+		slots, err = db.AccountSlots(t, a.ID)
+		if err != nil {
+			return err
+		}
+		taken, err = db.QueuedTimes(t, a.ID, now)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "slot added")
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		return
+	}
+	s.render(w, r, "accountsSlots.html", pongo2.Context{
+		"title":     "Posting Slots",
+		"account":   account,
+		"slots":     slots,
+		"next_slot": nextSlot(slots, now, taken),
+		"weekdays":  weekdays,
+		"weekday":   day,
+		"time":      hhmm,
+		"time_zone": zone,
+	})
+}
+
+// accountsIdSlotsIdDelete removes a posting slot.
+func (s *Server) accountsIdSlotsIdDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		accountID = atoi(mux.Vars(r)["id"])
+		redirect  = fmt.Sprintf("/accounts/%d/slots", accountID)
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	if err := db.DeleteSlot(&db.Token{}, accountID, atoi(mux.Vars(r)["slot"])); err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.addAlert(w, r, alertInfo, "slot deleted")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
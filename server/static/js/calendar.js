@@ -0,0 +1,44 @@
+/*
+ * Drag-to-reschedule for the calendar
+ */
+
+$(function () {
+
+    var $calendar = $('#calendar'),
+        $error = $('#calendar-error');
+
+    // Remember which tweet is being dragged
+    $calendar.on('dragstart', '.calendar-tweet', function (e) {
+        e.originalEvent.dataTransfer.setData('text/plain', $(this).data('id'));
+    });
+
+    // Allow tweets to be dropped on any day
+    $calendar.on('dragover', '.calendar-day', function (e) {
+        e.preventDefault();
+    });
+
+    // Move the tweet to the same time on the new day
+    $calendar.on('drop', '.calendar-day', function (e) {
+        var $day = $(this),
+            id = e.originalEvent.dataTransfer.getData('text/plain'),
+            $tweet = $calendar.find('.calendar-tweet[data-id="' + id + '"]');
+        e.preventDefault();
+        if (!$tweet.length) {
+            return;
+        }
+        $.ajax({
+            url: '/tweets/' + id + '/reschedule',
+            type: 'POST',
+            contentType: 'application/json',
+            data: JSON.stringify({
+                scheduled: $day.data('date') + 'T' + $tweet.data('time')
+            })
+        }).done(function () {
+            $error.prop('hidden', true);
+            $day.find('.calendar-tweets').append($tweet);
+        }).fail(function (xhr) {
+            $error.text(xhr.responseJSON ? xhr.responseJSON.error : xhr.statusText);
+            $error.prop('hidden', false);
+        });
+    });
+});
@@ -0,0 +1,64 @@
+/*
+ * Compose form for tweets and threads
+ */
+
+$(function () {
+
+    var $parts = $('#parts');
+
+    // Number the parts after one is added or removed
+    function renumber() {
+        $parts.find('.part-number').each(function (i) {
+            $(this).text(i + 1);
+        });
+    }
+
+    // Add an empty part to the end of the thread
+    $('#add-part').click(function () {
+        var $part = $parts.find('.part').last().clone(),
+            $count = $part.find('.text-count');
+        $part.find('textarea, input').val('');
+        $part.find('.part-files, .part-error').text('');
+        $count.text('0/' + $count.text().split('/')[1]).removeClass('text-danger');
+        $part.find('.has-danger').removeClass('has-danger');
+        $parts.append($part);
+        renumber();
+    });
+
+    // Remove a part, always leaving at least one
+    $parts.on('click', '.part-remove', function () {
+        if ($parts.find('.part').length > 1) {
+            $(this).closest('.part').remove();
+            renumber();
+        }
+    });
+
+    // Upload files as soon as they are chosen and remember their IDs
+    $parts.on('change', '.part-file', function () {
+        var $part = $(this).closest('.part'),
+            $media = $part.find('.part-media'),
+            $files = $part.find('.part-files'),
+            data = new FormData();
+        if (!this.files.length) {
+            return;
+        }
+        data.append('file', this.files[0]);
+        data.append('alt_text', $part.find('.part-alt').val());
+        $(this).val('');
+        $.ajax({
+            url: '/media/upload',
+            type: 'POST',
+            data: data,
+            processData: false,
+            contentType: false
+        }).done(function (m) {
+            var ids = $media.val() ? $media.val().split(',') : [];
+            ids.push(m.id);
+            $media.val(ids.join(','));
+            $part.find('.part-alt').val('');
+            $files.text($files.data('label') + ' ' + ids.join(', '));
+        }).fail(function (xhr) {
+            $files.text(xhr.responseJSON ? xhr.responseJSON.error : xhr.statusText);
+        });
+    });
+});
@@ -0,0 +1,24 @@
+/*
+ * Live length of tweets under the twitter-text rules
+ */
+
+$(function () {
+
+    // Ask the server for the weighted length of the text
+    function count($text) {
+        var $count = $text.closest('.form-group').find('.text-count');
+        $.post('/tweets/validate', {text: $text.val()}).done(function (r) {
+            $count.text(r.weighted_length + '/' + r.max_length);
+            $count.toggleClass('text-danger', r.weighted_length > r.max_length);
+        });
+    }
+
+    // Count the text shortly after the user stops typing
+    $(document).on('input', 'textarea.count', function () {
+        var $text = $(this);
+        clearTimeout($text.data('timer'));
+        $text.data('timer', setTimeout(function () {
+            count($text);
+        }, 300));
+    });
+});
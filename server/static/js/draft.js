@@ -0,0 +1,26 @@
+/*
+ * Autosave of forms as drafts on the server
+ */
+
+$(function () {
+
+    var $form = $('form.draft'),
+        $status = $form.find('.draft-status'),
+        timer;
+
+    // Store the values of the form and remember the ID of the draft
+    function save() {
+        $.post('/drafts/save', $form.serialize()).done(function (r) {
+            $form.find('input[name=draft]').val(r.id);
+            $status.text($status.data('label') + ' ' + new Date(r.updated).toLocaleTimeString());
+        }).fail(function (xhr) {
+            $status.text(xhr.responseJSON ? xhr.responseJSON.error : xhr.statusText);
+        });
+    }
+
+    // Save shortly after the user stops changing the form
+    $form.on('input change', function () {
+        clearTimeout(timer);
+        timer = setTimeout(save, 2000);
+    });
+});
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// hstsHeader is sent with every response served over HTTPS when HSTS is
+// enabled. One year is the minimum accepted for preloading.
+const hstsHeader = "max-age=31536000; includeSubDomains"
+
+// certCheckInterval determines how often the certificate files are checked for
+// modifications.
+const certCheckInterval = 30 * time.Second
+
+// TLSOptions configures HTTPS for the server. A certificate may be supplied
+// either as a pair of files or obtained automatically from an ACME server for
+// the specified domains.
+type TLSOptions struct {
+	Addr          string
+	CertFile      string
+	KeyFile       string
+	Redirect      bool
+	HSTS          bool
+	ACMEDomains   []string
+	ACMEDirectory string
+	ACMEEmail     string
+	ACMECACert    string
+}
+
+// certReloader provides the certificate for the TLS listener, loading it from
+// disk again whenever the files change or a reload is requested.
+type certReloader struct {
+	mutex    sync.RWMutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+	log      *logrus.Entry
+	stop     chan bool
+	stopped  chan bool
+}
+
+// newCertReloader loads the certificate and begins watching for changes.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		log:      logrus.WithField("context", "tls"),
+		stop:     make(chan bool),
+		stopped:  make(chan bool),
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	go c.run()
+	return c, nil
+}
+
+// latestModTime returns the most recent modification time of the two files.
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var t time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		i, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if i.ModTime().After(t) {
+			t = i.ModTime()
+		}
+	}
+	return t, nil
+}
+
+// run periodically checks the files for changes until stopped.
+func (c *certReloader) run() {
+	defer close(c.stopped)
+	for {
+		select {
+		case <-time.After(certCheckInterval):
+		case <-c.stop:
+			return
+		}
+		c.check()
+	}
+}
+
+// check reloads the certificate if either file was modified since it was
+// last loaded.
+func (c *certReloader) check() {
+	t, err := c.latestModTime()
+	if err != nil {
+		c.log.WithError(err).Warning("unable to check certificate")
+		return
+	}
+	c.mutex.RLock()
+	changed := t.After(c.modTime)
+	c.mutex.RUnlock()
+	if changed {
+		if err := c.Reload(); err != nil {
+			c.log.WithError(err).Error("unable to reload certificate")
+		}
+	}
+}
+
+// Reload loads the certificate and key from disk. The existing certificate
+// remains in use if an error occurs.
+func (c *certReloader) Reload() error {
+	t, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cert = &cert
+	c.modTime = t
+	c.log.Info("certificate loaded")
+	return nil
+}
+
+// GetCertificate returns the current certificate for a TLS handshake.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.cert, nil
+}
+
+// Close stops watching for changes.
+func (c *certReloader) Close() {
+	close(c.stop)
+	<-c.stopped
+}
+
+// newACMEManager creates a manager for obtaining certificates from an ACME
+// server. Certificates and the account key are cached in the data directory.
+func newACMEManager(o *TLSOptions, dataDir string) (*autocert.Manager, error) {
+	client := &acme.Client{
+		DirectoryURL: o.ACMEDirectory,
+	}
+	if len(o.ACMECACert) != 0 {
+		b, err := ioutil.ReadFile(o.ACMECACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("invalid ACME CA certificate")
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(path.Join(dataDir, "acme")),
+		HostPolicy: autocert.HostWhitelist(o.ACMEDomains...),
+		Client:     client,
+		Email:      o.ACMEEmail,
+	}, nil
+}
+
+// hsts adds the Strict-Transport-Security header to responses sent over HTTPS.
+func (s *Server) hsts(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", hstsHeader)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHTTPS sends the client to the same URL on the HTTPS listener.
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(s.tlsServer.Addr); err == nil && port != "443" && len(port) != 0 {
+		host = net.JoinHostPort(host, port)
+	}
+	u := *r.URL
+	u.Scheme = "https"
+	u.Host = host
+	http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+}
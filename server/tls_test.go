@@ -0,0 +1,416 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// testDomain is the domain that certificates are issued for.
+const testDomain = "informas.example.org"
+
+// acmeIdentifierOID is the extension that identifies a TLS-ALPN-01
+// challenge certificate.
+var acmeIdentifierOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// newTestKey generates a key for a test certificate.
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newTestCert creates a certificate for the public key, signed by the parent
+// or self-signed if the parent is nil.
+func newTestCert(t *testing.T, tmpl *x509.Certificate, pub interface{}, parent *x509.Certificate, key *ecdsa.PrivateKey) *x509.Certificate {
+	now := time.Now()
+	tmpl.SerialNumber = big.NewInt(now.UnixNano())
+	tmpl.NotBefore = now.Add(-time.Hour)
+	tmpl.NotAfter = now.Add(90 * 24 * time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// pemCert encodes certificates as PEM.
+func pemCert(certs ...*x509.Certificate) []byte {
+	b := []byte{}
+	for _, c := range certs {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return b
+}
+
+// writeTestCert writes a self-signed certificate with the common name and its
+// key to the files, marking them as modified at the specified time.
+func writeTestCert(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	var (
+		key  = newTestKey(t)
+		cert = newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: name}}, &key.PublicKey, nil, key)
+	)
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pemCert(cert), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// currentName returns the common name of the certificate being served.
+func currentName(t *testing.T, c *certReloader) string {
+	cert, err := c.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReload(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		certFile = path.Join(dir, "cert.pem")
+		keyFile  = path.Join(dir, "key.pem")
+		now      = time.Now()
+	)
+	writeTestCert(t, certFile, keyFile, "first", now.Add(-time.Hour))
+	c, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if n := currentName(t, c); n != "first" {
+		t.Fatalf("serving %q", n)
+	}
+	c.check()
+	if n := currentName(t, c); n != "first" {
+		t.Fatalf("serving %q after an unchanged check", n)
+	}
+	writeTestCert(t, certFile, keyFile, "second", now)
+	c.check()
+	if n := currentName(t, c); n != "second" {
+		t.Fatalf("serving %q after the files changed", n)
+	}
+	if err := ioutil.WriteFile(certFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(certFile, now.Add(time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	c.check()
+	if n := currentName(t, c); n != "second" {
+		t.Fatalf("serving %q after an invalid certificate was written", n)
+	}
+}
+
+// testCA is a stand-in ACME server that validates TLS-ALPN-01 challenges by
+// asking the manager for the challenge certificate and issues certificates
+// signed by its own CA. It serves a single account and order.
+type testCA struct {
+	*httptest.Server
+	t       *testing.T
+	mutex   sync.Mutex
+	key     *ecdsa.PrivateKey
+	cert    *x509.Certificate
+	manager *autocert.Manager
+	nonce   int
+	status  string
+	order   string
+	issued  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	ca := &testCA{
+		t:      t,
+		key:    newTestKey(t),
+		status: acme.StatusPending,
+		order:  acme.StatusPending,
+	}
+	ca.cert = newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &ca.key.PublicKey, nil, ca.key)
+	ca.Server = httptest.NewTLSServer(http.HandlerFunc(ca.handle))
+	t.Cleanup(ca.Close)
+	return ca
+}
+
+// payload decodes the payload of a JWS request. The signature is not
+// checked.
+func (ca *testCA) payload(r *http.Request, v interface{}) {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		ca.t.Error(err)
+		return
+	}
+	if len(jws.Payload) == 0 || v == nil {
+		return
+	}
+	b, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		ca.t.Error(err)
+		return
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		ca.t.Error(err)
+	}
+}
+
+// writeOrder writes the order with the specified status code.
+func (ca *testCA) writeOrder(w http.ResponseWriter, status int) {
+	o := map[string]interface{}{
+		"status":         ca.order,
+		"identifiers":    []map[string]string{{"type": "dns", "value": testDomain}},
+		"authorizations": []string{ca.URL + "/authz"},
+		"finalize":       ca.URL + "/finalize",
+	}
+	if ca.issued != nil {
+		o["certificate"] = ca.URL + "/cert"
+	}
+	w.Header().Set("Location", ca.URL+"/order/1")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(o)
+}
+
+// challenge returns the TLS-ALPN-01 challenge.
+func (ca *testCA) challenge() map[string]string {
+	return map[string]string{
+		"type":   "tls-alpn-01",
+		"url":    ca.URL + "/challenge",
+		"token":  "token",
+		"status": ca.status,
+	}
+}
+
+// validate checks that the manager serves the challenge certificate.
+func (ca *testCA) validate() bool {
+	cert, err := ca.manager.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      testDomain,
+		SupportedProtos: []string{acme.ALPNProto},
+	})
+	if err != nil {
+		ca.t.Error(err)
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		ca.t.Error(err)
+		return false
+	}
+	for _, e := range leaf.Extensions {
+		if e.Id.Equal(acmeIdentifierOID) {
+			return leaf.VerifyHostname(testDomain) == nil
+		}
+	}
+	return false
+}
+
+// issue signs a certificate for the names in the CSR.
+func (ca *testCA) issue(csr string) {
+	b, err := base64.RawURLEncoding.DecodeString(csr)
+	if err != nil {
+		ca.t.Error(err)
+		return
+	}
+	req, err := x509.ParseCertificateRequest(b)
+	if err != nil {
+		ca.t.Error(err)
+		return
+	}
+	leaf := newTestCert(ca.t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: req.DNSNames[0]},
+		DNSNames:    req.DNSNames,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, req.PublicKey, ca.cert, ca.key)
+	ca.issued = pemCert(leaf, ca.cert)
+	ca.order = acme.StatusValid
+}
+
+func (ca *testCA) handle(w http.ResponseWriter, r *http.Request) {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+	ca.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce%d", ca.nonce))
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/directory":
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   ca.URL + "/nonce",
+			"newAccount": ca.URL + "/account",
+			"newOrder":   ca.URL + "/order",
+			"revokeCert": ca.URL + "/revoke",
+			"keyChange":  ca.URL + "/key",
+		})
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+	case "/account":
+		ca.payload(r, nil)
+		w.Header().Set("Location", ca.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": acme.StatusValid})
+	case "/order":
+		var req struct {
+			Identifiers []map[string]string `json:"identifiers"`
+		}
+		ca.payload(r, &req)
+		if len(req.Identifiers) != 1 || req.Identifiers[0]["value"] != testDomain {
+			ca.t.Errorf("order for %v", req.Identifiers)
+		}
+		ca.writeOrder(w, http.StatusCreated)
+	case "/order/1":
+		ca.payload(r, nil)
+		ca.writeOrder(w, http.StatusOK)
+	case "/authz":
+		ca.payload(r, nil)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     ca.status,
+			"identifier": map[string]string{"type": "dns", "value": testDomain},
+			"challenges": []map[string]string{ca.challenge()},
+		})
+	case "/challenge":
+		ca.payload(r, nil)
+		if ca.validate() {
+			ca.status = acme.StatusValid
+			ca.order = acme.StatusReady
+		} else {
+			ca.status = acme.StatusInvalid
+			ca.order = acme.StatusInvalid
+		}
+		json.NewEncoder(w).Encode(ca.challenge())
+	case "/finalize":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		ca.payload(r, &req)
+		ca.issue(req.CSR)
+		ca.writeOrder(w, http.StatusOK)
+	case "/cert":
+		ca.payload(r, nil)
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(ca.issued)
+	default:
+		ca.t.Errorf("unexpected request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+// writeCACert writes the certificate that the stand-in ACME server is served
+// with, which is what --acme-ca-cert points to.
+func (ca *testCA) writeCACert(t *testing.T) string {
+	f := path.Join(t.TempDir(), "acme-ca.pem")
+	if err := ioutil.WriteFile(f, pemCert(ca.Certificate()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestACME(t *testing.T) {
+	var (
+		ca      = newTestCA(t)
+		dataDir = t.TempDir()
+	)
+	m, err := newACMEManager(&TLSOptions{
+		ACMEDomains:   []string{testDomain},
+		ACMEDirectory: ca.URL + "/directory",
+		ACMEEmail:     "admin@example.org",
+		ACMECACert:    ca.writeCACert(t),
+	}, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.manager = m
+	s := httptest.NewUnstartedServer(http.NotFoundHandler())
+	s.TLS = &tls.Config{GetCertificate: m.GetCertificate}
+	s.StartTLS()
+	defer s.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", s.Listener.Addr().String(), &tls.Config{
+		ServerName: testDomain,
+		RootCAs:    pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.VerifyHostname(testDomain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(dataDir, "acme", testDomain)); err != nil {
+		t.Fatalf("certificate was not cached: %v", err)
+	}
+}
+
+func TestACMEUntrustedCA(t *testing.T) {
+	ca := newTestCA(t)
+	m, err := newACMEManager(&TLSOptions{
+		ACMEDomains:   []string{testDomain},
+		ACMEDirectory: ca.URL + "/directory",
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: testDomain})
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("got %v, expected the ACME server to be untrusted", err)
+	}
+}
+
+func TestACMEInvalidCACert(t *testing.T) {
+	f := path.Join(t.TempDir(), "acme-ca.pem")
+	if err := ioutil.WriteFile(f, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newACMEManager(&TLSOptions{ACMECACert: f}, t.TempDir()); err == nil {
+		t.Fatal("invalid CA certificate was accepted")
+	}
+}
@@ -0,0 +1,845 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/nathan-osman/informas/approval"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/notify"
+	"github.com/nathan-osman/informas/publisher"
+	"github.com/nathan-osman/informas/twittertext"
+	"github.com/nathan-osman/informas/webhook"
+)
+
+const (
+	// maxParts limits the number of tweets in a thread
+	maxParts = 25
+
+	// scheduleLayout is the format of the datetime-local input used for
+	// choosing when a tweet is published
+	scheduleLayout = "2006-01-02T15:04"
+)
+
+// errInvalidParts is shown when one or more parts of a thread would be
+// rejected by the platform. The reason is shown beside each part.
+var errInvalidParts = newPublicError("some tweets cannot be published", nil)
+
+// composePart is one tweet of a thread as entered in the compose form. Media
+// contains the IDs of uploaded files separated by commas and Count is the
+// length of the text under the twitter-text rules.
+type composePart struct {
+	Text  string
+	Media string
+	Error string
+	Count *twittertext.Result
+}
+
+// newComposePart creates a part and counts its text.
+func newComposePart(text, media string) *composePart {
+	return &composePart{
+		Text:  text,
+		Media: media,
+		Count: twittertext.Parse(text),
+	}
+}
+
+// composeTarget is an account that the draft may be posted to. If Override
+// is not empty, it replaces the text of the first tweet for the account.
+type composeTarget struct {
+	Account  *db.Account
+	Selected bool
+	Override string
+	Error    string
+}
+
+// composeForm contains the values entered in the compose form. If Queue is
+// set, Scheduled is ignored and each tweet is placed in the next free posting
+// slot of its account. InReplyTo is set when replying to a mention. DraftID
+// is the draft that the form was autosaved to. Visibility and ContentWarning
+// only apply to the platforms that support them.
+type composeForm struct {
+	DraftID        int
+	AccountIDs     []int
+	Parts          []*composePart
+	Scheduled      string
+	Queue          bool
+	InReplyTo      string
+	Visibility     string
+	ContentWarning string
+	Targets        []*composeTarget
+}
+
+// parseComposeForm reads the values of the compose form, which are either
+// submitted or loaded from a draft. Parts without text or media are ignored
+// so that blank parts can be left in the form.
+func parseComposeForm(v url.Values) *composeForm {
+	var (
+		f = &composeForm{
+			DraftID:        atoi(v.Get("draft")),
+			Scheduled:      v.Get("scheduled"),
+			Queue:          len(v.Get("queue")) != 0,
+			Visibility:     v.Get("visibility"),
+			ContentWarning: strings.TrimSpace(v.Get("content_warning")),
+		}
+		media = v["media"]
+	)
+	for _, id := range v["account"] {
+		f.AccountIDs = append(f.AccountIDs, atoi(id))
+	}
+	for i, text := range v["text"] {
+		m := ""
+		if i < len(media) {
+			m = media[i]
+		}
+		p := newComposePart(text, m)
+		if len(strings.TrimSpace(p.Text)) == 0 && len(strings.TrimSpace(p.Media)) == 0 {
+			continue
+		}
+		f.Parts = append(f.Parts, p)
+	}
+	return f
+}
+
+// setAccounts lists the accounts that the draft may be posted to along with
+// the values entered for them. An error is returned if any of the chosen
+// accounts is not in the list.
+func (f *composeForm) setAccounts(v url.Values, accounts []*db.Account) error {
+	selected := 0
+	for _, a := range accounts {
+		target := &composeTarget{
+			Account:  a,
+			Override: v.Get(fmt.Sprintf("override_%d", a.ID)),
+		}
+		for _, id := range f.AccountIDs {
+			if id == a.ID {
+				target.Selected = true
+			}
+		}
+		if target.Selected {
+			selected++
+		}
+		f.Targets = append(f.Targets, target)
+	}
+	if selected != len(f.AccountIDs) {
+		return newPublicError("invalid account", nil)
+	}
+	return nil
+}
+
+// partMedia finds the files attached to a part, ensuring that they were
+// uploaded by the user.
+func partMedia(t *db.Token, u *db.User, v string) ([]*db.Media, error) {
+	files := []*db.Media{}
+	for _, id := range strings.Split(v, ",") {
+		if len(strings.TrimSpace(id)) == 0 {
+			continue
+		}
+		m, err := db.FindMedia(t, "ID", atoi(strings.TrimSpace(id)))
+		if err != nil || m.UserID != u.ID {
+			return nil, newPublicError("invalid attachment", err)
+		}
+		files = append(files, m)
+	}
+	return files, nil
+}
+
+// validatePart checks that the platform will accept the post with the files
+// attached. Only the types of the files are needed, so they are not read.
+func validatePart(pub publisher.Publisher, p *publisher.Post, files []*db.Media) error {
+	for _, m := range files {
+		p.Media = append(p.Media, &publisher.Media{
+			Filename:    m.Key,
+			Size:        m.Size,
+			ContentType: m.ContentType,
+			AltText:     m.AltText,
+		})
+	}
+	return pub.Validate(p)
+}
+
+// parseScheduled reads the time at which a tweet should be published in the
+// user's time zone. An empty value means as soon as possible.
+func parseScheduled(v string, loc *time.Location, now time.Time) (time.Time, error) {
+	if len(v) == 0 {
+		return now, nil
+	}
+	scheduled, err := time.ParseInLocation(scheduleLayout, v, loc)
+	if err != nil {
+		return time.Time{}, newPublicError("invalid time", err)
+	}
+	if scheduled.Before(now) {
+		return time.Time{}, newPublicError("scheduled time is in the past", nil)
+	}
+	return scheduled.UTC(), nil
+}
+
+// queueSlot finds the next posting slot of the account that no other tweet
+// is waiting for.
+func queueSlot(t *db.Token, a *db.Account, now time.Time) (time.Time, error) {
+	slots, err := db.AccountSlots(t, a.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	taken, err := db.QueuedTimes(t, a.ID, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	slot := nextSlot(slots, now, taken)
+	if slot.IsZero() {
+		return time.Time{}, newPublicError("one of the accounts has no free posting slots", nil)
+	}
+	return slot, nil
+}
+
+// addPartError records a problem with a part for one of the accounts.
+func addPartError(cp *composePart, a *db.Account, err error, accounts int) {
+	msg := err.Error()
+	if accounts > 1 {
+		msg = fmt.Sprintf("@%s: %s", a.Username, msg)
+	}
+	if len(cp.Error) != 0 {
+		msg = cp.Error + "; " + msg
+	}
+	cp.Error = msg
+}
+
+// createTweets validates the compose form and creates a group containing a
+// tweet for each of the chosen accounts. Approval applies to the group, so
+// the tweets are scheduled straight away only if the policy of every account
+// allows it; otherwise the approvers of the first stage are notified.
+func (s *Server) createTweets(t *db.Token, u *db.User, f *composeForm, loc *time.Location) ([]*db.Tweet, error) {
+	if len(f.AccountIDs) == 0 {
+		return nil, newPublicError("no accounts were chosen", nil)
+	}
+	if len(f.Parts) == 0 {
+		return nil, newPublicError("tweet is empty", nil)
+	}
+	if len(f.Parts) > maxParts {
+		return nil, newPublicError("thread has too many tweets", nil)
+	}
+	o, err := s.publisherOptions()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	scheduled := now
+	if !f.Queue {
+		scheduled, err = parseScheduled(f.Scheduled, loc, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	files := [][]*db.Media{}
+	for _, cp := range f.Parts {
+		m, err := partMedia(t, u, cp.Media)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, m)
+	}
+	var (
+		invalid   = false
+		tweets    = []*db.Tweet{}
+		parts     = map[*db.Tweet][]*db.TweetPart{}
+		usernames = []string{}
+		policies  = []*approval.Policy{}
+	)
+	for _, target := range f.Targets {
+		if !target.Selected {
+			continue
+		}
+		a := target.Account
+		pub, err := publisher.New(a, o)
+		if err != nil {
+			return nil, newPublicError("invalid account", err)
+		}
+		tw := &db.Tweet{
+			AccountID:      a.ID,
+			UserID:         u.ID,
+			InReplyTo:      f.InReplyTo,
+			Visibility:     f.Visibility,
+			ContentWarning: f.ContentWarning,
+			Scheduled:      scheduled,
+			NextAttempt:    scheduled,
+		}
+		if f.Queue {
+			slot, err := queueSlot(t, a, now)
+			if err != nil {
+				return nil, err
+			}
+			tw.Scheduled = slot
+			tw.NextAttempt = slot
+		}
+		for i, cp := range f.Parts {
+			part := &db.TweetPart{
+				Position: i + 1,
+				Text:     cp.Text,
+			}
+			for _, m := range files[i] {
+				part.MediaIDs = append(part.MediaIDs, m.ID)
+			}
+			override := i == 0 && len(strings.TrimSpace(target.Override)) != 0
+			if override {
+				part.Text = target.Override
+			}
+			post := &publisher.Post{
+				Text:           part.Text,
+				Visibility:     tw.Visibility,
+				ContentWarning: tw.ContentWarning,
+			}
+			if err := validatePart(pub, post, files[i]); err != nil {
+				if override {
+					target.Error = err.Error()
+				} else {
+					addPartError(cp, a, err, len(f.AccountIDs))
+				}
+				invalid = true
+			}
+			parts[tw] = append(parts[tw], part)
+		}
+		policy, err := approval.Load(t, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		tweets = append(tweets, tw)
+		policies = append(policies, policy)
+		usernames = append(usernames, "@"+a.Username)
+	}
+	if invalid {
+		return nil, errInvalidParts
+	}
+	policy := approval.Combine(u.ID, policies...)
+	state, err := policy.Start(u.ID, now)
+	if err != nil {
+		return nil, newPublicError("the approval policy cannot be met for this author", err)
+	}
+	g := &db.TweetGroup{UserID: u.ID}
+	approval.SetGroupState(g, state)
+	if err := g.Save(t); err != nil {
+		return nil, err
+	}
+	for _, tw := range tweets {
+		tw.GroupID = g.ID
+		tw.Status = db.TweetScheduled
+		if state.Status != approval.StatusApproved {
+			tw.Status = db.TweetPending
+		}
+		if err := tw.Save(t); err != nil {
+			return nil, err
+		}
+		for _, part := range parts[tw] {
+			part.TweetID = tw.ID
+			if err := part.Save(t); err != nil {
+				return nil, err
+			}
+		}
+		if err := addRevision(t, tw.ID, u.ID, parts[tw]); err != nil {
+			return nil, err
+		}
+	}
+	if err := webhook.EnqueueTweets(t, webhook.EventTweetSubmitted, tweets...); err != nil {
+		return nil, err
+	}
+	if state.Status == approval.StatusApproved {
+		if err := webhook.EnqueueTweets(t, webhook.EventTweetApproved, tweets...); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := notify.Notify(
+			t,
+			policy.Approvers(state),
+			notify.KindApprovalRequired,
+			fmt.Sprintf(
+				"%s wrote a tweet for %s that needs your approval",
+				u.Username,
+				strings.Join(usernames, ", "),
+			),
+			fmt.Sprintf("/tweets/%d", tweets[0].ID),
+		); err != nil {
+			return nil, err
+		}
+	}
+	return tweets, nil
+}
+
+// tweetsNew displays the compose form and creates a tweet or thread for one
+// or more of the accounts the user has access to. The form is filled from a
+// draft if one is chosen and the draft is removed once the tweet is created.
+func (s *Server) tweetsNew(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		values      = r.Form
+		form        = parseComposeForm(values)
+		drafts      []*draftView
+		tweets      []*db.Tweet
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		if id := r.URL.Query().Get("draft"); r.Method != http.MethodPost && len(id) != 0 {
+			d, err := db.FindDraft(t, currentUser.ID, atoi(id))
+			if err != nil {
+				return newPublicError("invalid draft", err)
+			}
+			values, err = draftValues(d)
+			if err != nil {
+				return err
+			}
+			form = parseComposeForm(values)
+		}
+		accounts, err := db.UserAccounts(t, currentUser)
+		if err != nil {
+			return err
+		}
+		if err := form.setAccounts(values, accounts); err != nil {
+			return err
+		}
+		if r.Method == http.MethodPost {
+			tweets, err = s.createTweets(t, currentUser, form, s.timePrefs(currentUser).Location)
+			if err != nil {
+				return err
+			}
+			if form.DraftID != 0 {
+				return db.DeleteDraft(t, currentUser.ID, form.DraftID)
+			}
+			return nil
+		}
+		drafts, err = userDrafts(t, currentUser)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.webhooks.Wake()
+		if tweets[0].Status == db.TweetScheduled {
+			s.sender.Wake()
+			s.addAlert(w, r, alertInfo, "tweet scheduled")
+		} else {
+			s.addAlert(w, r, alertInfo, "tweet submitted for approval")
+		}
+		http.Redirect(w, r, fmt.Sprintf("/tweets/%d", tweets[0].ID), http.StatusFound)
+		return
+	}
+	if len(form.Parts) == 0 {
+		form.Parts = []*composePart{newComposePart("", "")}
+	}
+	s.render(w, r, "tweetsNew.html", pongo2.Context{
+		"title":     "Compose",
+		"form":      form,
+		"drafts":    drafts,
+		"maxLength": twittertext.DefaultConfig.MaxWeightedTweetLength,
+	})
+}
+
+// tweetView is a tweet with its account and parts for display. Sent is the
+// number of parts that have been published.
+type tweetView struct {
+	*db.Tweet
+	Account *db.Account
+	Author  *db.User
+	Parts   []*db.TweetPart
+	Sent    int
+}
+
+// newTweetView loads the account, author and parts of a tweet.
+func newTweetView(t *db.Token, tw *db.Tweet) (*tweetView, error) {
+	a, err := db.FindAccount(t, "ID", tw.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := db.FindUser(t, "ID", tw.UserID)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := db.TweetParts(t, tw.ID)
+	if err != nil {
+		return nil, err
+	}
+	v := &tweetView{
+		Tweet:   tw,
+		Account: a,
+		Author:  u,
+		Parts:   parts,
+	}
+	for _, p := range parts {
+		if len(p.RemoteID) != 0 {
+			v.Sent++
+		}
+	}
+	return v, nil
+}
+
+// findTweet retrieves the tweet in the URL, ensuring that the current user has
+// access to its account.
+func findTweet(t *db.Token, r *http.Request) (*db.Tweet, error) {
+	currentUser := context.Get(r, contextCurrentUser).(*db.User)
+	return findUserTweet(t, currentUser, atoi(mux.Vars(r)["id"]))
+}
+
+// findUserTweet retrieves the tweet with the specified ID, ensuring that the
+// user has access to its account.
+func findUserTweet(t *db.Token, u *db.User, id int) (*db.Tweet, error) {
+	tw, err := db.FindTweet(t, "ID", id)
+	if err != nil {
+		return nil, newPublicError("invalid tweet", err)
+	}
+	ok, err := db.HasAccess(t, u, tw.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newPublicError("invalid tweet", nil)
+	}
+	return tw, nil
+}
+
+// tweetsId displays a tweet and the progress of publishing its parts along
+// with the other tweets in its group, the progress of its approval and the
+// comments of its reviewers.
+func (s *Server) tweetsId(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		tweet       *tweetView
+		others      []*tweetView
+		review      *approvalView
+		lastReview  *db.Review
+		changes     []*partDiff
+		comments    []*db.Comment
+		canRead     bool
+		canComment  bool
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		tweet, err = newTweetView(t, tw)
+		if err != nil {
+			return err
+		}
+		g, p, err := groupPolicy(t, tw)
+		if err != nil {
+			return err
+		}
+		group, err := db.GroupTweets(t, g.ID)
+		if err != nil {
+			return err
+		}
+		review, err = newApprovalView(t, g, group, currentUser)
+		if err != nil {
+			return err
+		}
+		lastReview, changes, err = reviewChanges(t, g, tw.ID, currentUser)
+		if err != nil {
+			return err
+		}
+		comments, err = db.GroupComments(t, g.ID)
+		if err != nil {
+			return err
+		}
+		canRead, canComment = threadAccess(p, g, comments, currentUser)
+		if !canRead {
+			comments = nil
+		}
+		for _, g := range group {
+			if g.ID == tw.ID {
+				continue
+			}
+			v, err := newTweetView(t, g)
+			if err != nil {
+				return err
+			}
+			others = append(others, v)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	s.render(w, r, "tweetsId.html", pongo2.Context{
+		"title":       "Tweet",
+		"tweet":       tweet,
+		"others":      others,
+		"approval":    review,
+		"review":      lastReview,
+		"changes":     changes,
+		"comments":    comments,
+		"can_read":    canRead,
+		"can_comment": canComment,
+	})
+}
+
+// tweetsIdRetry schedules a failed tweet again. Publishing resumes from the
+// first part that was not published.
+func (s *Server) tweetsIdRetry(w http.ResponseWriter, r *http.Request) {
+	redirect := fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		if tw.Status != db.TweetFailed {
+			return newPublicError("only failed tweets can be retried", nil)
+		}
+		tw.Status = db.TweetScheduled
+		tw.Attempts = 0
+		tw.Error = ""
+		tw.NextAttempt = time.Now().UTC()
+		return tw.Save(t)
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.sender.Wake()
+		s.addAlert(w, r, alertInfo, "tweet scheduled")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// tweetsValidate counts the text of a tweet under the twitter-text rules so
+// that the length can be shown while the tweet is written.
+func (s *Server) tweetsValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		text = r.Form.Get("text")
+		res  = twittertext.Parse(text)
+		v    = map[string]interface{}{
+			"weighted_length": res.WeightedLength,
+			"max_length":      twittertext.DefaultConfig.MaxWeightedTweetLength,
+			"permillage":      res.Permillage,
+			"valid":           res.Valid,
+		}
+	)
+	if err := twittertext.Validate(text); err != nil {
+		v["error"] = err.Error()
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// errNotEditable is shown when the user may not change the text of a tweet.
+var errNotEditable = newPublicError("only tweets waiting for your approval can be edited", nil)
+
+// groupPolicy loads the tweet's group and its combined approval policy.
+func groupPolicy(t *db.Token, tw *db.Tweet) (*db.TweetGroup, *approval.Policy, error) {
+	g, err := db.FindTweetGroup(t, tw.GroupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	group, err := db.GroupTweets(t, g.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := approval.LoadGroup(t, g, group)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, p, nil
+}
+
+// canEdit determines whether the user may change the text of the group's
+// tweets. Only the author and the approvers of the current stage may do so
+// and only while the group is waiting for approval.
+func canEdit(p *approval.Policy, g *db.TweetGroup, u *db.User) bool {
+	state := approval.GroupState(g)
+	if state.Status != approval.StatusPending {
+		return false
+	}
+	if u.ID == g.UserID {
+		return true
+	}
+	for _, id := range p.Approvers(state) {
+		if id == u.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// editTweet validates the new text of each part and saves it as a new
+// revision. Approvals already given for the current stage are discarded so
+// that the approvers review the new text, and they are notified of the
+// change.
+func (s *Server) editTweet(t *db.Token, u *db.User, g *db.TweetGroup, p *approval.Policy, tw *tweetView, parts []*composePart) error {
+	o, err := s.publisherOptions()
+	if err != nil {
+		return err
+	}
+	pub, err := publisher.New(tw.Account, o)
+	if err != nil {
+		return newPublicError("invalid account", err)
+	}
+	invalid := false
+	for i, part := range tw.Parts {
+		files := []*db.Media{}
+		for _, id := range part.MediaIDs {
+			m, err := db.FindMedia(t, "ID", id)
+			if err != nil {
+				return err
+			}
+			files = append(files, m)
+		}
+		post := &publisher.Post{
+			Text:           parts[i].Text,
+			Visibility:     tw.Visibility,
+			ContentWarning: tw.ContentWarning,
+		}
+		if err := validatePart(pub, post, files); err != nil {
+			parts[i].Error = err.Error()
+			invalid = true
+		}
+	}
+	if invalid {
+		return errInvalidParts
+	}
+	revisions, err := db.TweetRevisions(t, tw.ID)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		// Tweets written before revisions were recorded need the original
+		// text so that the edit can be compared with it
+		rev := &db.Revision{
+			TweetID: tw.ID,
+			UserID:  tw.UserID,
+			Created: tw.Created,
+			Texts:   partTexts(tw.Parts),
+		}
+		if err := rev.Save(t); err != nil {
+			return err
+		}
+	}
+	for i, part := range tw.Parts {
+		part.Text = parts[i].Text
+		if err := part.Save(t); err != nil {
+			return err
+		}
+	}
+	if err := addRevision(t, tw.ID, u.ID, tw.Parts); err != nil {
+		return err
+	}
+	g.Approvals = nil
+	if err := g.Save(t); err != nil {
+		return err
+	}
+	waiting := []int{}
+	for _, id := range p.Approvers(approval.GroupState(g)) {
+		if id != u.ID {
+			waiting = append(waiting, id)
+		}
+	}
+	return notify.Notify(
+		t,
+		waiting,
+		notify.KindApprovalRequired,
+		fmt.Sprintf("%s edited tweet #%d, which needs your approval", u.Username, tw.ID),
+		fmt.Sprintf("/tweets/%d", tw.ID),
+	)
+}
+
+// tweetsIdEdit allows the text of a tweet to be changed while it is waiting
+// for approval. Unsaved changes autosaved by the user are restored until
+// they are saved or discarded.
+func (s *Server) tweetsIdEdit(w http.ResponseWriter, r *http.Request) {
+	var (
+		currentUser = context.Get(r, contextCurrentUser).(*db.User)
+		redirect    = fmt.Sprintf("/tweets/%s", mux.Vars(r)["id"])
+		discard     = r.Form.Get("action") == "discard"
+		tweet       *tweetView
+		draft       *db.Draft
+		parts       = []*composePart{}
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		tw, err := findTweet(t, r)
+		if err != nil {
+			return err
+		}
+		g, p, err := groupPolicy(t, tw)
+		if err != nil {
+			return err
+		}
+		if !canEdit(p, g, currentUser) {
+			return errNotEditable
+		}
+		tweet, err = newTweetView(t, tw)
+		if err != nil {
+			return err
+		}
+		draft, err = db.FindTweetDraft(t, currentUser.ID, tw.ID)
+		if err == sql.ErrNoRows {
+			draft = nil
+		} else if err != nil {
+			return err
+		}
+		texts := r.Form["text"]
+		if r.Method != http.MethodPost && draft != nil {
+			v, err := url.ParseQuery(draft.Form)
+			if err != nil {
+				return err
+			}
+			if len(v["text"]) == len(tweet.Parts) {
+				texts = v["text"]
+			} else {
+				draft = nil
+			}
+		}
+		for i, part := range tweet.Parts {
+			text := part.Text
+			if i < len(texts) {
+				text = texts[i]
+			}
+			parts = append(parts, newComposePart(text, ""))
+		}
+		if r.Method != http.MethodPost {
+			return nil
+		}
+		if !discard {
+			if len(texts) != len(tweet.Parts) {
+				return newPublicError("invalid tweet", nil)
+			}
+			if err := s.editTweet(t, currentUser, g, p, tweet, parts); err != nil {
+				return err
+			}
+		}
+		if draft != nil {
+			return db.DeleteDraft(t, currentUser.ID, draft.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+		if tweet == nil {
+			http.Redirect(w, r, redirect, http.StatusFound)
+			return
+		}
+	} else if discard {
+		s.addAlert(w, r, alertInfo, "unsaved changes discarded")
+		http.Redirect(w, r, redirect+"/edit", http.StatusFound)
+		return
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "tweet updated")
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	s.render(w, r, "tweetsEdit.html", pongo2.Context{
+		"title":     "Edit Tweet",
+		"tweet":     tweet,
+		"parts":     parts,
+		"draft":     draft,
+		"maxLength": twittertext.DefaultConfig.MaxWeightedTweetLength,
+	})
+}
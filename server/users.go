@@ -1,8 +1,8 @@
 package server
 
 import (
-	"errors"
 	"net/http"
+	"time"
 
 	"github.com/flosch/pongo2"
 	"github.com/gorilla/context"
@@ -14,7 +14,7 @@ import (
 func (s *Server) usersIndex(w http.ResponseWriter, r *http.Request) {
 	u, err := db.AllUsers(&db.Token{}, "Username")
 	if err != nil {
-		s.addAlert(w, r, alertDanger, err.Error())
+		s.addError(w, r, err)
 	}
 	s.render(w, r, "usersIndex.html", pongo2.Context{
 		"title": "Users",
@@ -41,7 +41,7 @@ func (s *Server) usersCreateOrEdit(w http.ResponseWriter, r *http.Request, title
 		if action == "edit" {
 			u, err := db.FindUser(t, "ID", userID)
 			if err != nil {
-				return errors.New("invalid user")
+				return newPublicError("invalid user", err)
 			}
 			user = u
 		}
@@ -49,27 +49,47 @@ func (s *Server) usersCreateOrEdit(w http.ResponseWriter, r *http.Request, title
 			if action == "edit" {
 				if len(password) != 0 {
 					if password != password2 {
-						return errors.New("passwords do not match")
+						return newPublicError("passwords do not match", nil)
 					}
 					if err := user.SetPassword(password); err != nil {
-						return errors.New("unable to set password")
+						return newPublicError("unable to set password", err)
 					}
 				}
 			}
 			user.Username = r.Form.Get("username")
 			user.Email = r.Form.Get("email")
+			user.TimeZone = r.Form.Get("time_zone")
+			user.DateFormat = r.Form.Get("date_format")
+			if len(user.TimeZone) != 0 {
+				if _, err := time.LoadLocation(user.TimeZone); err != nil {
+					return newPublicError("unknown time zone", err)
+				}
+			}
+			if len(user.DateFormat) != 0 && !isDateFormat(user.DateFormat) {
+				return newPublicError("invalid date format", nil)
+			}
+			user.EmailMode = r.Form.Get("email_mode")
+			switch user.EmailMode {
+			case db.EmailOff, db.EmailInstant, db.EmailDigest:
+			default:
+				return newPublicError("invalid email notification setting", nil)
+			}
+			user.Locale = r.Form.Get("locale")
+			if len(user.Locale) != 0 && !s.isLocale(user.Locale) {
+				return newPublicError("unsupported language", nil)
+			}
 			if currentUser.IsAdmin {
 				user.IsAdmin = len(r.Form.Get("is_admin")) != 0
 				user.IsDisabled = len(r.Form.Get("is_disabled")) != 0
 			}
 			if err := user.Save(t); err != nil {
-				return errors.New("unable to save user")
+				return newPublicError("unable to save user", err)
 			}
 		}
 		return nil
 	})
 	if err != nil {
-		s.addAlert(w, r, alertDanger, err.Error())
+		s.addError(w, r, err)
 	} else if r.Method == http.MethodPost {
 		s.addAlert(w, r, alertInfo, "user account saved")
 		if currentUser.IsAdmin {
@@ -80,11 +100,14 @@ func (s *Server) usersCreateOrEdit(w http.ResponseWriter, r *http.Request, title
 		return
 	}
 	s.render(w, r, "usersCreateOrEdit.html", pongo2.Context{
-		"title":     title,
-		"action":    action,
-		"user":      user,
-		"password":  password,
-		"password2": password2,
+		"title":        title,
+		"action":       action,
+		"user":         user,
+		"password":     password,
+		"password2":    password2,
+		"date_formats": dateFormats,
+		"now":          time.Now(),
+		"locales":      s.availableLocales(),
 	})
 }
 
@@ -104,7 +127,7 @@ func (s *Server) usersIdDelete(w http.ResponseWriter, r *http.Request) {
 	err := db.Transaction(func(t *db.Token) error {
 		u, err := db.FindUser(t, "ID", atoi(mux.Vars(r)["id"]))
 		if err != nil {
-			return err
+			return newPublicError("invalid user", err)
 		}
 		user = u
 		if r.Method == http.MethodPost {
@@ -115,7 +138,7 @@ func (s *Server) usersIdDelete(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 	if err != nil {
-		s.addAlert(w, r, alertDanger, err.Error())
+		s.addError(w, r, err)
 	} else if r.Method == http.MethodPost {
 		s.addAlert(w, r, alertInfo, "user deleted")
 		http.Redirect(w, r, "/users", http.StatusFound)
@@ -139,22 +162,23 @@ func (s *Server) usersLogin(w http.ResponseWriter, r *http.Request) {
 			password = r.Form.Get("password")
 			u, err := db.FindUser(t, "Username", username)
 			if err != nil {
-				return errors.New("invalid username")
+				return newPublicError("invalid username", nil)
 			}
 			if err := u.Authenticate(password); err != nil {
-				return errors.New("invalid password")
+				return newPublicError("invalid password", nil)
 			}
 			if u.IsDisabled {
-				return errors.New("disabled account")
+				return newPublicError("disabled account", nil)
 			}
-			session, _ := s.sessions.Get(r, sessionName)
+			session, _ := s.sessionStore().Get(r, sessionName)
 			session.Values[sessionUserID] = u.ID
 			session.Save(r, w)
 			http.Redirect(w, r, "/", http.StatusFound)
 			return nil
 		})
 		if err != nil {
-			s.addAlert(w, r, alertDanger, err.Error())
+			loginFailuresTotal.Inc()
+			s.addError(w, r, err)
 		} else {
 			return
 		}
@@ -168,7 +192,7 @@ func (s *Server) usersLogin(w http.ResponseWriter, r *http.Request) {
 
 // usersLogout ends a user's current session.
 func (s *Server) usersLogout(w http.ResponseWriter, r *http.Request) {
-	session, _ := s.sessions.Get(r, sessionName)
+	session, _ := s.sessionStore().Get(r, sessionName)
 	delete(session.Values, sessionUserID)
 	session.Save(r, w)
 	s.addAlert(w, r, alertInfo, "you have been logged out")
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"encoding/json"
+	"net/http"
 	"strconv"
 )
 
@@ -13,3 +15,34 @@ func atoi(s string) int {
 	}
 	return v
 }
+
+// writeJSON encodes the value as JSON and writes it to the response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusWriter wraps an http.ResponseWriter in order to record the status code
+// and number of bytes written to the client.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader records the status code before passing it along.
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write ensures that an implicit 200 status is recorded.
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.size += n
+	return n, err
+}
@@ -30,7 +30,7 @@ func (s *Server) view(a access, f http.HandlerFunc) http.HandlerFunc {
 
 		// Check for a user session
 		var currentUser *db.User
-		session, _ := s.sessions.Get(r, sessionName)
+		session, _ := s.sessionStore().Get(r, sessionName)
 		if v, ok := session.Values[sessionUserID]; ok {
 			u, err := db.FindUser(&db.Token{}, "ID", v.(int))
 			if err == nil {
@@ -39,6 +39,13 @@ func (s *Server) view(a access, f http.HandlerFunc) http.HandlerFunc {
 		}
 		context.Set(r, contextCurrentUser, currentUser)
 
+		// Choose a language, preferring the user's choice over the browser's
+		var locale string
+		if currentUser != nil {
+			locale = currentUser.Locale
+		}
+		context.Set(r, contextCatalog, s.locales.Match(locale, r.Header.Get("Accept-Language")))
+
 		// Confirm that the user has permission to access the view
 		if a != accessPublic && currentUser == nil || a == accessAdmin && !currentUser.IsAdmin {
 			s.addAlert(w, r, alertDanger, "page requires authorization")
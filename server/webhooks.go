@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
+	"github.com/nathan-osman/informas/db"
+	"github.com/nathan-osman/informas/webhook"
+)
+
+// webhooksIndex displays a list of all webhooks.
+func (s *Server) webhooksIndex(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := db.AllWebhooks(&db.Token{})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "webhooksIndex.html", pongo2.Context{
+		"title":    "Webhooks",
+		"webhooks": webhooks,
+		"events":   webhook.Events,
+	})
+}
+
+// webhooksCreateOrEdit enables webhooks to be created and modified. A random
+// secret is generated if none is provided.
+func (s *Server) webhooksCreateOrEdit(w http.ResponseWriter, r *http.Request, title, action string) {
+	hook := &db.Webhook{IsEnabled: true}
+	err := db.Transaction(func(t *db.Token) error {
+		if action == "edit" {
+			h, err := db.FindWebhook(t, "ID", atoi(mux.Vars(r)["id"]))
+			if err != nil {
+				return newPublicError("invalid webhook", err)
+			}
+			hook = h
+		}
+		if r.Method == http.MethodPost {
+			hook.URL = strings.TrimSpace(r.Form.Get("url"))
+			hook.Secret = strings.TrimSpace(r.Form.Get("secret"))
+			hook.Events = strings.Join(r.Form["events"], ",")
+			hook.IsEnabled = len(r.Form.Get("is_enabled")) != 0
+			if u, err := url.Parse(hook.URL); err != nil ||
+				u.Scheme != "http" && u.Scheme != "https" || len(u.Host) == 0 {
+				return newPublicError("invalid URL", err)
+			}
+			for _, e := range r.Form["events"] {
+				if !isWebhookEvent(e) {
+					return newPublicError("invalid event", nil)
+				}
+			}
+			if len(hook.Secret) == 0 {
+				hook.Secret = hex.EncodeToString(securecookie.GenerateRandomKey(20))
+			}
+			if err := hook.Save(t); err != nil {
+				return newPublicError("unable to save webhook", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "webhook saved")
+		http.Redirect(w, r, "/webhooks", http.StatusFound)
+		return
+	}
+	s.render(w, r, "webhooksCreateOrEdit.html", pongo2.Context{
+		"title":   title,
+		"action":  action,
+		"webhook": hook,
+		"events":  webhook.Events,
+	})
+}
+
+// isWebhookEvent determines whether webhooks may subscribe to the event.
+func isWebhookEvent(event string) bool {
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhooksNew creates a new webhook.
+func (s *Server) webhooksNew(w http.ResponseWriter, r *http.Request) {
+	s.webhooksCreateOrEdit(w, r, "New Webhook", "create")
+}
+
+// webhooksIdEdit edits an existing webhook.
+func (s *Server) webhooksIdEdit(w http.ResponseWriter, r *http.Request) {
+	s.webhooksCreateOrEdit(w, r, "Edit Webhook", "edit")
+}
+
+// webhooksIdDelete removes a webhook along with its delivery log.
+func (s *Server) webhooksIdDelete(w http.ResponseWriter, r *http.Request) {
+	var hook *db.Webhook
+	err := db.Transaction(func(t *db.Token) error {
+		h, err := db.FindWebhook(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid webhook", err)
+		}
+		hook = h
+		if r.Method == http.MethodPost {
+			if err := h.Delete(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else if r.Method == http.MethodPost {
+		s.addAlert(w, r, alertInfo, "webhook deleted")
+		http.Redirect(w, r, "/webhooks", http.StatusFound)
+		return
+	}
+	s.render(w, r, "webhooksDelete.html", pongo2.Context{
+		"title":   "Delete Webhook",
+		"webhook": hook,
+	})
+}
+
+// webhooksIdDeliveries displays the most recent deliveries to a webhook.
+func (s *Server) webhooksIdDeliveries(w http.ResponseWriter, r *http.Request) {
+	var (
+		hook       *db.Webhook
+		deliveries []*db.Delivery
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		h, err := db.FindWebhook(t, "ID", atoi(mux.Vars(r)["id"]))
+		if err != nil {
+			return newPublicError("invalid webhook", err)
+		}
+		hook = h
+		deliveries, err = db.WebhookDeliveries(t, h.ID, 50)
+		return err
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	}
+	s.render(w, r, "webhooksDeliveries.html", pongo2.Context{
+		"title":      "Deliveries",
+		"webhook":    hook,
+		"deliveries": deliveries,
+	})
+}
+
+// webhooksIdPing queues a ping event for a webhook.
+func (s *Server) webhooksIdPing(w http.ResponseWriter, r *http.Request) {
+	var (
+		webhookID = atoi(mux.Vars(r)["id"])
+		redirect  = fmt.Sprintf("/webhooks/%d/deliveries", webhookID)
+	)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	err := db.Transaction(func(t *db.Token) error {
+		h, err := db.FindWebhook(t, "ID", webhookID)
+		if err != nil {
+			return newPublicError("invalid webhook", err)
+		}
+		return webhook.Ping(t, h)
+	})
+	if err != nil {
+		s.addError(w, r, err)
+	} else {
+		s.webhooks.Wake()
+		s.addAlert(w, r, alertInfo, "ping queued")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
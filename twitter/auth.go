@@ -0,0 +1,27 @@
+package twitter
+
+import (
+	"net/http"
+
+	"github.com/dghubble/oauth1"
+)
+
+// endpoint lists the URLs used for authorizing new accounts.
+var endpoint = oauth1.Endpoint{
+	RequestTokenURL: "https://api.twitter.com/oauth/request_token",
+	AuthorizeURL:    "https://api.twitter.com/oauth/authorize",
+	AccessTokenURL:  "https://api.twitter.com/oauth/access_token",
+}
+
+// AuthConfig creates the configuration for obtaining an access token using
+// the three-legged OAuth flow. The user is returned to callbackURL once they
+// have authorized the application.
+func AuthConfig(consumerKey, consumerSecret, callbackURL string) *oauth1.Config {
+	return &oauth1.Config{
+		ConsumerKey:    consumerKey,
+		ConsumerSecret: consumerSecret,
+		CallbackURL:    callbackURL,
+		Endpoint:       endpoint,
+		HTTPClient:     &http.Client{Timeout: timeout},
+	}
+}
@@ -0,0 +1,89 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/dghubble/oauth1"
+)
+
+const (
+	apiURL    = "https://api.twitter.com/1.1"
+	apiV2URL  = "https://api.twitter.com/2"
+	uploadURL = "https://upload.twitter.com/1.1"
+
+	// timeout limits the time taken by each request, including the upload of
+	// a chunk of media.
+	timeout = time.Minute
+)
+
+// Client makes authenticated requests to the Twitter API on behalf of a
+// single account.
+type Client struct {
+	client    *http.Client
+	apiURL    string
+	apiV2URL  string
+	uploadURL string
+}
+
+// NewClient creates a client using the application's consumer credentials and
+// the account's access token.
+func NewClient(consumerKey, consumerSecret, accessToken, accessSecret string) *Client {
+	var (
+		config = oauth1.NewConfig(consumerKey, consumerSecret)
+		token  = oauth1.NewToken(accessToken, accessSecret)
+	)
+	client := config.Client(oauth1.NoContext, token)
+	client.Timeout = timeout
+	return &Client{
+		client:    client,
+		apiURL:    apiURL,
+		apiV2URL:  apiV2URL,
+		uploadURL: uploadURL,
+	}
+}
+
+// Error is returned when the API responds with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns a description of the error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("twitter: %d %s", e.StatusCode, e.Message)
+}
+
+// do sends the request and decodes the JSON response into v, which may be nil
+// if the response body is not needed.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := http.StatusText(resp.StatusCode)
+		if json.Unmarshal(b, &e) == nil && len(e.Errors) != 0 {
+			msg = e.Errors[0].Message
+		}
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Message:    msg,
+		}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
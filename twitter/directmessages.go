@@ -0,0 +1,119 @@
+package twitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageCreate contains the details of a direct message.
+type MessageCreate struct {
+	Target struct {
+		RecipientID string `json:"recipient_id"`
+	} `json:"target"`
+	SenderID    string `json:"sender_id"`
+	MessageData struct {
+		Text string `json:"text"`
+	} `json:"message_data"`
+}
+
+// DirectMessage is a direct message event. CreatedTimestamp is the number of
+// milliseconds since the epoch.
+type DirectMessage struct {
+	Type             string         `json:"type"`
+	ID               string         `json:"id,omitempty"`
+	CreatedTimestamp string         `json:"created_timestamp,omitempty"`
+	MessageCreate    *MessageCreate `json:"message_create"`
+}
+
+// Created parses the time at which the message was sent.
+func (d *DirectMessage) Created() (time.Time, error) {
+	ms, err := strconv.ParseInt(d.CreatedTimestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
+
+// DirectMessages returns a page of direct message events from the last 30
+// days, newest first, along with the cursor for the next page, which is empty
+// on the last page.
+func (c *Client) DirectMessages(cursor string) ([]*DirectMessage, string, error) {
+	v := url.Values{}
+	v.Set("count", "50")
+	if len(cursor) != 0 {
+		v.Set("cursor", cursor)
+	}
+	req, err := http.NewRequest(
+		http.MethodGet,
+		c.apiURL+"/direct_messages/events/list.json?"+v.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	var resp struct {
+		Events     []*DirectMessage `json:"events"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Events, resp.NextCursor, nil
+}
+
+// SendDirectMessage sends a direct message to the user with the specified ID.
+func (c *Client) SendDirectMessage(recipientID, text string) (*DirectMessage, error) {
+	m := &MessageCreate{}
+	m.Target.RecipientID = recipientID
+	m.MessageData.Text = text
+	b, err := json.Marshal(map[string]interface{}{
+		"event": &DirectMessage{
+			Type:          "message_create",
+			MessageCreate: m,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.apiURL+"/direct_messages/events/new.json",
+		bytes.NewReader(b),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var resp struct {
+		Event *DirectMessage `json:"event"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Event, nil
+}
+
+// LookupUsers returns the accounts with the specified IDs. Up to 100 IDs may
+// be provided.
+func (c *Client) LookupUsers(ids []string) ([]*User, error) {
+	v := url.Values{}
+	v.Set("user_id", strings.Join(ids, ","))
+	req, err := http.NewRequest(
+		http.MethodGet,
+		c.apiURL+"/users/lookup.json?"+v.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	users := []*User{}
+	if err := c.do(req, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,66 @@
+package twitter
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// tweetFields lists the fields requested for tweets from the v2 API.
+const tweetFields = "created_at,public_metrics"
+
+// PublicMetrics contains the engagement counts for a tweet.
+type PublicMetrics struct {
+	RetweetCount    int `json:"retweet_count"`
+	ReplyCount      int `json:"reply_count"`
+	LikeCount       int `json:"like_count"`
+	QuoteCount      int `json:"quote_count"`
+	ImpressionCount int `json:"impression_count"`
+}
+
+// TweetMetrics describes a tweet returned by the v2 API along with its
+// engagement counts.
+type TweetMetrics struct {
+	ID            string         `json:"id"`
+	Text          string         `json:"text"`
+	CreatedAt     time.Time      `json:"created_at"`
+	PublicMetrics *PublicMetrics `json:"public_metrics"`
+}
+
+// getTweets retrieves tweets from the v2 API.
+func (c *Client) getTweets(path string, v url.Values) ([]*TweetMetrics, error) {
+	v.Set("tweet.fields", tweetFields)
+	req, err := http.NewRequest(
+		http.MethodGet,
+		c.apiV2URL+path+"?"+v.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data []*TweetMetrics `json:"data"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// UserTweets returns the most recent tweets posted by the user, newest first.
+// Retweets and replies are excluded.
+func (c *Client) UserTweets(userID string) ([]*TweetMetrics, error) {
+	v := url.Values{}
+	v.Set("max_results", "50")
+	v.Set("exclude", "retweets,replies")
+	return c.getTweets("/users/"+url.PathEscape(userID)+"/tweets", v)
+}
+
+// LookupTweets returns the tweets with the specified IDs. Up to 100 IDs may be
+// provided and tweets that no longer exist are omitted.
+func (c *Client) LookupTweets(ids []string) ([]*TweetMetrics, error) {
+	v := url.Values{}
+	v.Set("ids", strings.Join(ids, ","))
+	return c.getTweets("/tweets", v)
+}
@@ -0,0 +1,100 @@
+package twitter
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// User describes a Twitter account.
+type User struct {
+	IDString   string `json:"id_str"`
+	ScreenName string `json:"screen_name"`
+	Name       string `json:"name"`
+}
+
+// Tweet describes a status update. FullText is only set when requested with
+// the extended tweet mode.
+type Tweet struct {
+	IDString          string `json:"id_str"`
+	Text              string `json:"text"`
+	FullText          string `json:"full_text"`
+	CreatedAt         string `json:"created_at"`
+	InReplyToIDString string `json:"in_reply_to_status_id_str"`
+	User              *User  `json:"user"`
+}
+
+// Created parses the time at which the tweet was posted.
+func (t *Tweet) Created() (time.Time, error) {
+	return time.Parse(time.RubyDate, t.CreatedAt)
+}
+
+// VerifyCredentials returns the account the client is authenticated as.
+func (c *Client) VerifyCredentials() (*User, error) {
+	req, err := http.NewRequest(
+		http.MethodGet,
+		c.apiURL+"/account/verify_credentials.json",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	u := &User{}
+	if err := c.do(req, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// UpdateStatus posts a tweet with the specified media attached. If inReplyTo
+// is not empty, the tweet is posted as a reply to that tweet.
+func (c *Client) UpdateStatus(text string, mediaIDs []string, inReplyTo string) (*Tweet, error) {
+	v := url.Values{}
+	v.Set("status", text)
+	if len(mediaIDs) != 0 {
+		v.Set("media_ids", strings.Join(mediaIDs, ","))
+	}
+	if len(inReplyTo) != 0 {
+		v.Set("in_reply_to_status_id", inReplyTo)
+		v.Set("auto_populate_reply_metadata", "true")
+	}
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.apiURL+"/statuses/update.json",
+		strings.NewReader(v.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	t := &Tweet{}
+	if err := c.do(req, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// MentionsTimeline returns the most recent tweets mentioning the account,
+// newest first. If sinceID is not empty, only newer tweets are returned.
+func (c *Client) MentionsTimeline(sinceID string) ([]*Tweet, error) {
+	v := url.Values{}
+	v.Set("count", "200")
+	v.Set("tweet_mode", "extended")
+	if len(sinceID) != 0 {
+		v.Set("since_id", sinceID)
+	}
+	req, err := http.NewRequest(
+		http.MethodGet,
+		c.apiURL+"/statuses/mentions_timeline.json?"+v.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tweets := []*Tweet{}
+	if err := c.do(req, &tweets); err != nil {
+		return nil, err
+	}
+	return tweets, nil
+}
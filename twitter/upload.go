@@ -0,0 +1,188 @@
+package twitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// chunkSize is the size of each segment sent with the APPEND command
+	chunkSize = 4 * 1024 * 1024
+
+	// minProcessingWait is the shortest delay between status checks, used
+	// when Twitter does not suggest one
+	minProcessingWait = time.Second
+
+	// maxProcessingTime limits how long to wait for media to be processed
+	maxProcessingTime = 10 * time.Minute
+)
+
+// processingInfo describes the state of media being processed after upload.
+type processingInfo struct {
+	State          string `json:"state"`
+	CheckAfterSecs int    `json:"check_after_secs"`
+	Error          struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// uploadResponse is returned by the INIT, FINALIZE and STATUS commands.
+type uploadResponse struct {
+	MediaIDString  string          `json:"media_id_string"`
+	ProcessingInfo *processingInfo `json:"processing_info"`
+}
+
+// postForm sends a form-encoded command to the upload endpoint.
+func (c *Client) postForm(v url.Values, resp interface{}) error {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.uploadURL+"/media/upload.json",
+		strings.NewReader(v.Encode()),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, resp)
+}
+
+// appendChunk sends a single segment of the file.
+func (c *Client) appendChunk(mediaID string, index int, chunk []byte) error {
+	var (
+		b = &bytes.Buffer{}
+		w = multipart.NewWriter(b)
+	)
+	w.WriteField("command", "APPEND")
+	w.WriteField("media_id", mediaID)
+	w.WriteField("segment_index", strconv.Itoa(index))
+	p, err := w.CreateFormFile("media", "media")
+	if err != nil {
+		return err
+	}
+	p.Write(chunk)
+	if err := w.Close(); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.uploadURL+"/media/upload.json", b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.do(req, nil)
+}
+
+// waitForProcessing polls the status of the media until processing is done.
+// An error is returned if it does not finish within maxProcessingTime.
+func (c *Client) waitForProcessing(mediaID string, info *processingInfo) error {
+	deadline := time.Now().Add(maxProcessingTime)
+	for info != nil {
+		switch info.State {
+		case "succeeded":
+			return nil
+		case "failed":
+			if len(info.Error.Message) != 0 {
+				return errors.New(info.Error.Message)
+			}
+			return errors.New("media processing failed")
+		}
+		wait := time.Duration(info.CheckAfterSecs) * time.Second
+		if wait < minProcessingWait {
+			wait = minProcessingWait
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return errors.New("timed out waiting for media processing")
+		}
+		time.Sleep(wait)
+		v := url.Values{}
+		v.Set("command", "STATUS")
+		v.Set("media_id", mediaID)
+		req, err := http.NewRequest(
+			http.MethodGet,
+			c.uploadURL+"/media/upload.json?"+v.Encode(),
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		resp := &uploadResponse{}
+		if err := c.do(req, resp); err != nil {
+			return err
+		}
+		info = resp.ProcessingInfo
+	}
+	return nil
+}
+
+// UploadMedia uploads a file using the chunked INIT, APPEND and FINALIZE
+// commands, waiting for any processing to complete. The category must be one
+// of tweet_image, tweet_gif or tweet_video. The ID of the new media is
+// returned.
+func (c *Client) UploadMedia(r io.Reader, size int64, mediaType, category string) (string, error) {
+	v := url.Values{}
+	v.Set("command", "INIT")
+	v.Set("total_bytes", strconv.FormatInt(size, 10))
+	v.Set("media_type", mediaType)
+	v.Set("media_category", category)
+	resp := &uploadResponse{}
+	if err := c.postForm(v, resp); err != nil {
+		return "", err
+	}
+	mediaID := resp.MediaIDString
+	chunk := make([]byte, chunkSize)
+	for i := 0; ; i++ {
+		n, err := io.ReadFull(r, chunk)
+		if n != 0 {
+			if err := c.appendChunk(mediaID, i, chunk[:n]); err != nil {
+				return "", err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	v = url.Values{}
+	v.Set("command", "FINALIZE")
+	v.Set("media_id", mediaID)
+	resp = &uploadResponse{}
+	if err := c.postForm(v, resp); err != nil {
+		return "", err
+	}
+	if err := c.waitForProcessing(mediaID, resp.ProcessingInfo); err != nil {
+		return "", err
+	}
+	return mediaID, nil
+}
+
+// SetAltText attaches alt text to uploaded media.
+func (c *Client) SetAltText(mediaID, text string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"media_id": mediaID,
+		"alt_text": map[string]string{
+			"text": text,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(
+		http.MethodPost,
+		c.uploadURL+"/media/metadata/create.json",
+		bytes.NewReader(b),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, nil)
+}
@@ -0,0 +1,48 @@
+package twittertext
+
+// WeightRange assigns a weight to all code points between Start and End
+// inclusive.
+type WeightRange struct {
+	Start  rune
+	End    rune
+	Weight int
+}
+
+// Config determines how the length of a tweet is calculated. Weights are
+// multiplied by Scale so that fractional weights can be expressed as integers.
+type Config struct {
+	MaxWeightedTweetLength int
+	Scale                  int
+	DefaultWeight          int
+	TransformedURLLength   int
+	Ranges                 []WeightRange
+	EmojiParsingEnabled    bool
+}
+
+// DefaultConfig matches version 3 of the twitter-text configuration. Latin
+// script and common punctuation count as one character, CJK and most other
+// scripts count as two, every URL counts as the length of a t.co link, and
+// each emoji sequence counts as two regardless of the number of code points.
+var DefaultConfig = &Config{
+	MaxWeightedTweetLength: 280,
+	Scale:                  100,
+	DefaultWeight:          200,
+	TransformedURLLength:   23,
+	Ranges: []WeightRange{
+		{Start: 0, End: 4351, Weight: 100},
+		{Start: 8192, End: 8205, Weight: 100},
+		{Start: 8208, End: 8223, Weight: 100},
+		{Start: 8242, End: 8247, Weight: 100},
+	},
+	EmojiParsingEnabled: true,
+}
+
+// weight returns the weight of a single code point.
+func (c *Config) weight(r rune) int {
+	for _, w := range c.Ranges {
+		if r >= w.Start && r <= w.End {
+			return w.Weight
+		}
+	}
+	return c.DefaultWeight
+}
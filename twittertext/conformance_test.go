@@ -0,0 +1,82 @@
+package twittertext
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadConformance reads a conformance file from the testdata directory.
+func loadConformance(t *testing.T, name string, v interface{}) {
+	b, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWeightedLength(t *testing.T) {
+	var c struct {
+		Tests struct {
+			WeightedTweetsCounterTest []struct {
+				Description string
+				Text        string
+				Expected    struct {
+					WeightedLength int  `yaml:"weightedLength"`
+					Valid          bool `yaml:"valid"`
+					Permillage     int  `yaml:"permillage"`
+				}
+			} `yaml:"WeightedTweetsCounterTest"`
+		}
+	}
+	loadConformance(t, "validate.yml", &c)
+	if len(c.Tests.WeightedTweetsCounterTest) == 0 {
+		t.Fatal("no test cases")
+	}
+	for _, tc := range c.Tests.WeightedTweetsCounterTest {
+		r := Parse(tc.Text)
+		if r.WeightedLength != tc.Expected.WeightedLength ||
+			r.Valid != tc.Expected.Valid ||
+			r.Permillage != tc.Expected.Permillage {
+			t.Errorf(
+				"%s: got length %d, valid %t, permillage %d; expected %d, %t, %d",
+				tc.Description,
+				r.WeightedLength,
+				r.Valid,
+				r.Permillage,
+				tc.Expected.WeightedLength,
+				tc.Expected.Valid,
+				tc.Expected.Permillage,
+			)
+		}
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	var c struct {
+		Tests struct {
+			URLs []struct {
+				Description string
+				Text        string
+				Expected    []string
+			} `yaml:"urls"`
+		}
+	}
+	loadConformance(t, "extract.yml", &c)
+	if len(c.Tests.URLs) == 0 {
+		t.Fatal("no test cases")
+	}
+	for _, tc := range c.Tests.URLs {
+		urls := []string{}
+		for _, s := range ExtractURLs(tc.Text) {
+			urls = append(urls, tc.Text[s.Start:s.End])
+		}
+		if !reflect.DeepEqual(urls, tc.Expected) {
+			t.Errorf("%s: got %q, expected %q", tc.Description, urls, tc.Expected)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package twittertext
+
+const (
+	zeroWidthJoiner   = 0x200d
+	variationSelector = 0xfe0f
+	combiningKeycap   = 0x20e3
+	tagCancel         = 0xe007f
+)
+
+// pictographicRanges approximates the Extended_Pictographic property, which
+// is not provided by the unicode package.
+var pictographicRanges = [][2]rune{
+	{0x00a9, 0x00a9},
+	{0x00ae, 0x00ae},
+	{0x203c, 0x203c},
+	{0x2049, 0x2049},
+	{0x2122, 0x2122},
+	{0x2139, 0x2139},
+	{0x2194, 0x2199},
+	{0x21a9, 0x21aa},
+	{0x231a, 0x231b},
+	{0x2328, 0x2328},
+	{0x23cf, 0x23cf},
+	{0x23e9, 0x23f3},
+	{0x23f8, 0x23fa},
+	{0x24c2, 0x24c2},
+	{0x25aa, 0x25ab},
+	{0x25b6, 0x25b6},
+	{0x25c0, 0x25c0},
+	{0x25fb, 0x25fe},
+	{0x2600, 0x27bf},
+	{0x2934, 0x2935},
+	{0x2b05, 0x2b07},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x3030, 0x3030},
+	{0x303d, 0x303d},
+	{0x3297, 0x3297},
+	{0x3299, 0x3299},
+	{0x1f000, 0x1f0ff},
+	{0x1f10d, 0x1f10f},
+	{0x1f12f, 0x1f12f},
+	{0x1f16c, 0x1f171},
+	{0x1f17e, 0x1f17f},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f1ad, 0x1f1e5},
+	{0x1f201, 0x1f20f},
+	{0x1f21a, 0x1f21a},
+	{0x1f22f, 0x1f22f},
+	{0x1f232, 0x1f23a},
+	{0x1f23c, 0x1f23f},
+	{0x1f249, 0x1f3fa},
+	{0x1f400, 0x1f53d},
+	{0x1f546, 0x1f64f},
+	{0x1f680, 0x1f6ff},
+	{0x1f774, 0x1f77f},
+	{0x1f7d5, 0x1f7ff},
+	{0x1f80c, 0x1f80f},
+	{0x1f848, 0x1f84f},
+	{0x1f85a, 0x1f85f},
+	{0x1f888, 0x1f88f},
+	{0x1f8ae, 0x1f8ff},
+	{0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945},
+	{0x1f947, 0x1faff},
+	{0x1fc00, 0x1fffd},
+}
+
+func isPictographic(r rune) bool {
+	for _, p := range pictographicRanges {
+		if r >= p[0] && r <= p[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isSkinToneModifier(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
+
+func isTag(r rune) bool {
+	return r >= 0xe0020 && r <= 0xe007f
+}
+
+func isKeycapBase(r rune) bool {
+	return r >= '0' && r <= '9' || r == '#' || r == '*'
+}
+
+// at returns the rune at index i or -1 if i is out of range.
+func at(runes []rune, i int) rune {
+	if i < len(runes) {
+		return runes[i]
+	}
+	return -1
+}
+
+// emojiLength returns the number of code points in the emoji sequence that
+// starts at index i, or 0 if there is none. Code points that would otherwise
+// be displayed as text, such as the copyright sign, only begin an emoji when
+// followed by the emoji variation selector.
+func emojiLength(runes []rune, i int, c *Config) int {
+	r := runes[i]
+	switch {
+	case isRegionalIndicator(r):
+		if isRegionalIndicator(at(runes, i+1)) {
+			return 2
+		}
+		return 0
+	case isKeycapBase(r):
+		n := 1
+		if at(runes, i+n) == variationSelector {
+			n++
+		}
+		if at(runes, i+n) == combiningKeycap {
+			return n + 1
+		}
+		return 0
+	case !isPictographic(r):
+		return 0
+	case c.weight(r) <= c.Scale && at(runes, i+1) != variationSelector:
+		return 0
+	}
+	n := 0
+	for {
+		n++
+		if at(runes, i+n) == variationSelector {
+			n++
+		}
+		if isSkinToneModifier(at(runes, i+n)) {
+			n++
+		}
+		if isTag(at(runes, i+n)) {
+			for isTag(at(runes, i+n)) && at(runes, i+n) != tagCancel {
+				n++
+			}
+			if at(runes, i+n) == tagCancel {
+				n++
+			}
+		}
+		if at(runes, i+n) != zeroWidthJoiner || !isPictographic(at(runes, i+n+1)) {
+			return n
+		}
+		n++
+	}
+}
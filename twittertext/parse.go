@@ -0,0 +1,106 @@
+package twittertext
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// invalidRunes may not appear anywhere in a tweet.
+var invalidRunes = []rune{0xfffe, 0xfeff, 0xffff, 0x202a, 0x202b, 0x202c, 0x202d, 0x202e}
+
+// Result describes the outcome of parsing a tweet.
+type Result struct {
+
+	// Weighted length of the tweet after normalization
+	WeightedLength int
+
+	// Weighted length relative to the maximum, in thousandths
+	Permillage int
+
+	// Whether the tweet is non-empty, not too long and contains no invalid
+	// characters
+	Valid bool
+
+	// Whether the tweet contains characters that are never allowed
+	HasInvalidCharacters bool
+}
+
+// Parse calculates the weighted length of the text using the default
+// configuration.
+func Parse(text string) *Result {
+	return DefaultConfig.Parse(text)
+}
+
+// Parse calculates the weighted length of the text. The text is normalized to
+// NFC first, each URL counts as a t.co link and each emoji sequence counts as
+// a single character of default weight.
+func (c *Config) Parse(text string) *Result {
+	var (
+		normalized = norm.NFC.String(text)
+		weighted   = 0
+		res        = &Result{}
+		urls       = ExtractURLs(normalized)
+	)
+	for i := 0; i < len(normalized); {
+		if len(urls) != 0 && urls[0].Start == i {
+			weighted += c.TransformedURLLength * c.Scale
+			i = urls[0].End
+			urls = urls[1:]
+			continue
+		}
+		var (
+			end   = len(normalized)
+			runes []rune
+		)
+		if len(urls) != 0 {
+			end = urls[0].Start
+		}
+		runes = []rune(normalized[i:end])
+		for j := 0; j < len(runes); {
+			if c.EmojiParsingEnabled {
+				if n := emojiLength(runes, j, c); n != 0 {
+					weighted += c.DefaultWeight
+					j += n
+					continue
+				}
+			}
+			for _, r := range invalidRunes {
+				if runes[j] == r {
+					res.HasInvalidCharacters = true
+				}
+			}
+			weighted += c.weight(runes[j])
+			j++
+		}
+		i = end
+	}
+	res.WeightedLength = weighted / c.Scale
+	res.Permillage = res.WeightedLength * 1000 / c.MaxWeightedTweetLength
+	res.Valid = len(strings.TrimSpace(normalized)) != 0 &&
+		res.WeightedLength <= c.MaxWeightedTweetLength &&
+		!res.HasInvalidCharacters
+	return res
+}
+
+// Validate checks that the text may be posted and returns an error suitable
+// for displaying to the user if it may not.
+func Validate(text string) error {
+	res := Parse(text)
+	switch {
+	case !utf8.ValidString(text) || res.HasInvalidCharacters:
+		return errors.New("tweet contains invalid characters")
+	case len(strings.TrimSpace(text)) == 0:
+		return errors.New("tweet is empty")
+	case !res.Valid:
+		return fmt.Errorf(
+			"tweet is too long (%d/%d)",
+			res.WeightedLength,
+			DefaultConfig.MaxWeightedTweetLength,
+		)
+	}
+	return nil
+}
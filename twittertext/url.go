@@ -0,0 +1,97 @@
+package twittertext
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// urlRegexp matches candidate URLs, with or without a scheme. Candidates are
+// further checked by extractURLs since Go's regular expressions do not
+// support lookbehind.
+var urlRegexp = regexp.MustCompile(
+	`(?i)(https?://)?` +
+		`((?:[\p{L}\p{N}](?:[\p{L}\p{N}_-]*[\p{L}\p{N}])?\.)+\p{L}{2,})` +
+		`(:[0-9]{1,5})?` +
+		`(/[^\s]*)?`,
+)
+
+// genericTLDs lists the generic top-level domains recognized in URLs without
+// a scheme. Any two-letter top-level domain is assumed to be a country code.
+var genericTLDs = map[string]bool{}
+
+func init() {
+	for _, t := range strings.Fields(`
+        aero app arpa art asia biz blog cat club com coop design dev edu
+        email gov info int jobs live media mil mobi museum name net news
+        online org page pro shop site store tech tel travel tv wiki xxx xyz
+        `) {
+		genericTLDs[t] = true
+	}
+}
+
+// isValidPrecedingRune determines whether a URL without a scheme may follow
+// the rune. This prevents matches inside email addresses, mentions, hashtags
+// and cashtags.
+func isValidPrecedingRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) &&
+		!strings.ContainsRune("@$#.-/_", r)
+}
+
+// trimURL removes trailing punctuation that is more likely to belong to the
+// surrounding sentence than to the URL.
+func trimURL(s string) string {
+	for len(s) != 0 {
+		c := s[len(s)-1]
+		switch {
+		case strings.IndexByte(`.,;:!?'"`, c) != -1:
+		case c == ')' && strings.Count(s, "(") < strings.Count(s, ")"):
+		default:
+			return s
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// Span indicates the start and end byte offsets of an entity in the text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// ExtractURLs finds the locations of all URLs in the text, using the same
+// rules as when calculating the length of a tweet.
+func ExtractURLs(text string) []Span {
+	var spans []Span
+	for _, m := range urlRegexp.FindAllStringSubmatchIndex(text, -1) {
+		var (
+			start     = m[0]
+			hasScheme = m[2] != -1
+			host      = text[m[4]:m[5]]
+			tld       = strings.ToLower(host[strings.LastIndexByte(host, '.')+1:])
+		)
+		if start > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(text[:start])
+			if !isValidPrecedingRune(prev) {
+				if !hasScheme || unicode.IsLetter(prev) {
+					continue
+				}
+			}
+		}
+		if !hasScheme {
+			if len([]rune(tld)) != 2 && !genericTLDs[tld] {
+				continue
+			}
+			if m[1] < len(text) && text[m[1]] == '@' {
+				continue
+			}
+		}
+		spans = append(spans, Span{
+			Start: start,
+			End:   start + len(trimURL(text[start:m[1]])),
+		})
+	}
+	return spans
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pollInterval determines how often the queue is checked for deliveries
+	pollInterval = 10 * time.Second
+
+	// batchSize limits the number of deliveries sent on each check
+	batchSize = 20
+
+	// maxAttempts is the number of attempts made before giving up
+	maxAttempts = 10
+
+	// initialBackoff is the delay before the first retry, which doubles with
+	// each subsequent attempt up to maxBackoff
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+
+	// maxErrorLength limits the length of errors stored in the delivery log
+	maxErrorLength = 500
+
+	// leaseDuration is how long claimed deliveries are reserved for sending
+	// before another worker may claim them
+	leaseDuration = 5 * time.Minute
+)
+
+// Dispatcher sends queued deliveries in the background, retrying failed
+// attempts with exponential backoff.
+type Dispatcher struct {
+	client  *http.Client
+	log     *logrus.Entry
+	wake    chan bool
+	stop    chan bool
+	stopped chan bool
+}
+
+// NewDispatcher creates a new dispatcher and begins sending deliveries.
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		log:     logrus.WithField("context", "webhook"),
+		wake:    make(chan bool, 1),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go d.run()
+	return d
+}
+
+// backoff returns the delay before the next attempt.
+func backoff(attempts int) time.Duration {
+	b := initialBackoff
+	for i := 1; i < attempts && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff {
+		b = maxBackoff
+	}
+	return b
+}
+
+// send makes a single attempt at delivering to the webhook and records the
+// outcome in the delivery.
+func (d *Dispatcher) send(w *db.Webhook, dl *db.Delivery) {
+	dl.Attempts++
+	dl.ResponseCode = 0
+	dl.Error = ""
+	err := func() error {
+		body := []byte(dl.Payload)
+		req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Informas-Webhook")
+		req.Header.Set("X-Informas-Event", dl.Event)
+		req.Header.Set("X-Informas-Delivery", strconv.Itoa(dl.ID))
+		req.Header.Set("X-Informas-Signature", Sign(w.Secret, body))
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+		dl.ResponseCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &statusError{resp.Status}
+		}
+		return nil
+	}()
+	switch {
+	case err == nil:
+		dl.State = db.DeliveryDelivered
+	case dl.Attempts >= maxAttempts:
+		dl.State = db.DeliveryFailed
+	default:
+		dl.NextAttempt = time.Now().UTC().Add(backoff(dl.Attempts))
+	}
+	if err != nil {
+		dl.Error = err.Error()
+		if len(dl.Error) > maxErrorLength {
+			dl.Error = dl.Error[:maxErrorLength]
+		}
+		d.log.WithError(err).WithField("delivery", dl.ID).Warning("delivery failed")
+	}
+}
+
+// statusError indicates that the receiver responded with an error.
+type statusError struct {
+	status string
+}
+
+func (s *statusError) Error() string {
+	return "unexpected response: " + s.status
+}
+
+// dispatch claims a batch of due deliveries, sends them and returns the
+// number sent. No transaction is held open while waiting for receivers.
+func (d *Dispatcher) dispatch() (int, error) {
+	var (
+		deliveries []*db.Delivery
+		webhooks   = map[int]*db.Webhook{}
+	)
+	err := db.Transaction(func(t *db.Token) error {
+		var err error
+		deliveries, err = db.ClaimDeliveries(t, time.Now().UTC(), leaseDuration, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, dl := range deliveries {
+			if _, ok := webhooks[dl.WebhookID]; ok {
+				continue
+			}
+			w, err := db.FindWebhook(t, "ID", dl.WebhookID)
+			if err != nil {
+				return err
+			}
+			webhooks[dl.WebhookID] = w
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, dl := range deliveries {
+		d.send(webhooks[dl.WebhookID], dl)
+		if err := dl.Save(&db.Token{}); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// run sends deliveries until stopped. Full batches are followed immediately
+// by another check.
+func (d *Dispatcher) run() {
+	defer close(d.stopped)
+	for {
+		n, err := d.dispatch()
+		if err != nil {
+			d.log.WithError(err).Error("unable to dispatch deliveries")
+		}
+		if n == batchSize {
+			select {
+			case <-d.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-d.wake:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Wake checks for deliveries immediately rather than waiting for the next
+// poll. It should be called after committing a transaction that queued
+// deliveries.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- true:
+	default:
+	}
+}
+
+// Close stops sending deliveries.
+func (d *Dispatcher) Close() {
+	close(d.stop)
+	<-d.stopped
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+	"github.com/sirupsen/logrus"
+)
+
+// receiver is a webhook endpoint that verifies the signature of each request
+// and fails until the specified number of attempts have been made.
+type receiver struct {
+	*httptest.Server
+	secret   string
+	failures int
+	requests int
+}
+
+func newReceiver(t *testing.T, secret string, failures int) *receiver {
+	rc := &receiver{
+		secret:   secret,
+		failures: failures,
+	}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.requests++
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := r.Header.Get("X-Informas-Signature"); v != Sign(rc.secret, body) {
+			t.Errorf("got signature %q", v)
+		}
+		if v := r.Header.Get("X-Informas-Event"); v != EventTweetSent {
+			t.Errorf("got event %q", v)
+		}
+		if v := r.Header.Get("X-Informas-Delivery"); v != "7" {
+			t.Errorf("got delivery %q", v)
+		}
+		if rc.requests <= rc.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+// newTestDispatcher creates a dispatcher that is not running.
+func newTestDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: http.DefaultClient,
+		log:    logrus.WithField("context", "webhook"),
+	}
+}
+
+// newTestDelivery creates a pending delivery.
+func newTestDelivery() *db.Delivery {
+	return &db.Delivery{
+		ID:      7,
+		Event:   EventTweetSent,
+		Payload: `{"event":"tweet.sent","data":{"id":1}}`,
+		State:   db.DeliveryPending,
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	var (
+		rc = newReceiver(t, "secret", 2)
+		d  = newTestDispatcher()
+		w  = &db.Webhook{URL: rc.URL, Secret: "secret"}
+		dl = newTestDelivery()
+	)
+	for i := 1; i <= 2; i++ {
+		before := time.Now().UTC()
+		d.send(w, dl)
+		if dl.State != db.DeliveryPending || dl.Attempts != i {
+			t.Fatalf("attempt %d: got state %s after %d attempts", i, dl.State, dl.Attempts)
+		}
+		if dl.ResponseCode != http.StatusServiceUnavailable || len(dl.Error) == 0 {
+			t.Fatalf("attempt %d: got %d %q", i, dl.ResponseCode, dl.Error)
+		}
+		if dl.NextAttempt.Before(before.Add(backoff(i))) {
+			t.Fatalf("attempt %d: retried at %s", i, dl.NextAttempt)
+		}
+	}
+	d.send(w, dl)
+	if dl.State != db.DeliveryDelivered || dl.Attempts != 3 {
+		t.Fatalf("got state %s after %d attempts", dl.State, dl.Attempts)
+	}
+	if dl.ResponseCode != http.StatusNoContent || len(dl.Error) != 0 {
+		t.Fatalf("got %d %q", dl.ResponseCode, dl.Error)
+	}
+	if rc.requests != 3 {
+		t.Fatalf("received %d requests", rc.requests)
+	}
+}
+
+func TestSendGivesUp(t *testing.T) {
+	var (
+		rc = newReceiver(t, "secret", maxAttempts)
+		d  = newTestDispatcher()
+		w  = &db.Webhook{URL: rc.URL, Secret: "secret"}
+		dl = newTestDelivery()
+	)
+	for i := 0; i < maxAttempts; i++ {
+		if dl.State != db.DeliveryPending {
+			t.Fatalf("gave up after %d attempts", dl.Attempts)
+		}
+		d.send(w, dl)
+	}
+	if dl.State != db.DeliveryFailed || rc.requests != maxAttempts {
+		t.Fatalf("got state %s after %d requests", dl.State, rc.requests)
+	}
+}
+
+func TestSign(t *testing.T) {
+	const expected = "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b"
+	if v := Sign("secret", []byte("hello")); v != expected {
+		t.Fatalf("got %s", v)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{3, 4 * initialBackoff},
+		{20, maxBackoff},
+	} {
+		if b := backoff(tc.attempts); b != tc.expected {
+			t.Errorf("%d attempts: got %s, expected %s", tc.attempts, b, tc.expected)
+		}
+	}
+}
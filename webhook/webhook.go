@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+// Events that webhooks may subscribe to.
+const (
+	EventTweetSubmitted = "tweet.submitted"
+	EventTweetApproved  = "tweet.approved"
+	EventTweetRejected  = "tweet.rejected"
+	EventTweetSent      = "tweet.sent"
+	EventTweetFailed    = "tweet.failed"
+)
+
+// EventPing is sent on request to check that a webhook is reachable. Every
+// webhook receives it regardless of its event filter.
+const EventPing = "ping"
+
+// Events lists the events in the order they are shown to administrators.
+var Events = []string{
+	EventTweetSubmitted,
+	EventTweetApproved,
+	EventTweetRejected,
+	EventTweetSent,
+	EventTweetFailed,
+}
+
+// Tweet is the data sent with each of the tweet events.
+type Tweet struct {
+	ID        int       `json:"id"`
+	GroupID   int       `json:"group_id"`
+	AccountID int       `json:"account_id"`
+	UserID    int       `json:"user_id"`
+	Status    string    `json:"status"`
+	Scheduled time.Time `json:"scheduled"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// newTweet converts a tweet into the data sent with its events.
+func newTweet(tw *db.Tweet) *Tweet {
+	return &Tweet{
+		ID:        tw.ID,
+		GroupID:   tw.GroupID,
+		AccountID: tw.AccountID,
+		UserID:    tw.UserID,
+		Status:    tw.Status,
+		Scheduled: tw.Scheduled,
+		Error:     tw.Error,
+	}
+}
+
+// payload is the body of each request sent to a webhook.
+type payload struct {
+	Event   string      `json:"event"`
+	Created time.Time   `json:"created"`
+	Data    interface{} `json:"data"`
+}
+
+// Sign calculates the signature sent in the X-Informas-Signature header. The
+// receiver computes the HMAC-SHA256 of the raw request body with the shared
+// secret and compares it to the hex-encoded value after "sha256=".
+func Sign(secret string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// newDelivery creates a pending delivery of the event to the webhook.
+func newDelivery(t *db.Token, w *db.Webhook, event string, body []byte, now time.Time) error {
+	d := &db.Delivery{
+		WebhookID:   w.ID,
+		Event:       event,
+		Payload:     string(body),
+		State:       db.DeliveryPending,
+		NextAttempt: now,
+		Created:     now,
+	}
+	return d.Save(t)
+}
+
+// Enqueue queues the event for delivery to every enabled webhook subscribed
+// to it. The data is encoded as JSON. Deliveries are written using the
+// provided token so that they are only sent if the transaction that caused
+// the event is committed.
+func Enqueue(t *db.Token, event string, data interface{}) error {
+	webhooks, err := db.AllWebhooks(t)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	body, err := json.Marshal(&payload{
+		Event:   event,
+		Created: now,
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+	for _, w := range webhooks {
+		if !w.IsEnabled || !w.HasEvent(event) {
+			continue
+		}
+		if err := newDelivery(t, w, event, body, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueTweets queues the event once for each of the tweets.
+func EnqueueTweets(t *db.Token, event string, tweets ...*db.Tweet) error {
+	for _, tw := range tweets {
+		if err := Enqueue(t, event, newTweet(tw)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping queues a ping event for the webhook.
+func Ping(t *db.Token, w *db.Webhook) error {
+	now := time.Now().UTC()
+	body, err := json.Marshal(&payload{
+		Event:   EventPing,
+		Created: now,
+		Data: map[string]int{
+			"webhook_id": w.ID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return newDelivery(t, w, EventPing, body, now)
+}
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nathan-osman/informas/db"
+)
+
+func TestTweetPayload(t *testing.T) {
+	var (
+		scheduled = time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+		tw        = &db.Tweet{
+			ID:        3,
+			GroupID:   2,
+			AccountID: 1,
+			UserID:    4,
+			Status:    db.TweetFailed,
+			Scheduled: scheduled,
+			Error:     "part 1: unavailable",
+		}
+	)
+	b, err := json.Marshal(&payload{Event: EventTweetFailed, Data: newTweet(tw)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Event string                 `json:"event"`
+		Data  map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"id":         3.0,
+		"group_id":   2.0,
+		"account_id": 1.0,
+		"user_id":    4.0,
+		"status":     "failed",
+		"scheduled":  "2026-10-19T09:00:00Z",
+		"error":      "part 1: unavailable",
+	}
+	if v.Event != EventTweetFailed || !reflect.DeepEqual(v.Data, expected) {
+		t.Fatalf("got %s %v", v.Event, v.Data)
+	}
+}